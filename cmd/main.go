@@ -2,30 +2,41 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"github.com/go-chi/chi/v5"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	"zota-dev-challenge/internal"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/health"
+	ledger "zota-dev-challenge/internal/ledger/common"
+	"zota-dev-challenge/internal/reconciliation"
+	status "zota-dev-challenge/internal/status/common"
+	"zota-dev-challenge/internal/webhook"
 )
 
-func startServer(lc fx.Lifecycle, logger *zap.Logger, router *chi.Mux) {
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
-	}
-
+func startServer(lc fx.Lifecycle, logger *zap.Logger, server *http.Server) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			logger.Info("Starting server on :8080")
-			// Start server in a new goroutine
+			// listening here lets a taken port fail the startup instead of killing the process later
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			if server.TLSConfig != nil {
+				listener = tls.NewListener(listener, server.TLSConfig)
+			}
+
+			logger.Info("Starting server", zap.String("addr", listener.Addr().String()), zap.Bool("tls", server.TLSConfig != nil))
 			go func() {
-				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-					logger.Fatal("Failed to start server", zap.Error(err))
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Fatal("Server failed", zap.Error(err))
 				}
 			}()
 
@@ -38,15 +49,120 @@ func startServer(lc fx.Lifecycle, logger *zap.Logger, router *chi.Mux) {
 	})
 }
 
+func startPoller(lc fx.Lifecycle, logger *zap.Logger, poller *status.Poller) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting pending order poller")
+			poller.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping pending order poller")
+			return poller.Stop(ctx)
+		},
+	})
+}
+
+func startWebhooks(lc fx.Lifecycle, logger *zap.Logger, config *config.Config, dispatcher *webhook.Dispatcher) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting webhook dispatcher", zap.Int("subscriptions", len(config.Webhooks)))
+			dispatcher.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping webhook dispatcher")
+			return dispatcher.Stop(ctx)
+		},
+	})
+}
+
+func startLedgerSweeper(lc fx.Lifecycle, logger *zap.Logger, config *config.Config, sweeper *ledger.Sweeper) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting ledger sweeper", zap.Duration("interval", config.LedgerSweepInterval),
+				zap.Duration("window", config.LedgerSweepWindow))
+			sweeper.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping ledger sweeper")
+			return sweeper.Stop(ctx)
+		},
+	})
+}
+
+func startReconciliation(lc fx.Lifecycle, logger *zap.Logger, config *config.Config, job *reconciliation.Job) {
+	if !config.ReconciliationEnabled {
+		return
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting reconciliation job", zap.Duration("interval", config.ReconciliationInterval),
+				zap.Int("days", config.ReconciliationDays), zap.Bool("autoCorrect", config.ReconciliationAutoCorrect))
+			job.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping reconciliation job")
+			return job.Stop(ctx)
+		},
+	})
+}
+
+// startReadiness reports ready once the server runs. Its stop hook runs before the server's,
+// so load balancers see the instance as not ready and drain it before the server shuts down.
+func startReadiness(lc fx.Lifecycle, logger *zap.Logger, config *config.Config, checker *health.Checker) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			checker.SetReady(true)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Draining traffic before shutdown", zap.Duration("delay", config.ServerDrainDelay))
+			checker.SetReady(false)
+
+			select {
+			case <-time.After(config.ServerDrainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
 // @title			Merchant Server
 // @version		1.0
 // @description	This is a simple merchant's server implementing Zota payment gateway.
 // @host			localhost:8080
 // @BasePath		/api/v1
+//
+// @securityDefinitions.apikey	AdminToken
+// @in							header
+// @name						Authorization
+// @description				The admin token as "Bearer <ADMIN_API_TOKEN>"
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reconcile":
+			os.Exit(reconcile(os.Args[2:]))
+		case "report":
+			os.Exit(ordersReport(os.Args[2:]))
+		}
+	}
+
 	app := fx.New(
 		internal.AppModules,
+		// must come before startServer, so changes made by the last requests are still delivered
+		fx.Invoke(startWebhooks),
 		fx.Invoke(startServer),
+		fx.Invoke(startPoller),
+		fx.Invoke(startLedgerSweeper),
+		fx.Invoke(startReconciliation),
+		// must come after startServer, fx runs the stop hooks in reverse order
+		fx.Invoke(startReadiness),
 	)
 
 	// Handle SIGINT and SIGTERM signals for graceful shutdown
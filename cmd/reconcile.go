@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"zota-dev-challenge/internal"
+	"zota-dev-challenge/internal/config"
+	ledger "zota-dev-challenge/internal/ledger/common"
+	"zota-dev-challenge/internal/reconciliation"
+	"zota-dev-challenge/internal/webhook"
+)
+
+// reconcile runs a single reconciliation with Zota's orders report and exits with 1 when it found discrepancies
+func reconcile(args []string) int {
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	from := flags.String("from", yesterday, "first day of the orders, as 2024-01-31 in UTC")
+	to := flags.String("to", "", "last day of the orders, included, defaults to -from")
+	fix := flags.Bool("fix", false, "move non-final local orders to the status Zota reports, defaults to RECONCILIATION_AUTO_CORRECT")
+	out := flags.String("out", "", "file the discrepancy report is written to, - for stdout, defaults to a new file in RECONCILIATION_REPORT_DIR")
+	flags.Parse(args)
+
+	fromDate, err := time.Parse(time.DateOnly, *from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -from %q: %v\n", *from, err)
+		return 2
+	}
+	toDate := fromDate
+	if *to != "" {
+		if toDate, err = time.Parse(time.DateOnly, *to); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -to %q: %v\n", *to, err)
+			return 2
+		}
+	}
+
+	var (
+		logger     *zap.Logger
+		cfg        *config.Config
+		reconciler *reconciliation.Reconciler
+		dispatcher *webhook.Dispatcher
+	)
+	app := fx.New(
+		internal.AppModules,
+		fx.NopLogger,
+		// corrected deposits are credited like the ones approved by a callback
+		fx.Invoke(func(*ledger.Service) {}),
+		fx.Populate(&logger, &cfg, &reconciler, &dispatcher),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer app.Stop(context.Background())
+
+	autoCorrect := cfg.ReconciliationAutoCorrect
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "fix" {
+			autoCorrect = *fix
+		}
+	})
+
+	report, err := reconciler.Reconcile(ctx, fromDate, toDate, autoCorrect)
+	if err != nil {
+		return 2
+	}
+	if autoCorrect {
+		// the command exits right away, so the webhooks of the corrected orders get a single attempt
+		dispatcher.Dispatch(ctx)
+	}
+
+	path := *out
+	switch path {
+	case "-":
+		err = reconciliation.WriteCSV(os.Stdout, report)
+	case "":
+		path, err = reconciliation.SaveReport(cfg.ReconciliationReportDir, report)
+	default:
+		err = writeReportFile(path, report)
+	}
+	if err != nil {
+		logger.Error("Failed to write reconciliation report", zap.Error(err))
+		return 2
+	}
+	if path != "-" {
+		logger.Info("Wrote reconciliation report", zap.String("path", path), zap.Int("discrepancies", len(report.Discrepancies)))
+	}
+
+	if len(report.Discrepancies) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeReportFile(path string, report *reconciliation.Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reconciliation.WriteCSV(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
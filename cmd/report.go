@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"zota-dev-challenge/internal"
+	"zota-dev-challenge/internal/apperror"
+	report "zota-dev-challenge/internal/report/common"
+	"zota-dev-challenge/internal/report/shared"
+)
+
+// ordersReport writes Zota's orders report as JSON lines, or imports the orders the local store does not know.
+// An import exits with 1 when some orders could not be stored.
+func ordersReport(args []string) int {
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	from := flags.String("from", yesterday, "first day of the orders, as 2024-01-31 in UTC")
+	to := flags.String("to", "", "last day of the orders, included, defaults to -from")
+	dateType := flags.String("date-type", shared.DateTypeCreated, "created or ended, the date of the orders the range applies to")
+	types := flags.String("types", "", "comma separated order types, such as SALE,PAYOUT")
+	statuses := flags.String("statuses", "", "comma separated order statuses")
+	endpointIDs := flags.String("endpoint-ids", "", "comma separated Zota endpoint IDs")
+	importOrders := flags.Bool("import", false, "store the reported orders the local store does not know instead of writing them")
+	out := flags.String("out", "-", "file the report or the import summary is written to, - for stdout")
+	flags.Parse(args)
+
+	req := &shared.ClientRequest{FromDate: *from, ToDate: *to, DateType: *dateType, Types: *types,
+		Statuses: *statuses, EndpointIds: *endpointIDs}
+	if req.ToDate == "" {
+		req.ToDate = req.FromDate
+	}
+
+	var (
+		logger  *zap.Logger
+		service *report.Service
+	)
+	app := fx.New(
+		internal.AppModules,
+		fx.NopLogger,
+		fx.Populate(&logger, &service),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer app.Stop(context.Background())
+
+	var output io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer file.Close()
+		output = file
+	}
+	encoder := json.NewEncoder(output)
+
+	if !*importOrders {
+		if err := service.OrdersReport(ctx, req, func(record shared.Record) error {
+			return encoder.Encode(record)
+		}); err != nil {
+			printError(err)
+			return 2
+		}
+		return 0
+	}
+
+	summary, err := service.Import(ctx, req)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	if err := encoder.Encode(summary); err != nil {
+		logger.Error("Failed to write import summary", zap.Error(err))
+		return 2
+	}
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printError writes the error with the fields that failed validation, named as in the API
+func printError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		for _, field := range appErr.Fields {
+			fmt.Fprintf(os.Stderr, "  %s %s\n", field.Field, field.Message)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Command zotasim runs the Zota simulator, point ZOTA_BASE_URL at it to develop without sandbox credentials
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"zota-dev-challenge/internal/zotasim"
+)
+
+func main() {
+	addr := flag.String("addr", envOr("ZOTASIM_ADDR", ":9090"), "address to listen on")
+	publicURL := flag.String("public-url", envOr("ZOTASIM_PUBLIC_URL", "http://localhost:9090"), "URL the payment pages are served on")
+	merchantID := flag.String("merchant-id", envOr("ZOTA_MERCHANT_ID", "sim-merchant"), "merchant ID the simulator accepts")
+	secretKey := flag.String("secret-key", envOr("ZOTA_API_SECRET_KEY", "sim-secret"), "secret key requests are signed with")
+	scenario := flag.String("scenario", envOr("ZOTASIM_SCENARIO", string(zotasim.ScenarioApprove)),
+		"scenario of orders that are neither scripted nor tagged: approve, decline, delay, 5xx, malformed or manual")
+	processingTime := flag.Duration("processing-time", time.Second, "how long orders stay pending")
+	delay := flag.Duration("delay", 30*time.Second, "how late the answers about delay orders are")
+	flag.Parse()
+
+	logger, _ := zap.NewDevelopment()
+	defer logger.Sync()
+
+	defaultScenario, err := zotasim.ParseScenario(*scenario)
+	if err != nil {
+		logger.Fatal("Invalid scenario", zap.Error(err))
+	}
+
+	simulator := zotasim.New(logger, zotasim.Config{
+		MerchantID:      *merchantID,
+		SecretKey:       *secretKey,
+		PublicURL:       *publicURL,
+		DefaultScenario: defaultScenario,
+		ProcessingTime:  *processingTime,
+		Delay:           *delay,
+	})
+	server := &http.Server{Addr: *addr, Handler: simulator.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		logger.Info("Starting Zota simulator", zap.String("addr", *addr), zap.String("merchantID", *merchantID),
+			zap.String("scenario", string(defaultScenario)))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Simulator failed", zap.Error(err))
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to stop simulator", zap.Error(err))
+	}
+	simulator.Close()
+}
+
+func envOr(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -15,6 +15,64 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/callback/deposit": {
+            "post": {
+                "description": "receives the final order status from Zota, non-200 answers make Zota retry the callback",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "callback"
+                ],
+                "summary": "deposit and payout callback",
+                "responses": {
+                    "200": {
+                        "description": "Callback accepted"
+                    },
+                    "400": {
+                        "description": "Malformed callback"
+                    },
+                    "401": {
+                        "description": "Invalid signature"
+                    },
+                    "500": {
+                        "description": "Callback could not be processed"
+                    }
+                }
+            }
+        },
+        "/callback/payout": {
+            "post": {
+                "description": "receives the final order status from Zota, non-200 answers make Zota retry the callback",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "callback"
+                ],
+                "summary": "deposit and payout callback",
+                "responses": {
+                    "200": {
+                        "description": "Callback accepted"
+                    },
+                    "400": {
+                        "description": "Malformed callback"
+                    },
+                    "401": {
+                        "description": "Invalid signature"
+                    },
+                    "500": {
+                        "description": "Callback could not be processed"
+                    }
+                }
+            }
+        },
         "/deposit": {
             "post": {
                 "description": "handle deposit",
@@ -35,15 +93,299 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/deposit.ClientRequest"
+                            "$ref": "#/definitions/DepositRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Repeats with the same key and body are answered with the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Answer with 303 See Other to the Zota payment page instead of JSON",
+                        "name": "redirect",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Deposit Successful",
                         "schema": {
-                            "$ref": "#/definitions/zota.DepositResponse"
+                            "$ref": "#/definitions/DepositResponse"
+                        }
+                    },
+                    "303": {
+                        "description": "Redirect to the Zota payment page"
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency key reused with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unsupported currency or rejected by the payment gateway",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Unexpected payment gateway response",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment gateway unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payout": {
+            "post": {
+                "description": "handle payout (withdrawal) to the customer's bank account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payout"
+                ],
+                "summary": "payout example",
+                "parameters": [
+                    {
+                        "description": "Payout ClientRequest",
+                        "name": "payoutRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PayoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payout Successful",
+                        "schema": {
+                            "$ref": "#/definitions/PayoutResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unsupported currency or rejected by the payment gateway",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Unexpected payment gateway response",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment gateway unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/orders": {
+            "get": {
+                "description": "streams the orders Zota reports for the date range as a JSON array, while Zota is still sending the report.\nAn error after the first record can only cut the array short, it is logged.\nThe answer may take up to ZOTA_REPORT_TIMEOUT plus SERVER_WRITE_TIMEOUT.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Show Zota's orders report",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day, as 2024-01-31 in UTC",
+                        "name": "fromDate",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, included",
+                        "name": "toDate",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "created (default) or ended, the date of the orders the range applies to",
+                        "name": "dateType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated order types, such as SALE,PAYOUT",
+                        "name": "types",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated order statuses",
+                        "name": "statuses",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated Zota endpoint IDs",
+                        "name": "endpointIds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/ReportRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Unexpected payment gateway response",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment gateway unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/orders/import": {
+            "post": {
+                "description": "stores the reported orders the local store does not know yet, as Zota reports them.\nImported orders do not credit wallets or notify webhook subscribers.\nThe answer may take up to ZOTA_REPORT_TIMEOUT plus SERVER_WRITE_TIMEOUT.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Import Zota's orders report",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day, as 2024-01-31 in UTC",
+                        "name": "fromDate",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day, included",
+                        "name": "toDate",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "created (default) or ended, the date of the orders the range applies to",
+                        "name": "dateType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated order types, such as SALE,PAYOUT",
+                        "name": "types",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated order statuses",
+                        "name": "statuses",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated Zota endpoint IDs",
+                        "name": "endpointIds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ImportSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Unexpected payment gateway response",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment gateway unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
@@ -59,30 +401,321 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status check"
+                    "status check"
+                ],
+                "summary": "status check example",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID, looked up in the order store when omitted",
+                        "name": "orderId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Merchant Order ID",
+                        "name": "merchantOrderId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Status Check successful",
+                        "schema": {
+                            "$ref": "#/definitions/StatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Order belongs to the other Zota environment",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Unexpected payment gateway response",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Payment gateway unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/balances": {
+            "get": {
+                "description": "shows what the user holds per currency, credited from approved deposits",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "Show a user's wallet balances",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only the balance in the currency, zero when the user holds none",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/Balance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/transactions": {
+            "get": {
+                "description": "lists the ledger transactions of the user newest first, with their double-entry entries",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "wallet"
+                ],
+                "summary": "List a user's wallet transactions",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transactions in the currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Most transactions listed, defaults to 50, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/LedgerTransaction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries": {
+            "get": {
+                "description": "lists the webhook deliveries newest first, status=dead lists the dead letters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pending, delivered or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Most deliveries listed, defaults to 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}": {
+            "get": {
+                "description": "shows a webhook delivery with its delivery log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Show a webhook delivery",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/WebhookDeliveryLog"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "description": "sends a delivery again with a fresh set of attempts, e.g. a dead letter once the subscriber is fixed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
                 ],
-                "summary": "status check example",
-                "parameters": [
+                "summary": "Redeliver a webhook",
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Order ID",
-                        "name": "orderId",
-                        "in": "query",
-                        "required": true
-                    },
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Merchant Order ID",
-                        "name": "merchantOrderId",
-                        "in": "query",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Status Check successful",
+                    "202": {
+                        "description": "Delivery queued",
                         "schema": {
-                            "$ref": "#/definitions/zota.StatusResponse"
+                            "$ref": "#/definitions/WebhookDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
@@ -90,9 +723,40 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "deposit.ClientRequest": {
+        "Balance": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "DepositRequest": {
             "type": "object",
+            "required": [
+                "customerAddress",
+                "customerCity",
+                "customerCountryCode",
+                "customerEmail",
+                "customerFirstName",
+                "customerIp",
+                "customerLastName",
+                "customerPhone",
+                "customerZipCode",
+                "orderAmount",
+                "orderCurrency",
+                "userId"
+            ],
             "properties": {
+                "checkoutUrl": {
+                    "type": "string"
+                },
                 "customerAddress": {
                     "type": "string"
                 },
@@ -111,6 +775,9 @@ const docTemplate = `{
                 "customerFirstName": {
                     "type": "string"
                 },
+                "customerIp": {
+                    "type": "string"
+                },
                 "customerLastName": {
                     "type": "string"
                 },
@@ -123,6 +790,9 @@ const docTemplate = `{
                 "customerZipCode": {
                     "type": "string"
                 },
+                "language": {
+                    "type": "string"
+                },
                 "orderAmount": {
                     "type": "string"
                 },
@@ -134,140 +804,419 @@ const docTemplate = `{
                 }
             }
         },
-        "zota.Data": {
+        "DepositResponse": {
             "type": "object",
             "properties": {
-                "amount": {
+                "depositUrl": {
                     "type": "string"
                 },
-                "currency": {
+                "environment": {
                     "type": "string"
                 },
-                "customParam": {
+                "orderId": {
                     "type": "string"
                 },
-                "customerEmail": {
+                "paymentGatewayOrderId": {
+                    "type": "string"
+                },
+                "request": {
+                    "$ref": "#/definitions/DepositRequest"
+                }
+            }
+        },
+        "ErrorBody": {
+            "type": "object",
+            "properties": {
+                "code": {
                     "type": "string"
                 },
-                "endpointID": {
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/FieldError"
+                    }
+                },
+                "gateway": {
+                    "$ref": "#/definitions/GatewayFailure"
+                },
+                "message": {
                     "type": "string"
                 },
-                "errorMessage": {
+                "requestId": {
+                    "type": "string"
+                }
+            }
+        },
+        "ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/ErrorBody"
+                }
+            }
+        },
+        "FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
                     "type": "string"
                 },
-                "extraData": {
-                    "$ref": "#/definitions/zota.ExtraData"
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "GatewayFailure": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
                 },
-                "merchantOrderID": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "ImportFailure": {
+            "type": "object",
+            "properties": {
+                "error": {
                     "type": "string"
                 },
-                "orderID": {
+                "merchantOrderId": {
                     "type": "string"
                 },
-                "processorTransactionID": {
+                "orderId": {
                     "type": "string"
+                }
+            }
+        },
+        "ImportSummary": {
+            "type": "object",
+            "properties": {
+                "existing": {
+                    "type": "integer"
                 },
-                "request": {
-                    "$ref": "#/definitions/zota.StatusRequest"
+                "failed": {
+                    "type": "integer"
                 },
-                "status": {
+                "failures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ImportFailure"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                }
+            }
+        },
+        "LedgerEntry": {
+            "type": "object",
+            "properties": {
+                "account": {
                     "type": "string"
                 },
-                "type": {
+                "amount": {
+                    "type": "string"
+                },
+                "direction": {
                     "type": "string"
                 }
             }
         },
-        "zota.DepositResponse": {
+        "LedgerTransaction": {
             "type": "object",
             "properties": {
-                "code": {
+                "amount": {
                     "type": "string"
                 },
-                "data": {
-                    "$ref": "#/definitions/zota.DepositResponseData"
+                "createdAt": {
+                    "type": "string"
                 },
-                "message": {
+                "currency": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/LedgerEntry"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "merchantOrderId": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "requestedAmount": {
+                    "type": "string"
+                },
+                "userId": {
                     "type": "string"
                 }
             }
         },
-        "zota.DepositResponseData": {
+        "PayoutRequest": {
             "type": "object",
+            "required": [
+                "customerBankAccountName",
+                "customerBankAccountNumber",
+                "customerEmail",
+                "customerFirstName",
+                "customerIp",
+                "customerLastName",
+                "customerPhone",
+                "orderAmount",
+                "orderCurrency",
+                "userId"
+            ],
             "properties": {
-                "depositUrl": {
+                "customerBankAccountName": {
                     "type": "string"
                 },
-                "merchantOrderID": {
+                "customerBankAccountNumber": {
                     "type": "string"
                 },
-                "orderID": {
+                "customerBankAddress": {
                     "type": "string"
+                },
+                "customerBankArea": {
+                    "type": "string"
+                },
+                "customerBankBranch": {
+                    "type": "string"
+                },
+                "customerBankCode": {
+                    "type": "string"
+                },
+                "customerBankProvince": {
+                    "type": "string"
+                },
+                "customerBankRoutingNumber": {
+                    "type": "string"
+                },
+                "customerBankZipCode": {
+                    "type": "string"
+                },
+                "customerCountryCode": {
+                    "type": "string"
+                },
+                "customerEmail": {
+                    "type": "string"
+                },
+                "customerFirstName": {
+                    "type": "string"
+                },
+                "customerIp": {
+                    "type": "string"
+                },
+                "customerLastName": {
+                    "type": "string"
+                },
+                "customerPhone": {
+                    "type": "string"
+                },
+                "orderAmount": {
+                    "type": "string"
+                },
+                "orderCurrency": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "PayoutResponse": {
+            "type": "object",
+            "properties": {
+                "environment": {
+                    "type": "string"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "paymentGatewayOrderId": {
+                    "type": "string"
+                },
+                "request": {
+                    "$ref": "#/definitions/PayoutRequest"
                 }
             }
         },
-        "zota.ExtraData": {
+        "ReportRecord": {
             "type": "object",
             "properties": {
-                "amountChanged": {
-                    "type": "boolean"
+                "amount": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "customParam": {
+                    "type": "string"
+                },
+                "endedAt": {
+                    "type": "string"
+                },
+                "endpointId": {
+                    "type": "string"
                 },
-                "amountManipulated": {
-                    "type": "boolean"
+                "errorMessage": {
+                    "type": "string"
                 },
-                "amountRounded": {
-                    "type": "boolean"
+                "merchantOrderId": {
+                    "type": "string"
                 },
-                "dcc": {
-                    "type": "boolean"
+                "orderId": {
+                    "type": "string"
                 },
-                "originalAmount": {
+                "processorTransactionId": {
                     "type": "string"
                 },
-                "paymentMethod": {
+                "status": {
                     "type": "string"
                 },
-                "selectedBankCode": {
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "StatusRequest": {
+            "type": "object",
+            "properties": {
+                "merchantOrderId": {
                     "type": "string"
                 },
-                "selectedBankName": {
+                "orderId": {
                     "type": "string"
                 }
             }
         },
-        "zota.StatusRequest": {
+        "StatusResponse": {
             "type": "object",
             "properties": {
-                "merchantID": {
+                "amount": {
+                    "type": "string"
+                },
+                "currency": {
                     "type": "string"
                 },
-                "merchantOrderID": {
+                "customerEmail": {
                     "type": "string"
                 },
-                "orderID": {
+                "environment": {
                     "type": "string"
                 },
-                "signature": {
+                "request": {
+                    "$ref": "#/definitions/StatusRequest"
+                },
+                "status": {
                     "type": "string"
                 },
-                "timestamp": {
+                "type": {
                     "type": "string"
                 }
             }
         },
-        "zota.StatusResponse": {
+        "WebhookAttempt": {
             "type": "object",
             "properties": {
-                "code": {
+                "at": {
+                    "type": "string"
+                },
+                "deliveryId": {
                     "type": "string"
                 },
-                "data": {
-                    "$ref": "#/definitions/zota.Data"
+                "durationMs": {
+                    "type": "integer"
                 },
-                "message": {
+                "error": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "integer"
+                },
+                "statusCode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscription": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
+        },
+        "WebhookDeliveryLog": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/WebhookAttempt"
+                    }
+                },
+                "delivery": {
+                    "$ref": "#/definitions/WebhookDelivery"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "The admin token as \"Bearer \u003cADMIN_API_TOKEN\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,33 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"zota-dev-challenge/internal/apperror"
+)
+
+const bearerPrefix = "Bearer "
+
+// Middleware lets through the requests that carry the admin token as "Authorization: Bearer <token>".
+// Without a configured token the admin routes are closed, every request is refused.
+func Middleware(token string, logger *zap.Logger) func(http.Handler) http.Handler {
+	// hashed, so the comparison takes as long whatever the length of the token sent
+	expected := sha256.Sum256([]byte(token))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sent, ok := strings.CutPrefix(r.Header.Get("Authorization"), bearerPrefix)
+			actual := sha256.Sum256([]byte(sent))
+			if token == "" || !ok || subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 {
+				logger.Warn("Admin request refused", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				apperror.Write(w, r, logger, apperror.Unauthorized("a valid admin token is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(token, authorization string) *httptest.ResponseRecorder {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req := httptest.NewRequest("GET", "/api/v1/reports/orders", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rr := httptest.NewRecorder()
+	Middleware(token, zap.NewNop())(next).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMiddleware_AcceptsTheToken(t *testing.T) {
+	assert.Equal(t, http.StatusNoContent, serve("secret-token", "Bearer secret-token").Code)
+}
+
+func TestMiddleware_RefusesOtherRequests(t *testing.T) {
+	tests := map[string]struct {
+		token         string
+		authorization string
+	}{
+		"no token sent":   {"secret-token", ""},
+		"wrong token":     {"secret-token", "Bearer other-token"},
+		"token prefix":    {"secret-token", "Bearer secret"},
+		"other scheme":    {"secret-token", "Basic secret-token"},
+		"no token set":    {"", "Bearer "},
+		"no token at all": {"", ""},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := serve(test.token, test.authorization)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Contains(t, rr.Body.String(), `"code":"UNAUTHORIZED"`)
+			assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Machine-readable error codes, part of the API contract so they must stay stable
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeValidation          = "VALIDATION_FAILED"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeUnsupportedCurrency = "UNSUPPORTED_CURRENCY"
+	CodeNotFound            = "NOT_FOUND"
+	CodeConflict            = "CONFLICT"
+	CodeGatewayRejected     = "GATEWAY_REJECTED"
+	CodeGatewayError        = "GATEWAY_ERROR"
+	CodeGatewayUnavailable  = "GATEWAY_UNAVAILABLE"
+	CodeInternal            = "INTERNAL_ERROR"
+)
+
+var statuses = map[string]int{
+	CodeInvalidRequest:      http.StatusBadRequest,
+	CodeValidation:          http.StatusBadRequest,
+	CodeUnauthorized:        http.StatusUnauthorized,
+	CodeUnsupportedCurrency: http.StatusUnprocessableEntity,
+	CodeNotFound:            http.StatusNotFound,
+	CodeConflict:            http.StatusConflict,
+	CodeGatewayRejected:     http.StatusUnprocessableEntity,
+	CodeGatewayError:        http.StatusBadGateway,
+	CodeGatewayUnavailable:  http.StatusServiceUnavailable,
+	CodeInternal:            http.StatusInternalServerError,
+}
+
+// FieldError - why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+} //@name FieldError
+
+// GatewayFailure - the error code and message the payment gateway itself answered with
+type GatewayFailure struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+} //@name GatewayFailure
+
+// Error - a domain error that knows how it is presented to API clients
+type Error struct {
+	Code    string
+	Message string
+	Fields  []FieldError
+	Gateway *GatewayFailure
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the status code the error is answered with
+func (e *Error) HTTPStatus() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func InvalidRequest(message string, err error) *Error {
+	return &Error{Code: CodeInvalidRequest, Message: message, Err: err}
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// InvalidField - a validation error of a single field caused by err
+func InvalidField(field, rule string, err error) *Error {
+	return &Error{
+		Code:    CodeValidation,
+		Message: "request validation failed",
+		Fields:  []FieldError{{Field: field, Rule: rule, Message: err.Error()}},
+		Err:     err,
+	}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+func UnsupportedCurrency(currency string) *Error {
+	return &Error{Code: CodeUnsupportedCurrency, Message: fmt.Sprintf("currency %q is not supported", currency)}
+}
+
+func NotFound(message string, err error) *Error {
+	return &Error{Code: CodeNotFound, Message: message, Err: err}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+// GatewayRejected - the payment gateway refused the request as sent
+func GatewayRejected(message string, err error) *Error {
+	return &Error{Code: CodeGatewayRejected, Message: message, Err: err}
+}
+
+// GatewayError - the payment gateway answered with something we cannot use
+func GatewayError(message string, err error) *Error {
+	return &Error{Code: CodeGatewayError, Message: message, Err: err}
+}
+
+// GatewayUnavailable - the payment gateway could not be reached or is failing, retrying later may succeed
+func GatewayUnavailable(message string, err error) *Error {
+	return &Error{Code: CodeGatewayUnavailable, Message: message, Err: err}
+}
+
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+// From returns err as an *Error, anything unknown becomes an internal error
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// FromHTTPStatus classifies a non-OK answer of the payment gateway
+func FromHTTPStatus(statusCode int, err error) *Error {
+	switch {
+	case statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests:
+		return GatewayUnavailable("payment gateway is unavailable", err)
+	case statusCode >= http.StatusBadRequest:
+		return GatewayRejected("payment gateway rejected the request", err)
+	default:
+		return GatewayError("unexpected payment gateway response", err)
+	}
+}
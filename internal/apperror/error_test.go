@@ -0,0 +1,76 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromHTTPStatus(t *testing.T) {
+	cause := errors.New("non-OK response")
+	for statusCode, expectedCode := range map[int]string{
+		http.StatusBadRequest:          CodeGatewayRejected,
+		http.StatusUnauthorized:        CodeGatewayRejected,
+		http.StatusTooManyRequests:     CodeGatewayUnavailable,
+		http.StatusInternalServerError: CodeGatewayUnavailable,
+		http.StatusServiceUnavailable:  CodeGatewayUnavailable,
+		http.StatusFound:               CodeGatewayError,
+	} {
+		err := FromHTTPStatus(statusCode, cause)
+		assert.Equal(t, expectedCode, err.Code, "status %d", statusCode)
+		assert.ErrorIs(t, err, cause)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	notFound := NotFound("order not found", nil)
+	assert.Same(t, notFound, From(fmt.Errorf("lookup: %w", notFound)))
+	assert.Equal(t, http.StatusNotFound, From(notFound).HTTPStatus())
+
+	internal := From(errors.New("boom"))
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.Equal(t, http.StatusInternalServerError, internal.HTTPStatus())
+}
+
+func TestWrite(t *testing.T) {
+	logger := zap.NewNop()
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, logger, Internal(errors.New("database password is wrong")))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response Response
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, Body{Code: CodeInternal, Message: "internal error", RequestID: "req-1"}, response.Error)
+}
+
+func TestFromValidation(t *testing.T) {
+	type request struct {
+		Email    string `validate:"required,email"`
+		Currency string `validate:"required,iso4217"`
+	}
+
+	err := FromValidation(validator.New().Struct(request{Email: "not-an-email"}))
+	assert.Equal(t, CodeValidation, err.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "Email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "Currency", Rule: "required", Message: "is required"},
+	}, err.Fields)
+
+	assert.Equal(t, CodeInvalidRequest, FromValidation(errors.New("not a validation error")).Code)
+}
@@ -0,0 +1,80 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Response - the JSON envelope every API error is answered with
+type Response struct {
+	Error Body `json:"error"`
+} //@name ErrorResponse
+
+type Body struct {
+	Code      string          `json:"code"`
+	Message   string          `json:"message"`
+	Details   []FieldError    `json:"details,omitempty"`
+	Gateway   *GatewayFailure `json:"gateway,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+} //@name ErrorBody
+
+// Write answers the request with the error envelope, internal details never reach the client
+func Write(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error) {
+	appErr := From(err)
+	status := appErr.HTTPStatus()
+
+	if status >= http.StatusInternalServerError {
+		logger.Error("Request failed", zap.String("code", appErr.Code), zap.Error(err))
+	}
+
+	body := Body{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		Details:   appErr.Fields,
+		Gateway:   appErr.Gateway,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(Response{Error: body}); err != nil {
+		logger.Error("Failed to encode error response", zap.Error(err))
+	}
+}
+
+// FromValidation turns validator errors into per-field details
+func FromValidation(err error) *Error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return InvalidRequest("invalid request", err)
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldErr.Field(),
+			Rule:    fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+	return Validation("request validation failed", fields...)
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "iso4217":
+		return "must be an ISO 4217 currency code"
+	default:
+		return "failed the " + fieldErr.Tag() + " rule"
+	}
+}
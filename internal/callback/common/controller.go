@@ -0,0 +1,53 @@
+package common
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"zota-dev-challenge/internal/callback/shared"
+)
+
+// maxCallbackBodySize - Zota callbacks are small JSON documents, anything bigger is rejected
+const maxCallbackBodySize = 1 << 20
+
+// Handler
+// @Summary deposit and payout callback
+// @Schemes
+// @Description receives the final order status from Zota, non-200 answers make Zota retry the callback
+// @Tags callback
+// @Accept json
+// @Produce plain
+// @Success 200 "Callback accepted"
+// @Failure 400 "Malformed callback"
+// @Failure 401 "Invalid signature"
+// @Failure 500 "Callback could not be processed"
+// @Router /callback/deposit [post]
+// @Router /callback/payout [post]
+func Handler(service ServiceInterface, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBodySize))
+		if err != nil {
+			logger.Error("Failed to read callback body", zap.Error(err))
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := service.ProcessCallback(r.Context(), body); err != nil {
+			switch {
+			case errors.Is(err, shared.ErrMalformedCallback):
+				logger.Warn("Rejected malformed callback", zap.Error(err))
+				http.Error(w, "Malformed callback", http.StatusBadRequest)
+			case errors.Is(err, shared.ErrInvalidSignature):
+				logger.Warn("Rejected callback with invalid signature", zap.Error(err))
+				http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			default:
+				logger.Error("Failed to process callback", zap.Error(err))
+				http.Error(w, "Failed to process callback", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
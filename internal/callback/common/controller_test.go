@@ -0,0 +1,80 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zota-dev-challenge/internal/callback/shared"
+)
+
+type controllerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockService *MockServiceInterface
+	logger      *zap.Logger
+	body        []byte
+}
+
+func (s *controllerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockService = NewMockServiceInterface(s.mockCtrl)
+	s.logger, _ = zap.NewDevelopment()
+	s.body = []byte(`{"orderID":"order123","merchantOrderID":"merchantOrder123","status":"APPROVED"}`)
+}
+
+func (s *controllerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *controllerTestSuite) serve() *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/v1/callback/deposit", bytes.NewBuffer(s.body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	Handler(s.mockService, s.logger).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestHandler_Success(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessCallback(gomock.Any(), s.body).Return(&shared.Event{Status: "APPROVED"}, nil)
+
+	assert.Equal(t, http.StatusOK, s.serve().Code)
+}
+
+func TestHandler_MalformedCallback(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessCallback(gomock.Any(), s.body).Return(nil, shared.ErrMalformedCallback)
+
+	assert.Equal(t, http.StatusBadRequest, s.serve().Code)
+}
+
+func TestHandler_InvalidSignature(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessCallback(gomock.Any(), s.body).Return(nil, shared.ErrInvalidSignature)
+
+	assert.Equal(t, http.StatusUnauthorized, s.serve().Code)
+}
+
+func TestHandler_ServiceError(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessCallback(gomock.Any(), s.body).Return(nil, errors.New("service error"))
+
+	assert.Equal(t, http.StatusInternalServerError, s.serve().Code)
+}
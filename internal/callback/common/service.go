@@ -0,0 +1,65 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"zota-dev-challenge/internal/callback/shared"
+	"zota-dev-challenge/internal/config"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+type ServiceInterface interface {
+	ProcessCallback(ctx context.Context, body []byte) (*shared.Event, error)
+}
+
+type Service struct {
+	logger          *zap.Logger
+	config          *config.Config
+	callbackGateway shared.CallbackPaymentGateway
+	stateMachine    *order.StateMachine
+}
+
+func NewService(logger *zap.Logger, config *config.Config, callbackGateway shared.CallbackPaymentGateway, stateMachine *order.StateMachine) *Service {
+	return &Service{logger: logger, config: config, callbackGateway: callbackGateway, stateMachine: stateMachine}
+}
+
+func (s *Service) ProcessCallback(ctx context.Context, body []byte) (*shared.Event, error) {
+	ctx, span := tracing.Start(ctx, "callback.Service.ProcessCallback")
+	event, err := s.processCallback(ctx, body)
+	if event != nil {
+		span.SetAttributes(tracing.AttrMerchantOrderID.String(event.MerchantOrderID), tracing.AttrZotaOrderID.String(event.OrderID),
+			tracing.AttrCurrency.String(event.Currency), tracing.AttrStatus.String(event.Status))
+	}
+	tracing.End(span, err)
+	return event, err
+}
+
+func (s *Service) processCallback(ctx context.Context, body []byte) (*shared.Event, error) {
+	event, err := s.callbackGateway.ParseCallback(body)
+	if err != nil {
+		s.logger.Error("Failed to verify callback", zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Received order status callback",
+		zap.String("merchantOrderID", event.MerchantOrderID),
+		zap.String("orderID", event.OrderID),
+		zap.String("status", event.Status))
+
+	// Zota reports the amount it settled, which differs from the requested one when extraData.amountChanged is set
+	_, err = s.stateMachine.Settle(event.MerchantOrderID, event.Status, orderShared.SourceCallback, event.ErrorMessage, event.Amount)
+	switch {
+	case err == nil:
+		return event, nil
+	case errors.Is(err, orderShared.ErrOrderNotFound), errors.Is(err, orderShared.ErrIllegalTransition):
+		// a retry would not change the outcome, so the callback is acknowledged anyway
+		s.logger.Warn("Ignored order status callback", zap.String("merchantOrderID", event.MerchantOrderID), zap.Error(err))
+		return event, nil
+	default:
+		s.logger.Error("Failed to update order status", zap.String("merchantOrderID", event.MerchantOrderID), zap.Error(err))
+		return nil, err
+	}
+}
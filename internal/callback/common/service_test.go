@@ -0,0 +1,102 @@
+package common
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"zota-dev-challenge/internal/callback/common/zota"
+	"zota-dev-challenge/internal/callback/shared"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+type serviceTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockGateway *zota.MockCallbackPaymentGateway
+	logger      *zap.Logger
+	orders      *order.MemoryRepository
+	service     *Service
+	body        []byte
+}
+
+func (s *serviceTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockGateway = zota.NewMockCallbackPaymentGateway(s.mockCtrl)
+	s.logger, _ = zap.NewDevelopment()
+
+	cfg := &config.Config{}
+
+	s.orders = order.NewMemoryRepository()
+	s.service = NewService(s.logger, cfg, s.mockGateway, order.NewStateMachine(s.logger, s.orders, metrics.New()))
+	s.body = []byte(`{"orderID":"order123"}`)
+}
+
+func (s *serviceTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func TestProcessCallback_Success(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID: "merchantOrder123",
+		Status:          orderShared.StatusPending,
+	}))
+
+	expectedEvent := &shared.Event{
+		OrderID:         "order123",
+		MerchantOrderID: "merchantOrder123",
+		Status:          orderShared.StatusApproved,
+	}
+
+	s.mockGateway.EXPECT().ParseCallback(s.body).Return(expectedEvent, nil)
+
+	event, err := s.service.ProcessCallback(context.Background(), s.body)
+	require.NoError(t, err)
+	assert.Equal(t, expectedEvent, event)
+
+	stored, err := s.orders.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, stored.Status)
+
+	transitions, err := s.orders.ListTransitions("merchantOrder123")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, orderShared.SourceCallback, transitions[0].Source)
+}
+
+func TestProcessCallback_UnknownOrderIsAcknowledged(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	expectedEvent := &shared.Event{
+		MerchantOrderID: "missing",
+		Status:          orderShared.StatusApproved,
+	}
+
+	s.mockGateway.EXPECT().ParseCallback(s.body).Return(expectedEvent, nil)
+
+	event, err := s.service.ProcessCallback(context.Background(), s.body)
+	require.NoError(t, err)
+	assert.Equal(t, expectedEvent, event)
+}
+
+func TestProcessCallback_InvalidSignature(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockGateway.EXPECT().ParseCallback(s.body).Return(nil, shared.ErrInvalidSignature)
+
+	event, err := s.service.ProcessCallback(context.Background(), s.body)
+	assert.ErrorIs(t, err, shared.ErrInvalidSignature)
+	assert.Nil(t, event)
+}
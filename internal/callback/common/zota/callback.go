@@ -0,0 +1,89 @@
+package zota
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"zota-dev-challenge/internal/callback/shared"
+	"zota-dev-challenge/internal/config"
+)
+
+// CallbackRequest - the payload Zota posts to the merchant's callbackUrl once an order reaches a final status
+type CallbackRequest struct {
+	Type                   string          `json:"type"`
+	Status                 string          `json:"status"`
+	ErrorMessage           string          `json:"errorMessage,omitempty"`
+	EndpointID             string          `json:"endpointID"`
+	ProcessorTransactionID string          `json:"processorTransactionID"`
+	OrderID                string          `json:"orderID"`
+	MerchantOrderID        string          `json:"merchantOrderID"`
+	Amount                 string          `json:"amount"`
+	Currency               string          `json:"currency"`
+	CustomerEmail          string          `json:"customerEmail"`
+	CustomParam            string          `json:"customParam"`
+	ExtraData              json.RawMessage `json:"extraData,omitempty"`
+	OriginalRequest        json.RawMessage `json:"originalRequest,omitempty"`
+	Signature              string          `json:"signature"`
+}
+
+type CallbackGateway struct {
+	logger *zap.Logger
+	config *config.Config
+}
+
+func NewCallbackGateway(logger *zap.Logger, config *config.Config) *CallbackGateway {
+	return &CallbackGateway{logger: logger, config: config}
+}
+
+func (c *CallbackGateway) ParseCallback(body []byte) (*shared.Event, error) {
+	var callbackReq CallbackRequest
+	if err := json.Unmarshal(body, &callbackReq); err != nil {
+		c.logger.Error("Failed to unmarshal callback body", zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", shared.ErrMalformedCallback, err)
+	}
+
+	if callbackReq.OrderID == "" || callbackReq.MerchantOrderID == "" || callbackReq.Status == "" || callbackReq.Signature == "" {
+		c.logger.Error("Callback is missing required fields",
+			zap.String("orderID", callbackReq.OrderID),
+			zap.String("merchantOrderID", callbackReq.MerchantOrderID))
+		return nil, fmt.Errorf("%w: missing required fields", shared.ErrMalformedCallback)
+	}
+
+	if !c.verifySignature(callbackReq) {
+		c.logger.Error("Callback signature mismatch",
+			zap.String("orderID", callbackReq.OrderID),
+			zap.String("merchantOrderID", callbackReq.MerchantOrderID))
+		return nil, shared.ErrInvalidSignature
+	}
+
+	return &shared.Event{
+		Type:                   callbackReq.Type,
+		Status:                 callbackReq.Status,
+		ErrorMessage:           callbackReq.ErrorMessage,
+		EndpointID:             callbackReq.EndpointID,
+		ProcessorTransactionID: callbackReq.ProcessorTransactionID,
+		OrderID:                callbackReq.OrderID,
+		MerchantOrderID:        callbackReq.MerchantOrderID,
+		Amount:                 callbackReq.Amount,
+		Currency:               callbackReq.Currency,
+		CustomerEmail:          callbackReq.CustomerEmail,
+		CustomParam:            callbackReq.CustomParam,
+	}, nil
+}
+
+// verifySignature compares the received signature with the expected one in constant time
+func (c *CallbackGateway) verifySignature(callbackReq CallbackRequest) bool {
+	expected := c.buildSignature(callbackReq)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(callbackReq.Signature)) == 1
+}
+
+func (c *CallbackGateway) buildSignature(callbackReq CallbackRequest) string {
+	signatureString := fmt.Sprintf("%s%s%s%s%s%s%s", callbackReq.EndpointID, callbackReq.OrderID, callbackReq.MerchantOrderID,
+		callbackReq.Status, callbackReq.Amount, callbackReq.CustomerEmail, c.config.ZotaAPISecretKey)
+	hash := sha256.New()
+	hash.Write([]byte(signatureString))
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,107 @@
+package zota
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"zota-dev-challenge/internal/callback/shared"
+	"zota-dev-challenge/internal/config"
+)
+
+type callbackGatewayTestSuite struct {
+	logger          *zap.Logger
+	config          *config.Config
+	callbackGateway *CallbackGateway
+	request         CallbackRequest
+}
+
+func (s *callbackGatewayTestSuite) setup(t *testing.T) {
+	s.logger, _ = zap.NewDevelopment()
+
+	s.config = &config.Config{
+		ZotaMerchantId:   "merchant123",
+		ZotaAPISecretKey: "secret123",
+		ZotaEndpointId:   "endpoint123",
+	}
+
+	s.callbackGateway = NewCallbackGateway(s.logger, s.config)
+
+	s.request = CallbackRequest{
+		Type:            "SALE",
+		Status:          "APPROVED",
+		EndpointID:      "endpoint123",
+		OrderID:         "order123",
+		MerchantOrderID: "merchantOrder123",
+		Amount:          "100.00",
+		Currency:        "USD",
+		CustomerEmail:   "test@example.com",
+		CustomParam:     `{"UserId":"user123"}`,
+	}
+	s.request.Signature = s.callbackGateway.buildSignature(s.request)
+}
+
+func TestParseCallback_Success(t *testing.T) {
+	s := &callbackGatewayTestSuite{}
+	s.setup(t)
+
+	body, _ := json.Marshal(s.request)
+
+	event, err := s.callbackGateway.ParseCallback(body)
+	require.NoError(t, err)
+	assert.Equal(t, "APPROVED", event.Status)
+	assert.Equal(t, "order123", event.OrderID)
+	assert.Equal(t, "merchantOrder123", event.MerchantOrderID)
+	assert.Equal(t, "100.00", event.Amount)
+	assert.Equal(t, "USD", event.Currency)
+}
+
+func TestParseCallback_InvalidSignature(t *testing.T) {
+	s := &callbackGatewayTestSuite{}
+	s.setup(t)
+
+	s.request.Amount = "1000.00"
+	body, _ := json.Marshal(s.request)
+
+	event, err := s.callbackGateway.ParseCallback(body)
+	assert.ErrorIs(t, err, shared.ErrInvalidSignature)
+	assert.Nil(t, event)
+}
+
+func TestParseCallback_MalformedBody(t *testing.T) {
+	s := &callbackGatewayTestSuite{}
+	s.setup(t)
+
+	event, err := s.callbackGateway.ParseCallback([]byte("invalid JSON"))
+	assert.ErrorIs(t, err, shared.ErrMalformedCallback)
+	assert.Nil(t, event)
+}
+
+func TestParseCallback_MissingFields(t *testing.T) {
+	s := &callbackGatewayTestSuite{}
+	s.setup(t)
+
+	s.request.MerchantOrderID = ""
+	body, _ := json.Marshal(s.request)
+
+	event, err := s.callbackGateway.ParseCallback(body)
+	assert.ErrorIs(t, err, shared.ErrMalformedCallback)
+	assert.Nil(t, event)
+}
+
+func TestBuildSignature(t *testing.T) {
+	s := &callbackGatewayTestSuite{}
+	s.setup(t)
+
+	signature := s.callbackGateway.buildSignature(s.request)
+
+	expectedSignatureString := fmt.Sprintf("%s%s%s%s%s%s%s", s.request.EndpointID, s.request.OrderID, s.request.MerchantOrderID,
+		s.request.Status, s.request.Amount, s.request.CustomerEmail, s.config.ZotaAPISecretKey)
+	hash := sha256.New()
+	hash.Write([]byte(expectedSignatureString))
+	assert.Equal(t, hex.EncodeToString(hash.Sum(nil)), signature)
+}
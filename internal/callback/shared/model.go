@@ -0,0 +1,29 @@
+package shared
+
+import "errors"
+
+var (
+	// ErrMalformedCallback - the callback body could not be parsed
+	ErrMalformedCallback = errors.New("malformed callback payload")
+	// ErrInvalidSignature - the callback signature does not match the payload
+	ErrInvalidSignature = errors.New("invalid callback signature")
+)
+
+// Event - verified order status notification received from the payment gateway
+type Event struct {
+	Type                   string `json:"type"`
+	Status                 string `json:"status"`
+	ErrorMessage           string `json:"errorMessage,omitempty"`
+	EndpointID             string `json:"endpointID"`
+	ProcessorTransactionID string `json:"processorTransactionID"`
+	OrderID                string `json:"orderID"`
+	MerchantOrderID        string `json:"merchantOrderID"`
+	Amount                 string `json:"amount"`
+	Currency               string `json:"currency"`
+	CustomerEmail          string `json:"customerEmail"`
+	CustomParam            string `json:"customParam"`
+}
+
+type CallbackPaymentGateway interface {
+	ParseCallback(body []byte) (*Event, error)
+}
@@ -1,39 +1,279 @@
 package config
 
 import (
-	"github.com/joho/godotenv"
-	"go.uber.org/zap"
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// legacyEndpointCurrency - the currency ZOTA_ENDPOINT_ID was used for before the per-currency endpoints
+	legacyEndpointCurrency = "USD"
+
+	// defaultEnvFile - read when CONFIG_FILE does not name another file, it is optional
+	defaultEnvFile = ".env"
 )
 
 type Config struct {
 	ZotaMerchantId         string
 	ZotaAPISecretKey       string
 	ZotaEndpointId         string
+	ZotaEndpointIds        map[string]string // currency -> endpoint ID
 	ZotaBaseUrl            string
 	ZotaDepositCallBackUrl string
 	ZotaDepositRedirectUrl string
-	ENV                    string
+	ZotaPayoutEndpointIds  map[string]string // currency -> payout endpoint ID, the deposit endpoint serves the other currencies
+	ZotaPayoutCallBackUrl  string
+	Environment            string // sandbox or live, picks the Profile
+	Profile                Profile
+	Port                   int
+	ListenAddr             string
+	PublicBaseURL          string
+	TLSCertFile            string
+	TLSKeyFile             string
+	TLSClientCAFile        string
+	AdminAPIToken          string // bearer token of the admin routes, while it is unset they refuse every request
+	ServerReadTimeout      time.Duration
+	ServerHeaderTimeout    time.Duration
+	ServerWriteTimeout     time.Duration
+	ServerIdleTimeout      time.Duration
+	ServerMaxHeaderBytes   int
+	ServerDrainDelay       time.Duration
+	LogLevel               zapcore.Level
+	// LogUnredacted - log customer data and signatures as they are, for debugging in the sandbox only
+	LogUnredacted   bool
+	OrderStore      string
+	OrderStoreDSN   string
+	LedgerStoreDSN  string // the ledger has its own database, of the kind picked by ORDER_STORE
+	WebhookStoreDSN string // so do the webhook deliveries
+	// LedgerSweepInterval - how often approved deposits missing from the ledger are looked for and credited
+	LedgerSweepInterval time.Duration
+	// LedgerSweepWindow - how far back a sweep looks for approved deposits
+	LedgerSweepWindow    time.Duration
+	PollerInterval       time.Duration
+	PollerMaxBackoff     time.Duration
+	PollerConcurrency    int
+	PollerBatchSize      int
+	IdempotencyTTL       time.Duration
+	ZotaDepositTimeout   time.Duration
+	ZotaStatusTimeout    time.Duration
+	ZotaPayoutTimeout    time.Duration
+	ZotaReportTimeout    time.Duration // bounds reading a whole orders report, the report routes add it to ServerWriteTimeout
+	ZotaConnectTimeout   time.Duration
+	ZotaReadTimeout      time.Duration
+	ZotaMaxIdleConns     int
+	ZotaMaxAttempts      int
+	ZotaRetryBaseDelay   time.Duration
+	ZotaRetryMaxDelay    time.Duration
+	ZotaBreakerThreshold int
+	ZotaBreakerCooldown  time.Duration
+	HealthCheckTimeout   time.Duration
+	HealthZotaProbeTTL   time.Duration
+	TracingExporter      string
+	TracingOTLPEndpoint  string
+	TracingServiceName   string
+	Webhooks             []WebhookSubscription
+	// WebhookMaxAttempts - how often a delivery is tried before it becomes a dead letter
+	WebhookMaxAttempts    int
+	WebhookRetryBaseDelay time.Duration
+	WebhookRetryMaxDelay  time.Duration
+	WebhookTimeout        time.Duration
+	WebhookConcurrency    int
+	// WebhookRetention - how long delivered events stay in the delivery log, dead letters are kept
+	WebhookRetention time.Duration
+	// ReconciliationEnabled - runs the reconciliation with Zota's orders report on a schedule,
+	// the reconcile command works either way
+	ReconciliationEnabled  bool
+	ReconciliationInterval time.Duration
+	// ReconciliationDays - how many days up to today a scheduled reconciliation covers
+	ReconciliationDays int
+	// ReconciliationAutoCorrect - moves non-final local orders to the status Zota reports
+	ReconciliationAutoCorrect bool
+	ReconciliationReportDir   string
 }
 
-func New(logger *zap.Logger) *Config {
-	err := godotenv.Load()
-	if err != nil {
-		logger.Error("Error loading .env file", zap.Error(err))
+// New loads the configuration from the defaults, the optional env file and the process environment,
+// in increasing priority. Any invalid or missing setting fails with all the problems at once.
+func New() (*Config, error) {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = defaultEnvFile
 	}
 
-	env, err := godotenv.Read()
+	env, err := loadEnv(file, os.Environ())
 	if err != nil {
-		logger.Error("Error reading .env file", zap.Error(err))
+		return nil, err
+	}
+	return Parse(env)
+}
+
+// Parse builds and validates the configuration from already loaded variables
+func Parse(env map[string]string) (*Config, error) {
+	r := &reader{env: env}
+
+	environment := strings.ToLower(r.string("ENVIRONMENT", EnvironmentSandbox))
+	profile, ok := profiles[environment]
+	if !ok {
+		r.fail("ENVIRONMENT must be sandbox or live, got %q", environment)
+		profile = profiles[EnvironmentSandbox]
+	}
+	// the Zota settings may be given per environment, e.g. LIVE_ZOTA_MERCHANT_ID
+	pr := profileReader{reader: r, profile: profile}
+
+	endpointIds, invalidMappings := parseEndpoints(pr.string("ZOTA_ENDPOINTS", ""), pr.string("ZOTA_ENDPOINT_ID", ""))
+	for _, mapping := range invalidMappings {
+		r.fail("ZOTA_ENDPOINTS has an invalid currency:endpoint mapping %q", mapping)
+	}
+	payoutEndpointIds, invalidMappings := parseEndpoints(pr.string("ZOTA_PAYOUT_ENDPOINTS", ""), pr.string("ZOTA_PAYOUT_ENDPOINT_ID", ""))
+	for _, mapping := range invalidMappings {
+		r.fail("ZOTA_PAYOUT_ENDPOINTS has an invalid currency:endpoint mapping %q", mapping)
+	}
+
+	config := &Config{
+		ZotaMerchantId:         pr.string("ZOTA_MERCHANT_ID", ""),
+		ZotaAPISecretKey:       pr.string("ZOTA_API_SECRET_KEY", ""),
+		ZotaEndpointId:         pr.string("ZOTA_ENDPOINT_ID", ""),
+		ZotaEndpointIds:        endpointIds,
+		ZotaBaseUrl:            strings.TrimSuffix(pr.string("ZOTA_BASE_URL", profile.BaseURL), "/"),
+		ZotaDepositCallBackUrl: r.string("ZOTA_DEPOSIT_CALLBACK_URL", ""),
+		ZotaDepositRedirectUrl: r.string("ZOTA_DEPOSIT_REDIRECT_URL", ""),
+		ZotaPayoutEndpointIds:  payoutEndpointIds,
+		ZotaPayoutCallBackUrl:  r.string("ZOTA_PAYOUT_CALLBACK_URL", ""),
+		Environment:            profile.Name,
+		Profile:                profile,
+		Port:                   r.int("PORT", 8080),
+		TLSCertFile:            r.string("TLS_CERT_FILE", ""),
+		TLSKeyFile:             r.string("TLS_KEY_FILE", ""),
+		TLSClientCAFile:        r.string("TLS_CLIENT_CA_FILE", ""),
+		AdminAPIToken:          r.string("ADMIN_API_TOKEN", ""),
+		ServerReadTimeout:      r.duration("SERVER_READ_TIMEOUT", 15*time.Second),
+		ServerHeaderTimeout:    r.duration("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+		ServerWriteTimeout:     r.duration("SERVER_WRITE_TIMEOUT", 60*time.Second),
+		ServerIdleTimeout:      r.duration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+		ServerMaxHeaderBytes:   r.int("SERVER_MAX_HEADER_BYTES", 1<<20),
+		ServerDrainDelay:       r.duration("SERVER_DRAIN_DELAY", 5*time.Second),
+		LogLevel:               r.logLevel("LOG_LEVEL", zapcore.InfoLevel),
+		LogUnredacted:          r.bool("LOG_UNREDACTED", false),
+		OrderStore:             r.string("ORDER_STORE", ""),
+		OrderStoreDSN:          r.string("ORDER_STORE_DSN", ""),
+		LedgerStoreDSN:         r.string("LEDGER_STORE_DSN", ""),
+		WebhookStoreDSN:        r.string("WEBHOOK_STORE_DSN", ""),
+		LedgerSweepInterval:    r.duration("LEDGER_SWEEP_INTERVAL", 10*time.Minute),
+		LedgerSweepWindow:      r.duration("LEDGER_SWEEP_WINDOW", 7*24*time.Hour),
+		PollerInterval:         r.duration("POLLER_INTERVAL", 30*time.Second),
+		PollerMaxBackoff:       r.duration("POLLER_MAX_BACKOFF", time.Hour),
+		PollerConcurrency:      r.int("POLLER_CONCURRENCY", 5),
+		PollerBatchSize:        r.int("POLLER_BATCH_SIZE", 500),
+		IdempotencyTTL:         r.duration("IDEMPOTENCY_TTL", 24*time.Hour),
+		ZotaDepositTimeout:     r.duration("ZOTA_DEPOSIT_TIMEOUT", 30*time.Second),
+		ZotaStatusTimeout:      r.duration("ZOTA_STATUS_TIMEOUT", 10*time.Second),
+		ZotaPayoutTimeout:      r.duration("ZOTA_PAYOUT_TIMEOUT", 30*time.Second),
+		ZotaReportTimeout:      r.duration("ZOTA_REPORT_TIMEOUT", 2*time.Minute),
+		ZotaConnectTimeout:     r.duration("ZOTA_CONNECT_TIMEOUT", 5*time.Second),
+		ZotaReadTimeout:        r.duration("ZOTA_READ_TIMEOUT", 30*time.Second),
+		ZotaMaxIdleConns:       r.int("ZOTA_MAX_IDLE_CONNS", 10),
+		ZotaMaxAttempts:        r.int("ZOTA_MAX_ATTEMPTS", 3),
+		ZotaRetryBaseDelay:     r.duration("ZOTA_RETRY_BASE_DELAY", 200*time.Millisecond),
+		ZotaRetryMaxDelay:      r.duration("ZOTA_RETRY_MAX_DELAY", 2*time.Second),
+		ZotaBreakerThreshold:   r.nonNegativeInt("ZOTA_BREAKER_THRESHOLD", 5),
+		ZotaBreakerCooldown:    r.duration("ZOTA_BREAKER_COOLDOWN", 30*time.Second),
+		HealthCheckTimeout:     r.duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		HealthZotaProbeTTL:     r.duration("HEALTH_ZOTA_PROBE_TTL", 30*time.Second),
+		TracingExporter:        strings.ToLower(r.string("TRACING_EXPORTER", "none")),
+		TracingOTLPEndpoint:    r.string("TRACING_OTLP_ENDPOINT", ""),
+		TracingServiceName:     r.string("TRACING_SERVICE_NAME", "merchant-server"),
+		Webhooks:               parseWebhooks(r),
+		WebhookMaxAttempts:     r.int("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookRetryBaseDelay:  r.duration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+		WebhookRetryMaxDelay:   r.duration("WEBHOOK_RETRY_MAX_DELAY", time.Hour),
+		WebhookTimeout:         r.duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookConcurrency:     r.int("WEBHOOK_CONCURRENCY", 5),
+		WebhookRetention:       r.duration("WEBHOOK_RETENTION", 7*24*time.Hour),
+		ReconciliationEnabled:  r.bool("RECONCILIATION_ENABLED", false),
+		ReconciliationInterval: r.duration("RECONCILIATION_INTERVAL", 24*time.Hour),
+		ReconciliationDays:     r.int("RECONCILIATION_DAYS", 2),
+		// orders are only corrected when asked for, a report bug must not rewrite them
+		ReconciliationAutoCorrect: r.bool("RECONCILIATION_AUTO_CORRECT", false),
+		ReconciliationReportDir:   r.string("RECONCILIATION_REPORT_DIR", "reconciliation"),
+	}
+
+	// the listen address and the public URL follow PORT unless set explicitly
+	config.ListenAddr = r.string("LISTEN_ADDR", fmt.Sprintf(":%d", config.Port))
+	config.PublicBaseURL = strings.TrimSuffix(r.string("PUBLIC_BASE_URL", config.defaultPublicBaseURL()), "/")
+
+	config.validate(r)
+	config.validateProfile(pr)
+	if err := r.err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the loaded configuration again, e.g. for the readiness probe
+func (c *Config) Validate() error {
+	r := &reader{}
+	c.validate(r)
+	return r.err()
+}
+
+// IsLive reports whether the orders move real money
+func (c *Config) IsLive() bool {
+	return c.Environment == EnvironmentLive
+}
+
+// TLSEnabled reports whether the server terminates TLS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
+func (c *Config) defaultPublicBaseURL() string {
+	scheme := "http"
+	if c.TLSEnabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, c.Port)
+}
+
+// EndpointID returns the Zota endpoint configured for the currency
+func (c *Config) EndpointID(currency string) (string, bool) {
+	endpointID, ok := c.ZotaEndpointIds[currency]
+	return endpointID, ok && endpointID != ""
+}
+
+// PayoutEndpointID returns the Zota endpoint payouts in the currency are sent to, the deposit endpoint unless a payout
+// endpoint is configured for it
+func (c *Config) PayoutEndpointID(currency string) (string, bool) {
+	if endpointID, ok := c.ZotaPayoutEndpointIds[currency]; ok && endpointID != "" {
+		return endpointID, true
+	}
+	return c.EndpointID(currency)
+}
+
+// parseEndpoints reads the "USD:1050,EUR:1051" currency to endpoint mapping,
+// the legacy single endpoint keeps serving USD unless it is mapped explicitly
+func parseEndpoints(value, legacyEndpointID string) (map[string]string, []string) {
+	endpoints := make(map[string]string)
+	var invalid []string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		currency, endpointID, ok := strings.Cut(pair, ":")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		endpointID = strings.TrimSpace(endpointID)
+		if !ok || currency == "" || endpointID == "" {
+			invalid = append(invalid, pair)
+			continue
+		}
+		endpoints[currency] = endpointID
 	}
 
-	// Read environment variables and assign them to the configuration variables
-	return &Config{
-		ZotaMerchantId:         env["ZOTA_MERCHANT_ID"],
-		ZotaAPISecretKey:       env["ZOTA_API_SECRET_KEY"],
-		ZotaEndpointId:         env["ZOTA_ENDPOINT_ID"],
-		ZotaBaseUrl:            env["ZOTA_BASE_URL"],
-		ZotaDepositCallBackUrl: env["ZOTA_DEPOSIT_CALLBACK_URL"],
-		ZotaDepositRedirectUrl: env["ZOTA_DEPOSIT_REDIRECT_URL"],
-		ENV:                    env["ENVIRONMENT"],
+	if _, ok := endpoints[legacyEndpointCurrency]; !ok && legacyEndpointID != "" {
+		endpoints[legacyEndpointCurrency] = legacyEndpointID
 	}
+	return endpoints, invalid
 }
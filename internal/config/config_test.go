@@ -0,0 +1,411 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// validEnv - the smallest environment the application starts with
+func validEnv() map[string]string {
+	return map[string]string{
+		"ZOTA_MERCHANT_ID":          "merchant",
+		"ZOTA_API_SECRET_KEY":       "secret",
+		"ZOTA_ENDPOINT_ID":          "1050",
+		"ZOTA_BASE_URL":             "https://api.zotapay-stage.com/",
+		"ZOTA_DEPOSIT_CALLBACK_URL": "https://merchant.example.com/api/v1/callback/deposit",
+		"ZOTA_DEPOSIT_REDIRECT_URL": "https://merchant.example.com/return",
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, invalid := parseEndpoints("usd:1050, EUR:1051,broken,GBP:", "")
+	assert.Equal(t, map[string]string{"USD": "1050", "EUR": "1051"}, endpoints)
+	assert.Equal(t, []string{"broken", "GBP:"}, invalid)
+}
+
+func TestParseEndpoints_LegacyEndpointServesUSD(t *testing.T) {
+	endpoints, _ := parseEndpoints("EUR:1051", "1000")
+	assert.Equal(t, map[string]string{"USD": "1000", "EUR": "1051"}, endpoints)
+
+	endpoints, _ = parseEndpoints("USD:1050", "1000")
+	assert.Equal(t, map[string]string{"USD": "1050"}, endpoints)
+}
+
+func TestEndpointID(t *testing.T) {
+	cfg := &Config{ZotaEndpointIds: map[string]string{"USD": "1050"}}
+
+	endpointID, ok := cfg.EndpointID("USD")
+	assert.True(t, ok)
+	assert.Equal(t, "1050", endpointID)
+
+	_, ok = cfg.EndpointID("EUR")
+	assert.False(t, ok)
+}
+
+func TestParse_Defaults(t *testing.T) {
+	cfg, err := Parse(validEnv())
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://api.zotapay-stage.com", cfg.ZotaBaseUrl)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, zapcore.InfoLevel, cfg.LogLevel)
+	assert.Equal(t, 30*time.Second, cfg.PollerInterval)
+	assert.Equal(t, 10*time.Second, cfg.ZotaStatusTimeout)
+	assert.Equal(t, map[string]string{"USD": "1050"}, cfg.ZotaEndpointIds)
+}
+
+func TestParse_TypedSettings(t *testing.T) {
+	env := validEnv()
+	env["PORT"] = "9090"
+	env["LOG_LEVEL"] = "debug"
+	env["ZOTA_STATUS_TIMEOUT"] = "3s"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, zapcore.DebugLevel, cfg.LogLevel)
+	assert.Equal(t, 3*time.Second, cfg.ZotaStatusTimeout)
+}
+
+func TestParse_AggregatesProblems(t *testing.T) {
+	env := validEnv()
+	delete(env, "ZOTA_API_SECRET_KEY")
+	delete(env, "ZOTA_ENDPOINT_ID")
+	env["ZOTA_BASE_URL"] = "api.zotapay.com"
+	env["PORT"] = "http"
+	env["LOG_LEVEL"] = "loud"
+	env["ORDER_STORE"] = "postgres"
+
+	cfg, err := Parse(env)
+	assert.Nil(t, cfg)
+
+	var configErr *Error
+	require.True(t, errors.As(err, &configErr))
+	assert.ElementsMatch(t, []string{
+		`PORT must be a positive number, got "http"`,
+		`LOG_LEVEL must be one of debug, info, warn, error, got "loud"`,
+		"ZOTA_API_SECRET_KEY is required",
+		"ZOTA_ENDPOINTS or ZOTA_ENDPOINT_ID is required",
+		"no payout endpoint can be resolved, set ZOTA_PAYOUT_ENDPOINTS or ZOTA_ENDPOINTS",
+		`ZOTA_BASE_URL must be an absolute http(s) URL, got "api.zotapay.com"`,
+		`ORDER_STORE must be memory or sqlite, got "postgres"`,
+	}, configErr.Problems)
+	assert.Contains(t, err.Error(), "invalid configuration:\n  - ")
+}
+
+func TestParse_InvalidEndpointMapping(t *testing.T) {
+	env := validEnv()
+	env["ZOTA_ENDPOINTS"] = "EUR"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, `ZOTA_ENDPOINTS has an invalid currency:endpoint mapping "EUR"`)
+}
+
+func TestParse_PayoutEndpointsWithOnlyEndpointMapping(t *testing.T) {
+	env := validEnv()
+	delete(env, "ZOTA_ENDPOINT_ID")
+	env["ZOTA_ENDPOINTS"] = "USD:1050,EUR:1051"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	endpointID, ok := cfg.PayoutEndpointID("EUR")
+	assert.True(t, ok)
+	assert.Equal(t, "1051", endpointID, "payouts fall back to the deposit endpoint of their currency")
+
+	env["ZOTA_PAYOUT_ENDPOINTS"] = "EUR:2051"
+	cfg, err = Parse(env)
+	require.NoError(t, err)
+	endpointID, _ = cfg.PayoutEndpointID("EUR")
+	assert.Equal(t, "2051", endpointID)
+	endpointID, _ = cfg.PayoutEndpointID("USD")
+	assert.Equal(t, "1050", endpointID)
+	_, ok = cfg.PayoutEndpointID("GBP")
+	assert.False(t, ok)
+
+	env["ZOTA_PAYOUT_ENDPOINTS"] = "EUR"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, `ZOTA_PAYOUT_ENDPOINTS has an invalid currency:endpoint mapping "EUR"`)
+}
+
+func TestLoadEnv_EnvironmentOverridesFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(file, []byte("ZOTA_MERCHANT_ID=from-file\nPORT=9090\n"), 0o600))
+
+	env, err := loadEnv(file, []string{"ZOTA_MERCHANT_ID=from-env", "EMPTY="})
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", env["ZOTA_MERCHANT_ID"])
+	assert.Equal(t, "9090", env["PORT"])
+	assert.Contains(t, env, "EMPTY")
+}
+
+func TestLoadEnv_MissingFileIsOptional(t *testing.T) {
+	env, err := loadEnv(filepath.Join(t.TempDir(), "missing.env"), []string{"PORT=9090"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "9090"}, env)
+}
+
+func TestParse_ServerSettings(t *testing.T) {
+	env := validEnv()
+	env["PORT"] = "9443"
+	env["TLS_CERT_FILE"] = "cert.pem"
+	env["TLS_KEY_FILE"] = "key.pem"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, ":9443", cfg.ListenAddr)
+	assert.Equal(t, "https://localhost:9443", cfg.PublicBaseURL)
+	assert.Equal(t, 60*time.Second, cfg.ServerWriteTimeout)
+
+	env["LISTEN_ADDR"] = "127.0.0.1:9000"
+	env["PUBLIC_BASE_URL"] = "https://pay.example.com/merchant/"
+	cfg, err = Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9000", cfg.ListenAddr)
+	assert.Equal(t, "https://pay.example.com/merchant", cfg.PublicBaseURL)
+}
+
+func TestParse_TLSFilesGoTogether(t *testing.T) {
+	env := validEnv()
+	env["TLS_KEY_FILE"] = "key.pem"
+	env["TLS_CLIENT_CA_FILE"] = "ca.pem"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	assert.ErrorContains(t, err, "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+}
+
+func TestParse_TracingExporter(t *testing.T) {
+	env := validEnv()
+	env["TRACING_EXPORTER"] = "OTLP"
+	env["TRACING_OTLP_ENDPOINT"] = "http://collector:4318"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, "otlp", cfg.TracingExporter)
+	assert.Equal(t, "merchant-server", cfg.TracingServiceName)
+
+	env["TRACING_EXPORTER"] = "jaeger"
+	env["TRACING_OTLP_ENDPOINT"] = "collector"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, `TRACING_EXPORTER must be none, stdout or otlp, got "jaeger"`)
+	assert.ErrorContains(t, err, "TRACING_OTLP_ENDPOINT")
+}
+
+func TestParse_SandboxProfileByDefault(t *testing.T) {
+	env := validEnv()
+	delete(env, "ZOTA_BASE_URL")
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, EnvironmentSandbox, cfg.Environment)
+	assert.False(t, cfg.IsLive())
+	assert.True(t, cfg.Profile.DevelopmentLogger)
+	assert.Equal(t, "https://api.zotapay-sandbox.com", cfg.ZotaBaseUrl)
+}
+
+func TestParse_ProfilePicksEnvironmentSettings(t *testing.T) {
+	env := validEnv()
+	delete(env, "ZOTA_BASE_URL")
+	env["ENVIRONMENT"] = "LIVE"
+	env["LIVE_ZOTA_MERCHANT_ID"] = "live-merchant"
+	env["LIVE_ZOTA_API_SECRET_KEY"] = "live-secret"
+	env["LIVE_ZOTA_ENDPOINTS"] = "EUR:2051"
+	env["SANDBOX_ZOTA_MERCHANT_ID"] = "sandbox-merchant"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, EnvironmentLive, cfg.Environment)
+	assert.False(t, cfg.Profile.DevelopmentLogger)
+	assert.Equal(t, "https://api.zotapay.com", cfg.ZotaBaseUrl)
+	assert.Equal(t, "live-merchant", cfg.ZotaMerchantId)
+	assert.Equal(t, "live-secret", cfg.ZotaAPISecretKey)
+	// the shared ZOTA_ENDPOINT_ID still serves USD next to the live mapping
+	assert.Equal(t, map[string]string{"USD": "1050", "EUR": "2051"}, cfg.ZotaEndpointIds)
+}
+
+func TestParse_UnknownEnvironment(t *testing.T) {
+	env := validEnv()
+	env["ENVIRONMENT"] = "staging"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, `ENVIRONMENT must be sandbox or live, got "staging"`)
+}
+
+func TestParse_LiveRefusesSandboxSettings(t *testing.T) {
+	env := validEnv()
+	env["ENVIRONMENT"] = "live"
+	env["SANDBOX_ZOTA_MERCHANT_ID"] = "merchant"
+	env["SANDBOX_ZOTA_API_SECRET_KEY"] = "secret"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, `ZOTA_BASE_URL "https://api.zotapay-stage.com" belongs to the sandbox Zota API`)
+	assert.ErrorContains(t, err, "ZOTA_MERCHANT_ID is the sandbox merchant, it must not be used in the live environment")
+	assert.ErrorContains(t, err, "ZOTA_API_SECRET_KEY is the sandbox secret key, it must not be used in the live environment")
+
+	env["ZOTA_BASE_URL"] = "http://zota.internal"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, "ZOTA_BASE_URL must use https in the live environment")
+}
+
+func TestParse_UnredactedLogsOnlyInSandbox(t *testing.T) {
+	env := validEnv()
+	env["LOG_UNREDACTED"] = "true"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.True(t, cfg.LogUnredacted)
+
+	env["ENVIRONMENT"] = "live"
+	env["ZOTA_BASE_URL"] = "https://api.zotapay.com"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, "LOG_UNREDACTED is only allowed in the sandbox environment")
+
+	env["LOG_UNREDACTED"] = "yes please"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, `LOG_UNREDACTED must be true or false, got "yes please"`)
+}
+
+func TestParse_Webhooks(t *testing.T) {
+	env := validEnv()
+	env["WEBHOOKS"] = "ledger, crm-sync"
+	env["WEBHOOK_LEDGER_URL"] = "https://ledger.example.com/hooks"
+	env["WEBHOOK_LEDGER_SECRET"] = "ledger-secret"
+	env["WEBHOOK_LEDGER_EVENTS"] = "deposit.approved, Deposit.Declined, payout.*"
+	env["WEBHOOK_CRM_SYNC_URL"] = "https://crm.example.com/hooks"
+	env["WEBHOOK_CRM_SYNC_SECRET"] = "crm-secret"
+
+	cfg, err := Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, []WebhookSubscription{
+		{Name: "ledger", URL: "https://ledger.example.com/hooks", Secret: "ledger-secret",
+			Events: []string{"deposit.approved", "deposit.declined", "payout.*"}},
+		{Name: "crm-sync", URL: "https://crm.example.com/hooks", Secret: "crm-secret", Events: []string{"*"}},
+	}, cfg.Webhooks)
+	assert.Equal(t, 10, cfg.WebhookMaxAttempts)
+
+	env["WEBHOOKS"] = "ledger,ledger,Bad Name"
+	env["WEBHOOK_LEDGER_URL"] = "ledger.example.com"
+	env["WEBHOOK_LEDGER_SECRET"] = ""
+	env["WEBHOOK_LEDGER_EVENTS"] = "deposit.settled"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, `WEBHOOKS lists the subscription "ledger" twice`)
+	assert.ErrorContains(t, err, `WEBHOOKS has an invalid subscription name "bad name"`)
+	assert.ErrorContains(t, err, `WEBHOOK_LEDGER_URL must be an absolute http(s) URL, got "ledger.example.com"`)
+	assert.ErrorContains(t, err, "WEBHOOK_LEDGER_SECRET is required")
+	assert.ErrorContains(t, err, `WEBHOOK_LEDGER_EVENTS has an unknown event type "deposit.settled"`)
+}
+
+func TestParse_LiveWebhooksUseHTTPS(t *testing.T) {
+	env := validEnv()
+	env["ENVIRONMENT"] = "live"
+	env["ZOTA_BASE_URL"] = "https://api.zotapay.com"
+	env["WEBHOOKS"] = "ledger"
+	env["WEBHOOK_LEDGER_URL"] = "http://ledger.example.com/hooks"
+	env["WEBHOOK_LEDGER_SECRET"] = "ledger-secret"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, `the ledger webhook must use https in the live environment, got "http://ledger.example.com/hooks"`)
+}
+
+func TestParse_LedgerSweep(t *testing.T) {
+	cfg, err := Parse(validEnv())
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, cfg.LedgerSweepInterval)
+	assert.Equal(t, 7*24*time.Hour, cfg.LedgerSweepWindow)
+
+	env := validEnv()
+	env["LEDGER_SWEEP_INTERVAL"] = "1m"
+	env["LEDGER_SWEEP_WINDOW"] = "48h"
+	cfg, err = Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, cfg.LedgerSweepInterval)
+	assert.Equal(t, 48*time.Hour, cfg.LedgerSweepWindow)
+}
+
+func TestParse_BreakerThreshold(t *testing.T) {
+	cfg, err := Parse(validEnv())
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.ZotaBreakerThreshold)
+
+	env := validEnv()
+	env["ZOTA_BREAKER_THRESHOLD"] = "0"
+	cfg, err = Parse(env)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.ZotaBreakerThreshold, "0 disables the breaker")
+
+	env["ZOTA_BREAKER_THRESHOLD"] = "-1"
+	_, err = Parse(env)
+	var configErr *Error
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{`ZOTA_BREAKER_THRESHOLD must be 0 or a positive number, got "-1"`}, configErr.Problems)
+}
+
+func TestParse_Reconciliation(t *testing.T) {
+	cfg, err := Parse(validEnv())
+	require.NoError(t, err)
+	assert.False(t, cfg.ReconciliationEnabled)
+	assert.False(t, cfg.ReconciliationAutoCorrect)
+	assert.Equal(t, 24*time.Hour, cfg.ReconciliationInterval)
+	assert.Equal(t, 2, cfg.ReconciliationDays)
+	assert.Equal(t, "reconciliation", cfg.ReconciliationReportDir)
+
+	env := validEnv()
+	env["RECONCILIATION_ENABLED"] = "true"
+	env["RECONCILIATION_INTERVAL"] = "6h"
+	env["RECONCILIATION_DAYS"] = "7"
+	env["RECONCILIATION_AUTO_CORRECT"] = "true"
+	cfg, err = Parse(env)
+	require.NoError(t, err)
+	assert.True(t, cfg.ReconciliationEnabled)
+	assert.True(t, cfg.ReconciliationAutoCorrect)
+	assert.Equal(t, 6*time.Hour, cfg.ReconciliationInterval)
+	assert.Equal(t, 7, cfg.ReconciliationDays)
+
+	env["RECONCILIATION_DAYS"] = "0"
+	_, err = Parse(env)
+	assert.ErrorContains(t, err, `RECONCILIATION_DAYS must be a positive number, got "0"`)
+}
+
+func TestParse_SandboxRefusesLiveSettings(t *testing.T) {
+	env := validEnv()
+	env["ZOTA_BASE_URL"] = "https://api.zotapay.com"
+	env["LIVE_ZOTA_API_SECRET_KEY"] = "secret"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, "belongs to the live Zota API, which the sandbox environment must not use")
+	assert.ErrorContains(t, err, "ZOTA_API_SECRET_KEY is the live secret key")
+}
+
+func TestParse_RefusesTheOtherEnvironmentsEndpoints(t *testing.T) {
+	env := validEnv()
+	env["ENVIRONMENT"] = "live"
+	env["ZOTA_BASE_URL"] = "https://api.zotapay.com"
+	// the unprefixed endpoint is the sandbox one, only the prefix tells
+	env["SANDBOX_ZOTA_ENDPOINTS"] = "USD:1050,EUR:1051"
+	env["SANDBOX_ZOTA_PAYOUT_ENDPOINTS"] = "USD:1060"
+	env["LIVE_ZOTA_PAYOUT_ENDPOINTS"] = "USD:1060"
+
+	_, err := Parse(env)
+	assert.ErrorContains(t, err, "ZOTA_ENDPOINTS maps USD to the sandbox endpoint 1050, it must not be used in the live environment")
+	assert.ErrorContains(t, err, "ZOTA_PAYOUT_ENDPOINTS maps USD to the sandbox endpoint 1060")
+
+	env["LIVE_ZOTA_ENDPOINTS"] = "USD:2050"
+	env["LIVE_ZOTA_PAYOUT_ENDPOINTS"] = "USD:2060"
+	_, err = Parse(env)
+	assert.NoError(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Parse(validEnv())
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ZotaAPISecretKey = ""
+	assert.ErrorContains(t, cfg.Validate(), "ZOTA_API_SECRET_KEY is required")
+}
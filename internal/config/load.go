@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap/zapcore"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error - every problem found in the configuration, reported together so they can be fixed in one go
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// loadEnv layers the process environment over the env file, a missing file is not an error
+func loadEnv(file string, environ []string) (map[string]string, error) {
+	env, err := godotenv.Read(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		env = make(map[string]string)
+	} else if err != nil {
+		return nil, fmt.Errorf("read config file %s: %w", file, err)
+	}
+
+	for _, variable := range environ {
+		if key, value, ok := strings.Cut(variable, "="); ok {
+			env[key] = value
+		}
+	}
+	return env, nil
+}
+
+// reader reads typed settings, collecting the problems instead of stopping at the first one
+type reader struct {
+	env      map[string]string
+	problems []string
+}
+
+func (r *reader) fail(format string, args ...any) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+func (r *reader) err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: r.problems}
+}
+
+func (r *reader) string(key, def string) string {
+	value := strings.TrimSpace(r.env[key])
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+func (r *reader) duration(key string, def time.Duration) time.Duration {
+	value := r.string(key, "")
+	if value == "" {
+		return def
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		r.fail("%s must be a positive duration such as 30s, got %q", key, value)
+		return def
+	}
+	return duration
+}
+
+func (r *reader) int(key string, def int) int {
+	value := r.string(key, "")
+	if value == "" {
+		return def
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		r.fail("%s must be a positive number, got %q", key, value)
+		return def
+	}
+	return number
+}
+
+// nonNegativeInt reads a number that may be 0, for settings where 0 turns a feature off
+func (r *reader) nonNegativeInt(key string, def int) int {
+	value := r.string(key, "")
+	if value == "" {
+		return def
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		r.fail("%s must be 0 or a positive number, got %q", key, value)
+		return def
+	}
+	return number
+}
+
+func (r *reader) bool(key string, def bool) bool {
+	value := r.string(key, "")
+	if value == "" {
+		return def
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		r.fail("%s must be true or false, got %q", key, value)
+		return def
+	}
+	return enabled
+}
+
+func (r *reader) logLevel(key string, def zapcore.Level) zapcore.Level {
+	value := r.string(key, "")
+	if value == "" {
+		return def
+	}
+
+	level, err := zapcore.ParseLevel(value)
+	if err != nil {
+		r.fail("%s must be one of debug, info, warn, error, got %q", key, value)
+		return def
+	}
+	return level
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Environments a Zota merchant account lives in, each has its own credentials and base URL
+const (
+	EnvironmentSandbox = "sandbox"
+	EnvironmentLive    = "live"
+)
+
+// Profile - the settings an environment picks unless they are configured explicitly
+type Profile struct {
+	Name string
+	// BaseURL - the Zota API the environment talks to by default
+	BaseURL string
+	// Hosts - the Zota API hosts that belong to the environment, the other environment's hosts are refused
+	Hosts []string
+	// DevelopmentLogger - human-readable logs instead of JSON
+	DevelopmentLogger bool
+}
+
+var profiles = map[string]Profile{
+	EnvironmentSandbox: {
+		Name:              EnvironmentSandbox,
+		BaseURL:           "https://api.zotapay-sandbox.com",
+		Hosts:             []string{"api.zotapay-sandbox.com", "api.zotapay-stage.com"},
+		DevelopmentLogger: true,
+	},
+	EnvironmentLive: {
+		Name:    EnvironmentLive,
+		BaseURL: "https://api.zotapay.com",
+		Hosts:   []string{"api.zotapay.com", "mg-api.zotapay.com"},
+	},
+}
+
+// other returns the profile of the opposite environment
+func (p Profile) other() Profile {
+	if p.Name == EnvironmentLive {
+		return profiles[EnvironmentSandbox]
+	}
+	return profiles[EnvironmentLive]
+}
+
+// ownsHost reports whether the URL points to one of the environment's Zota hosts
+func (p Profile) ownsHost(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, known := range p.Hosts {
+		if host == known {
+			return true
+		}
+	}
+	return false
+}
+
+// envPrefix - the prefix of the settings that only apply to the environment, e.g. LIVE_ZOTA_MERCHANT_ID
+func (p Profile) envPrefix() string {
+	return strings.ToUpper(p.Name) + "_"
+}
+
+// profileReader reads the environment specific setting first and falls back to the shared one,
+// so both sets of credentials can live in one file and ENVIRONMENT picks between them
+type profileReader struct {
+	*reader
+	profile Profile
+}
+
+func (r profileReader) string(key, def string) string {
+	if value := r.reader.string(r.profile.envPrefix()+key, ""); value != "" {
+		return value
+	}
+	return r.reader.string(key, def)
+}
+
+// scoped returns the value configured for the profile only, without the shared fallback
+func (r profileReader) scoped(profile Profile, key string) string {
+	return r.reader.string(profile.envPrefix()+key, "")
+}
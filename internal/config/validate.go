@@ -0,0 +1,136 @@
+package config
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// order stores, kept in sync with the order repository
+var orderStores = map[string]bool{"": true, "memory": true, "sqlite": true}
+
+// tracing exporters, kept in sync with the tracing package
+var tracingExporters = map[string]bool{"none": true, "stdout": true, "otlp": true}
+
+func (c *Config) validate(r *reader) {
+	if c.ZotaMerchantId == "" {
+		r.fail("ZOTA_MERCHANT_ID is required")
+	}
+	if c.ZotaAPISecretKey == "" {
+		r.fail("ZOTA_API_SECRET_KEY is required")
+	}
+	if len(c.ZotaEndpointIds) == 0 {
+		r.fail("ZOTA_ENDPOINTS or ZOTA_ENDPOINT_ID is required")
+	}
+	if len(c.ZotaEndpointIds) == 0 && len(c.ZotaPayoutEndpointIds) == 0 {
+		r.fail("no payout endpoint can be resolved, set ZOTA_PAYOUT_ENDPOINTS or ZOTA_ENDPOINTS")
+	}
+
+	requireURL(r, "ZOTA_BASE_URL", c.ZotaBaseUrl)
+	requireURL(r, "ZOTA_DEPOSIT_CALLBACK_URL", c.ZotaDepositCallBackUrl)
+	requireURL(r, "ZOTA_DEPOSIT_REDIRECT_URL", c.ZotaDepositRedirectUrl)
+	if c.ZotaPayoutCallBackUrl != "" {
+		requireURL(r, "ZOTA_PAYOUT_CALLBACK_URL", c.ZotaPayoutCallBackUrl)
+	}
+
+	if c.Port > 65535 {
+		r.fail("PORT must be at most 65535, got %d", c.Port)
+	}
+	requireURL(r, "PUBLIC_BASE_URL", c.PublicBaseURL)
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		r.fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSClientCAFile != "" && !c.TLSEnabled() {
+		r.fail("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if !orderStores[c.OrderStore] {
+		r.fail("ORDER_STORE must be memory or sqlite, got %q", c.OrderStore)
+	}
+	if !tracingExporters[c.TracingExporter] {
+		r.fail("TRACING_EXPORTER must be none, stdout or otlp, got %q", c.TracingExporter)
+	}
+	if c.TracingOTLPEndpoint != "" {
+		requireURL(r, "TRACING_OTLP_ENDPOINT", c.TracingOTLPEndpoint)
+	}
+	if c.PollerMaxBackoff < c.PollerInterval {
+		r.fail("POLLER_MAX_BACKOFF must not be shorter than POLLER_INTERVAL")
+	}
+	if c.ZotaRetryMaxDelay < c.ZotaRetryBaseDelay {
+		r.fail("ZOTA_RETRY_MAX_DELAY must not be shorter than ZOTA_RETRY_BASE_DELAY")
+	}
+	c.validateWebhooks(r)
+}
+
+// requireURL - Zota only talks to absolute http(s) URLs
+func requireURL(r *reader, key, value string) {
+	if value == "" {
+		r.fail("%s is required", key)
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		r.fail("%s must be an absolute http(s) URL, got %q", key, value)
+	}
+}
+
+// validateProfile keeps the environments apart, test traffic must never reach live Zota and the reverse
+func (c *Config) validateProfile(r profileReader) {
+	other := r.profile.other()
+
+	if other.ownsHost(c.ZotaBaseUrl) {
+		r.fail("ZOTA_BASE_URL %q belongs to the %s Zota API, which the %s environment must not use",
+			c.ZotaBaseUrl, other.Name, r.profile.Name)
+	}
+	if c.IsLive() && !strings.HasPrefix(c.ZotaBaseUrl, "https://") {
+		r.fail("ZOTA_BASE_URL must use https in the live environment, got %q", c.ZotaBaseUrl)
+	}
+	for _, subscription := range c.Webhooks {
+		if c.IsLive() && !strings.HasPrefix(subscription.URL, "https://") {
+			r.fail("the %s webhook must use https in the live environment, got %q", subscription.Name, subscription.URL)
+		}
+	}
+	if c.IsLive() && c.LogUnredacted {
+		r.fail("LOG_UNREDACTED is only allowed in the sandbox environment, live logs must not carry customer data")
+	}
+
+	// The other environment's settings are only known when they are configured with its prefix. The settings in use,
+	// prefixed or not, are compared with them, but without them nothing tells which environment an unprefixed
+	// merchant ID, secret key or endpoint belongs to: a sandbox merchant set unprefixed passes in live.
+	if merchantID := r.scoped(other, "ZOTA_MERCHANT_ID"); merchantID != "" && merchantID == c.ZotaMerchantId {
+		r.fail("ZOTA_MERCHANT_ID is the %s merchant, it must not be used in the %s environment", other.Name, r.profile.Name)
+	}
+	if secretKey := r.scoped(other, "ZOTA_API_SECRET_KEY"); secretKey != "" && secretKey == c.ZotaAPISecretKey {
+		r.fail("ZOTA_API_SECRET_KEY is the %s secret key, it must not be used in the %s environment", other.Name, r.profile.Name)
+	}
+
+	otherEndpoints, _ := parseEndpoints(r.scoped(other, "ZOTA_ENDPOINTS"), r.scoped(other, "ZOTA_ENDPOINT_ID"))
+	otherPayoutEndpoints, _ := parseEndpoints(r.scoped(other, "ZOTA_PAYOUT_ENDPOINTS"), r.scoped(other, "ZOTA_PAYOUT_ENDPOINT_ID"))
+	otherEndpointIDs := make(map[string]bool, len(otherEndpoints)+len(otherPayoutEndpoints))
+	for _, endpointID := range otherEndpoints {
+		otherEndpointIDs[endpointID] = true
+	}
+	for _, endpointID := range otherPayoutEndpoints {
+		otherEndpointIDs[endpointID] = true
+	}
+	refuseOtherEndpoints := func(key string, endpoints map[string]string) {
+		for _, currency := range sortedKeys(endpoints) {
+			if otherEndpointIDs[endpoints[currency]] {
+				r.fail("%s maps %s to the %s endpoint %s, it must not be used in the %s environment",
+					key, currency, other.Name, endpoints[currency], r.profile.Name)
+			}
+		}
+	}
+	refuseOtherEndpoints("ZOTA_ENDPOINTS", c.ZotaEndpointIds)
+	refuseOtherEndpoints("ZOTA_PAYOUT_ENDPOINTS", c.ZotaPayoutEndpointIds)
+}
+
+// sortedKeys - the problems are reported in the same order on every start
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
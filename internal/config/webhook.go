@@ -0,0 +1,100 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WebhookSubscription - a downstream service told about order status changes
+type WebhookSubscription struct {
+	Name string
+	URL  string
+	// Events - the event types delivered, such as deposit.approved, deposit.* or *
+	Events []string
+	// Secret - signs every delivery, so the subscriber can check it came from us
+	Secret string
+}
+
+// webhookName - subscription names end up in env keys and metric labels
+var webhookName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// webhook event types, kept in sync with the webhook package
+var (
+	webhookOrderTypes = map[string]bool{"deposit": true, "payout": true}
+	webhookStatuses   = map[string]bool{
+		"created": true, "pending": true, "processing": true, "approved": true,
+		"declined": true, "filtered": true, "unknown": true, "error": true,
+	}
+)
+
+// parseWebhooks reads the subscriptions listed in WEBHOOKS, each configured with
+// WEBHOOK_<NAME>_URL, WEBHOOK_<NAME>_EVENTS and WEBHOOK_<NAME>_SECRET
+func parseWebhooks(r *reader) []WebhookSubscription {
+	var subscriptions []WebhookSubscription
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(r.string("WEBHOOKS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !webhookName.MatchString(name) {
+			r.fail("WEBHOOKS has an invalid subscription name %q, use lowercase letters, digits, - and _", name)
+			continue
+		}
+		if seen[name] {
+			r.fail("WEBHOOKS lists the subscription %q twice", name)
+			continue
+		}
+		seen[name] = true
+
+		prefix := "WEBHOOK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		subscriptions = append(subscriptions, WebhookSubscription{
+			Name:   name,
+			URL:    r.string(prefix+"URL", ""),
+			Events: splitList(r.string(prefix+"EVENTS", "*")),
+			Secret: r.string(prefix+"SECRET", ""),
+		})
+	}
+	return subscriptions
+}
+
+func (c *Config) validateWebhooks(r *reader) {
+	for _, subscription := range c.Webhooks {
+		prefix := "WEBHOOK_" + strings.ToUpper(strings.ReplaceAll(subscription.Name, "-", "_")) + "_"
+
+		requireURL(r, prefix+"URL", subscription.URL)
+		if subscription.Secret == "" {
+			r.fail("%sSECRET is required, subscribers check the signature with it", prefix)
+		}
+		if len(subscription.Events) == 0 {
+			r.fail("%sEVENTS must list at least one event type", prefix)
+		}
+		for _, event := range subscription.Events {
+			if !isWebhookEvent(event) {
+				r.fail("%sEVENTS has an unknown event type %q, expected e.g. deposit.approved, deposit.* or *", prefix, event)
+			}
+		}
+	}
+	if c.WebhookRetryMaxDelay < c.WebhookRetryBaseDelay {
+		r.fail("WEBHOOK_RETRY_MAX_DELAY must not be shorter than WEBHOOK_RETRY_BASE_DELAY")
+	}
+}
+
+// isWebhookEvent accepts *, <type>.* and <type>.<status>
+func isWebhookEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	orderType, status, ok := strings.Cut(event, ".")
+	return ok && webhookOrderTypes[orderType] && (status == "*" || webhookStatuses[status])
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"net/http"
-	_ "zota-dev-challenge/internal/deposit/common/zota"
+	"strconv"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/tracing"
 )
 
 // Handler
@@ -17,32 +19,52 @@ import (
 // @Tags deposit
 // @Accept json
 // @Produce json
-// @Param depositRequest body ClientRequest true "Deposit ClientRequest"
-// @Success 200 {object} _.DepositResponse "Deposit Successful"
+// @Param depositRequest body shared.ClientRequest true "Deposit ClientRequest"
+// @Param Idempotency-Key header string false "Repeats with the same key and body are answered with the first response"
+// @Param redirect query bool false "Answer with 303 See Other to the Zota payment page instead of JSON"
+// @Success 200 {object} shared.Response "Deposit Successful"
+// @Success 303 "Redirect to the Zota payment page"
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 409 {object} apperror.Response "Idempotency key reused with a different request"
+// @Failure 422 {object} apperror.Response "Unsupported currency or rejected by the payment gateway"
+// @Failure 502 {object} apperror.Response "Unexpected payment gateway response"
+// @Failure 503 {object} apperror.Response "Payment gateway unavailable"
 // @Router /deposit [post]
 func Handler(service ServiceInterface, logger *zap.Logger, validator *validator.Validate) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "deposit.Handler")
+		defer span.End()
+
 		var req shared.ClientRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			logger.Error("Failed to decode request body", zap.Error(err))
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apperror.Write(w, r, logger, apperror.InvalidRequest("invalid request body", err))
 			return
 		}
 
 		//validate request
 		if err := validator.Struct(req); err != nil {
 			logger.Error("Failed to validate request", zap.Error(err))
-			http.Error(w, "", http.StatusBadRequest)
+			apperror.Write(w, r, logger, apperror.FromValidation(err))
 			return
 		}
 
-		res, err := service.ProcessDeposit(&req)
+		res, err := service.ProcessDeposit(ctx, &req)
+		tracing.Record(span, err)
 		if err != nil {
 			logger.Error("Failed to process deposit", zap.Error(err))
-			http.Error(w, "Failed to process deposit", http.StatusInternalServerError)
+			apperror.Write(w, r, logger, err)
 			return
 		}
 
+		if redirect, _ := strconv.ParseBool(r.URL.Query().Get("redirect")); redirect {
+			if res.DepositUrl != "" {
+				http.Redirect(w, r, res.DepositUrl, http.StatusSeeOther)
+				return
+			}
+			logger.Warn("Redirect requested but no deposit URL was returned", zap.String("orderId", res.OrderID))
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		if err := json.NewEncoder(w).Encode(res); err != nil {
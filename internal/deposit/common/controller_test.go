@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/deposit/shared"
 )
 
@@ -56,8 +57,13 @@ func TestHandler(t *testing.T) {
 	defer teardown()
 
 	mockService.EXPECT().
-		ProcessDeposit(gomock.Any()).
-		Return(&shared.Response{requestPayload, "1", "11"}, nil)
+		ProcessDeposit(gomock.Any(), gomock.Any()).
+		Return(&shared.Response{
+			ClientRequest:         requestPayload,
+			OrderID:               "1",
+			PaymentGatewayOrderID: "11",
+			DepositUrl:            "https://example.com/deposit",
+		}, nil)
 
 	handler := Handler(mockService, logger, validate)
 
@@ -72,6 +78,33 @@ func TestHandler(t *testing.T) {
 	var response shared.Response
 	json.NewDecoder(rr.Body).Decode(&response)
 	assert.Equal(t, requestPayload, response.ClientRequest)
+	assert.Equal(t, "https://example.com/deposit", response.DepositUrl)
+}
+
+func TestHandler_Redirect(t *testing.T) {
+	setup(t)
+	defer teardown()
+
+	mockService.EXPECT().
+		ProcessDeposit(gomock.Any(), gomock.Any()).
+		Return(&shared.Response{
+			ClientRequest:         requestPayload,
+			OrderID:               "1",
+			PaymentGatewayOrderID: "11",
+			DepositUrl:            "https://example.com/deposit",
+		}, nil)
+
+	handler := Handler(mockService, logger, validate)
+
+	payloadBytes, _ := json.Marshal(requestPayload)
+	req, _ := http.NewRequest("POST", "/api/v1/deposit?redirect=true", bytes.NewBuffer(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "https://example.com/deposit", rr.Header().Get("Location"))
 }
 
 func TestHandler_InvalidJSON(t *testing.T) {
@@ -105,6 +138,13 @@ func TestHandler_MissingRequiredFields(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var response apperror.Response
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, apperror.CodeValidation, response.Error.Code)
+	if assert.Len(t, response.Error.Details, 1) {
+		assert.Equal(t, "required", response.Error.Details[0].Rule)
+	}
 }
 
 func TestHandler_InvalidFieldValues(t *testing.T) {
@@ -125,12 +165,30 @@ func TestHandler_InvalidFieldValues(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }
 
+func TestHandler_InvalidCurrencyCode(t *testing.T) {
+	setup(t)
+	defer teardown()
+
+	requestPayload.OrderCurrency = "DOLLARS"
+
+	handler := Handler(mockService, logger, validate)
+
+	payloadBytes, _ := json.Marshal(requestPayload)
+	req, _ := http.NewRequest("POST", "/api/v1/deposit", bytes.NewBuffer(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
 func TestHandler_ServiceError(t *testing.T) {
 	setup(t)
 	defer teardown()
 
 	mockService.EXPECT().
-		ProcessDeposit(gomock.Any()).
+		ProcessDeposit(gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("service error"))
 
 	handler := Handler(mockService, logger, validate)
@@ -144,3 +202,27 @@ func TestHandler_ServiceError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
+
+func TestHandler_GatewayUnavailable(t *testing.T) {
+	setup(t)
+	defer teardown()
+
+	mockService.EXPECT().
+		ProcessDeposit(gomock.Any(), gomock.Any()).
+		Return(nil, apperror.GatewayUnavailable("payment gateway is unreachable", fmt.Errorf("connection refused")))
+
+	handler := Handler(mockService, logger, validate)
+
+	payloadBytes, _ := json.Marshal(requestPayload)
+	req, _ := http.NewRequest("POST", "/api/v1/deposit", bytes.NewBuffer(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var response apperror.Response
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, apperror.CodeGatewayUnavailable, response.Error.Code)
+	assert.NotContains(t, response.Error.Message, "connection refused")
+}
@@ -1,12 +1,13 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: internal/deposit/service.go
+// Source: internal/deposit/common/service.go
 
-// Package deposit is a generated GoMock package.
+// Package common is a generated GoMock package.
 package common
 
 import (
+	context "context"
 	reflect "reflect"
-	"zota-dev-challenge/internal/deposit/shared"
+	shared "zota-dev-challenge/internal/deposit/shared"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,16 +36,16 @@ func (m *MockServiceInterface) EXPECT() *MockServiceInterfaceMockRecorder {
 }
 
 // ProcessDeposit mocks base method.
-func (m *MockServiceInterface) ProcessDeposit(r *shared.ClientRequest) (*shared.Response, error) {
+func (m *MockServiceInterface) ProcessDeposit(ctx context.Context, r *shared.ClientRequest) (*shared.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessDeposit", r)
+	ret := m.ctrl.Call(m, "ProcessDeposit", ctx, r)
 	ret0, _ := ret[0].(*shared.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProcessDeposit indicates an expected call of ProcessDeposit.
-func (mr *MockServiceInterfaceMockRecorder) ProcessDeposit(r interface{}) *gomock.Call {
+func (mr *MockServiceInterfaceMockRecorder) ProcessDeposit(ctx, r interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessDeposit", reflect.TypeOf((*MockServiceInterface)(nil).ProcessDeposit), r)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessDeposit", reflect.TypeOf((*MockServiceInterface)(nil).ProcessDeposit), ctx, r)
 }
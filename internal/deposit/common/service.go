@@ -1,48 +1,105 @@
 package common
 
 import (
-	"fmt"
+	"context"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
 	"zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/tracing"
 )
 
 type ServiceInterface interface {
-	ProcessDeposit(r *shared.ClientRequest) (*shared.Response, error)
+	ProcessDeposit(ctx context.Context, r *shared.ClientRequest) (*shared.Response, error)
 }
 
 type Service struct {
 	logger         *zap.Logger
 	config         *config.Config
 	depositGateway shared.DepositPaymentGateway // Use the interface
+	orders         orderShared.OrderRepository
+	stateMachine   *order.StateMachine
+	metrics        *metrics.Metrics
 }
 
-func NewService(logger *zap.Logger, config *config.Config, depositGateway shared.DepositPaymentGateway) *Service {
-	return &Service{logger: logger, config: config, depositGateway: depositGateway}
+func NewService(logger *zap.Logger, config *config.Config, depositGateway shared.DepositPaymentGateway,
+	orders orderShared.OrderRepository, stateMachine *order.StateMachine, metrics *metrics.Metrics) *Service {
+	return &Service{logger: logger, config: config, depositGateway: depositGateway, orders: orders, stateMachine: stateMachine,
+		metrics: metrics}
 }
 
-func (s *Service) ProcessDeposit(req *shared.ClientRequest) (*shared.Response, error) {
-	//validate the currency is USD as it is the only supported currency
-	if req.OrderCurrency != "USD" {
-		s.logger.Error("Invalid currency", zap.String("currency", req.OrderCurrency))
-		return nil, fmt.Errorf("invalid currency")
+func (s *Service) ProcessDeposit(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "deposit.Service.ProcessDeposit", tracing.AttrCurrency.String(req.OrderCurrency))
+	res, err := s.processDeposit(ctx, req)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s *Service) processDeposit(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	//Zota assigns an endpoint per currency, only the configured currencies can be deposited
+	if _, ok := s.config.EndpointID(req.OrderCurrency); !ok {
+		s.logger.Error("Unsupported currency", zap.String("currency", req.OrderCurrency))
+		return nil, apperror.UnsupportedCurrency(req.OrderCurrency)
+	}
+
+	amount, err := money.ParsePositive(req.OrderAmount, req.OrderCurrency)
+	if err != nil {
+		s.logger.Error("Invalid amount", zap.String("amount", req.OrderAmount), zap.Error(err))
+		return nil, apperror.InvalidField("orderAmount", "amount", err)
 	}
 
 	//we use service model here to be easily extendable and decouple the service from the controller
 	serviceModel := shared.Request{
-		ClientRequest: *req,
+		ClientRequest:   *req,
+		MerchantOrderID: uuid.New().String(),
+		Amount:          amount,
 	}
+	tracing.Annotate(ctx, tracing.AttrMerchantOrderID.String(serviceModel.MerchantOrderID))
 
-	depositRes, err := s.depositGateway.Deposit(serviceModel)
+	//record the attempt before calling the payment gateway, so we know about the order even if the call fails
+	newOrder := &orderShared.Order{
+		MerchantOrderID: serviceModel.MerchantOrderID,
+		Type:            orderShared.TypeSale,
+		UserID:          req.UserId,
+		Amount:          amount.String(),
+		Currency:        req.OrderCurrency,
+		Status:          orderShared.StatusCreated,
+		Environment:     s.config.Environment,
+	}
+	if err := s.orders.Create(newOrder); err != nil {
+		s.logger.Error("Failed to store order", zap.Error(err))
+		return nil, err
+	}
+
+	depositRes, err := s.depositGateway.Deposit(ctx, serviceModel)
+	s.metrics.ObserveDepositRequest(req.OrderCurrency, err)
 	if err != nil {
 		s.logger.Error("Failed to process deposit", zap.Error(err))
+		if _, transitionErr := s.stateMachine.Transition(newOrder.MerchantOrderID, orderShared.FailureStatus(err),
+			orderShared.SourceDeposit, err.Error()); transitionErr != nil {
+			s.logger.Error("Failed to mark order as failed", zap.String("merchantOrderID", newOrder.MerchantOrderID), zap.Error(transitionErr))
+		}
 		return nil, err
 	}
 
+	newOrder.PaymentGatewayOrderID = depositRes.PaymentGatewayOrderID
+	tracing.Annotate(ctx, tracing.AttrZotaOrderID.String(depositRes.PaymentGatewayOrderID))
+	if err := s.orders.Update(newOrder); err != nil {
+		// the deposit itself already went through the payment gateway, so a store failure is only logged
+		s.logger.Error("Failed to update order", zap.String("merchantOrderID", newOrder.MerchantOrderID), zap.Error(err))
+	}
+
 	response := shared.Response{
 		ClientRequest:         *req,
 		OrderID:               depositRes.OrderID,
 		PaymentGatewayOrderID: depositRes.PaymentGatewayOrderID,
+		DepositUrl:            depositRes.DepositUrl,
+		Environment:           newOrder.Environment,
 	}
 	return &response, nil
 }
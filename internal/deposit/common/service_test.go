@@ -1,21 +1,34 @@
 package common
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
 	"testing"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
 	"zota-dev-challenge/internal/deposit/common/zota"
 	"zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 type serviceTestSuite struct {
 	mockCtrl    *gomock.Controller
 	mockGateway *zota.MockDepositPaymentGateway
 	logger      *zap.Logger
+	orders      *order.MemoryRepository
 	service     *Service
 	request     shared.ClientRequest
 }
@@ -25,9 +38,13 @@ func (s *serviceTestSuite) setup(t *testing.T) {
 	s.mockGateway = zota.NewMockDepositPaymentGateway(s.mockCtrl)
 	s.logger, _ = zap.NewDevelopment()
 
-	cfg := &config.Config{}
+	cfg := &config.Config{
+		ZotaEndpointIds: map[string]string{"USD": "usdEndpoint", "EUR": "eurEndpoint"},
+	}
 
-	s.service = NewService(s.logger, cfg, s.mockGateway)
+	s.orders = order.NewMemoryRepository()
+	s.service = NewService(s.logger, cfg, s.mockGateway, s.orders, order.NewStateMachine(s.logger, s.orders, metrics.New()),
+		metrics.New())
 
 	s.request = shared.ClientRequest{
 		UserId:              "user123",
@@ -60,15 +77,49 @@ func TestProcessDeposit_Success(t *testing.T) {
 		ClientRequest:         s.request,
 		OrderID:               "order123",
 		PaymentGatewayOrderID: "gateway123",
+		DepositUrl:            "https://example.com/deposit",
 	}
 
-	s.mockGateway.EXPECT().Deposit(shared.Request{
-		ClientRequest: s.request,
-	}).Return(expectedResponse, nil)
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		assert.Equal(t, s.request, req.ClientRequest)
+		assert.Equal(t, "100.00", req.Amount.String())
+		merchantOrderID = req.MerchantOrderID
+		return expectedResponse, nil
+	})
 
-	response, err := s.service.ProcessDeposit(&s.request)
+	response, err := s.service.ProcessDeposit(context.Background(), &s.request)
 	require.NoError(t, err)
 	assert.Equal(t, expectedResponse, response)
+
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, "gateway123", stored.PaymentGatewayOrderID)
+	assert.Equal(t, orderShared.StatusCreated, stored.Status)
+	assert.Equal(t, s.request.UserId, stored.UserID)
+	assert.Equal(t, s.request.OrderAmount, stored.Amount)
+	assert.Equal(t, s.request.OrderCurrency, stored.Currency)
+}
+
+func TestProcessDeposit_RecordsEnvironment(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+	s.service.config.Environment = config.EnvironmentLive
+
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		merchantOrderID = req.MerchantOrderID
+		return &shared.Response{OrderID: req.MerchantOrderID, PaymentGatewayOrderID: "gateway123"}, nil
+	})
+
+	response, err := s.service.ProcessDeposit(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, config.EnvironmentLive, response.Environment)
+
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, config.EnvironmentLive, stored.Environment)
 }
 
 func TestProcessDeposit_InvalidCurrency(t *testing.T) {
@@ -76,12 +127,47 @@ func TestProcessDeposit_InvalidCurrency(t *testing.T) {
 	s.setup(t)
 	defer s.teardown()
 
-	s.request.OrderCurrency = "EUR"
+	s.request.OrderCurrency = "GBP"
 
-	response, err := s.service.ProcessDeposit(&s.request)
+	response, err := s.service.ProcessDeposit(context.Background(), &s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
-	assert.Equal(t, "invalid currency", err.Error())
+	assert.Equal(t, apperror.CodeUnsupportedCurrency, apperror.From(err).Code)
+}
+
+func TestProcessDeposit_InvalidAmount(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	for amount, expectedErr := range map[string]error{
+		"abc":    money.ErrInvalidAmount,
+		"0.00":   money.ErrNonPositiveAmount,
+		"-5":     money.ErrNegativeAmount,
+		"10.123": money.ErrTooPrecise,
+	} {
+		s.request.OrderAmount = amount
+
+		response, err := s.service.ProcessDeposit(context.Background(), &s.request)
+		assert.ErrorIs(t, err, expectedErr, amount)
+		assert.Nil(t, response)
+	}
+}
+
+func TestProcessDeposit_ConfiguredCurrency(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.OrderCurrency = "EUR"
+
+	s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		assert.Equal(t, "EUR", req.OrderCurrency)
+		return &shared.Response{ClientRequest: s.request, OrderID: req.MerchantOrderID}, nil
+	})
+
+	_, err := s.service.ProcessDeposit(context.Background(), &s.request)
+	require.NoError(t, err)
 }
 
 func TestProcessDeposit_DepositError(t *testing.T) {
@@ -91,12 +177,79 @@ func TestProcessDeposit_DepositError(t *testing.T) {
 
 	expectedError := errors.New("deposit error")
 
-	s.mockGateway.EXPECT().Deposit(shared.Request{
-		ClientRequest: s.request,
-	}).Return(nil, expectedError)
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		merchantOrderID = req.MerchantOrderID
+		return nil, expectedError
+	})
 
-	response, err := s.service.ProcessDeposit(&s.request)
+	response, err := s.service.ProcessDeposit(context.Background(), &s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 	assert.Equal(t, expectedError, err)
+
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusError, stored.Status)
+	assert.Equal(t, expectedError.Error(), stored.ErrorMessage)
+}
+
+func TestProcessDeposit_TimeoutLeavesOutcomeUnknown(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		merchantOrderID = req.MerchantOrderID
+		return nil, fmt.Errorf("send deposit: %w", context.DeadlineExceeded)
+	})
+
+	_, err := s.service.ProcessDeposit(context.Background(), &s.request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Zota may have accepted the deposit before the deadline hit, so the order is not failed for good
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusUnknown, stored.Status)
+}
+
+func TestProcessDeposit_FailureStatus(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	tests := map[string]struct {
+		err    error
+		status string
+	}{
+		// the request may have created the order before Zota failed to answer
+		"unavailable": {apperror.GatewayUnavailable("payment gateway is unavailable",
+			&zotaapi.Error{HTTPStatus: http.StatusServiceUnavailable, Code: "503"}), orderShared.StatusUnknown},
+		"reset after the write": {apperror.GatewayUnavailable("payment gateway is unreachable", reset), orderShared.StatusUnknown},
+		"malformed answer":      {apperror.GatewayError("malformed payment gateway response", io.ErrUnexpectedEOF), orderShared.StatusUnknown},
+		"rejected": {apperror.GatewayRejected("payment gateway rejected the request",
+			&zotaapi.Error{HTTPStatus: http.StatusBadRequest, Code: "400"}), orderShared.StatusError},
+		"never connected": {apperror.GatewayUnavailable("payment gateway is unreachable", refused), orderShared.StatusError},
+		"breaker open":    {apperror.GatewayUnavailable("payment gateway is unavailable", zotaapi.ErrCircuitOpen), orderShared.StatusError},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &serviceTestSuite{}
+			s.setup(t)
+			defer s.teardown()
+
+			var merchantOrderID string
+			s.mockGateway.EXPECT().Deposit(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+				merchantOrderID = req.MerchantOrderID
+				return nil, test.err
+			})
+
+			_, err := s.service.ProcessDeposit(context.Background(), &s.request)
+			assert.ErrorIs(t, err, test.err)
+
+			stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+			require.NoError(t, err)
+			assert.Equal(t, test.status, stored.Status)
+		})
+	}
 }
@@ -1,17 +1,21 @@
 package zota
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
+	"time"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
 	"zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 const PaymentGatewayDepositApiPath = "api/v1/deposit/request"
@@ -21,22 +25,22 @@ type DepositRequest struct {
 	MerchantOrderDesc   string `json:"merchantOrderDesc"`
 	OrderAmount         string `json:"orderAmount"`
 	OrderCurrency       string `json:"orderCurrency"`
-	CustomerEmail       string `json:"customerEmail"`
-	CustomerFirstName   string `json:"customerFirstName"`
-	CustomerLastName    string `json:"customerLastName"`
-	CustomerAddress     string `json:"customerAddress"`
+	CustomerEmail       string `json:"customerEmail" log:"email"`
+	CustomerFirstName   string `json:"customerFirstName" log:"mask"`
+	CustomerLastName    string `json:"customerLastName" log:"mask"`
+	CustomerAddress     string `json:"customerAddress" log:"mask"`
 	CustomerCountryCode string `json:"customerCountryCode"`
-	CustomerCity        string `json:"customerCity"`
-	CustomerState       string `json:"customerState"`
-	CustomerZipCode     string `json:"customerZipCode"`
-	CustomerPhone       string `json:"customerPhone"`
-	CustomerBankCode    string `json:"customerBankCode"`
-	CustomerIP          string `json:"customerIP"`
+	CustomerCity        string `json:"customerCity" log:"mask"`
+	CustomerState       string `json:"customerState" log:"mask"`
+	CustomerZipCode     string `json:"customerZipCode" log:"mask"`
+	CustomerPhone       string `json:"customerPhone" log:"phone"`
+	CustomerBankCode    string `json:"customerBankCode" log:"mask"`
+	CustomerIP          string `json:"customerIP" log:"hash"`
 	RedirectUrl         string `json:"redirectUrl"`
 	CallbackUrl         string `json:"callbackUrl"`
 	CustomParam         string `json:"customParam"`
 	CheckoutUrl         string `json:"checkoutUrl"`
-	Signature           string `json:"signature"`
+	Signature           string `json:"signature" log:"secret"`
 }
 
 type CustomParam struct {
@@ -56,15 +60,28 @@ type DepositResponseData struct {
 }
 
 type DepositGateway struct {
-	logger *zap.Logger
-	config *config.Config
+	logger  *zap.Logger
+	config  *config.Config
+	client  *zotaapi.Client
+	metrics *metrics.Metrics
 }
 
-func NewDepositGateway(logger *zap.Logger, config *config.Config) *DepositGateway {
-	return &DepositGateway{logger: logger, config: config}
+func NewDepositGateway(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) *DepositGateway {
+	return &DepositGateway{logger: logger, config: config, client: client, metrics: metrics}
 }
 
-func (d *DepositGateway) Deposit(req shared.Request) (*shared.Response, error) {
+func (d *DepositGateway) Deposit(ctx context.Context, req shared.Request) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "zota.DepositGateway.Deposit",
+		tracing.AttrMerchantOrderID.String(req.MerchantOrderID), tracing.AttrCurrency.String(req.OrderCurrency))
+	res, err := d.deposit(ctx, req)
+	if res != nil {
+		span.SetAttributes(tracing.AttrZotaOrderID.String(res.PaymentGatewayOrderID))
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
+func (d *DepositGateway) deposit(ctx context.Context, req shared.Request) (*shared.Response, error) {
 	d.logger.Info("Processing deposit with Zota", zap.Any("request", req))
 
 	depositReq, err := d.buildDepositReq(req)
@@ -79,16 +96,24 @@ func (d *DepositGateway) Deposit(req shared.Request) (*shared.Response, error) {
 		return nil, err
 	}
 
-	respBody, statusCode, err := d.sendDepositRequest(depositReqJSON)
+	endpointID, err := d.endpointID(depositReq.OrderCurrency)
 	if err != nil {
 		return nil, err
 	}
 
-	if statusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK depositResponse from Zota server: %s", http.StatusText(statusCode))
+	ctx, cancel := zotaapi.WithTimeout(ctx, d.config.ZotaDepositTimeout)
+	defer cancel()
+
+	// the latency includes the retries, it is what the caller waited for
+	started := time.Now()
+	respBody, statusCode, err := d.sendDepositRequest(ctx, depositReqJSON, endpointID)
+	if err != nil {
+		d.metrics.ObserveZotaCall(metrics.OperationDeposit, started, err)
+		return nil, err
 	}
 
-	response, err := d.handleDepositResponse(respBody, req)
+	response, err := d.handleDepositResponse(respBody, statusCode, req)
+	d.metrics.ObserveZotaCall(metrics.OperationDeposit, started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -97,13 +122,21 @@ func (d *DepositGateway) Deposit(req shared.Request) (*shared.Response, error) {
 }
 
 func (d *DepositGateway) buildDepositReq(req shared.Request) (DepositRequest, error) {
-	endpointID := d.config.ZotaEndpointId
+	endpointID, err := d.endpointID(req.OrderCurrency)
+	if err != nil {
+		return DepositRequest{}, err
+	}
 	merchantSecretKey := d.config.ZotaAPISecretKey
 	zotaDepositCallBackUrl := d.config.ZotaDepositCallBackUrl
 	zotaDepositRedirectUrl := d.config.ZotaDepositRedirectUrl
-	merchantOrderId := uuid.New()
+	merchantOrderId := req.MerchantOrderID
+	if merchantOrderId == "" {
+		merchantOrderId = uuid.New().String()
+	}
 
-	signature := d.buildSignature(req.OrderAmount, req.CustomerEmail, endpointID, merchantOrderId, merchantSecretKey)
+	// the normalized amount is both sent and signed, so Zota sees exactly what we recorded
+	orderAmount := req.Amount.String()
+	signature := d.buildSignature(orderAmount, req.CustomerEmail, endpointID, merchantOrderId, merchantSecretKey)
 
 	customParamJSON, err := d.marshalCustomParam(req.ClientRequest.UserId)
 	if err != nil {
@@ -111,9 +144,9 @@ func (d *DepositGateway) buildDepositReq(req shared.Request) (DepositRequest, er
 	}
 
 	return DepositRequest{
-		MerchantOrderID:     merchantOrderId.String(),
+		MerchantOrderID:     merchantOrderId,
 		MerchantOrderDesc:   "Deposit",
-		OrderAmount:         req.OrderAmount,
+		OrderAmount:         orderAmount,
 		OrderCurrency:       req.OrderCurrency,
 		CustomerEmail:       req.CustomerEmail,
 		CustomerFirstName:   req.CustomerFirstName,
@@ -134,6 +167,15 @@ func (d *DepositGateway) buildDepositReq(req shared.Request) (DepositRequest, er
 	}, nil
 }
 
+// endpointID - Zota assigns an endpoint per currency, it is part of both the URL and the signature
+func (d *DepositGateway) endpointID(currency string) (string, error) {
+	endpointID, ok := d.config.EndpointID(currency)
+	if !ok {
+		return "", fmt.Errorf("no Zota endpoint configured for currency %q", currency)
+	}
+	return endpointID, nil
+}
+
 func (d *DepositGateway) marshalCustomParam(userId string) (string, error) {
 	customParam := CustomParam{UserId: userId}
 	customParamJSON, err := json.Marshal(customParam)
@@ -144,53 +186,46 @@ func (d *DepositGateway) marshalCustomParam(userId string) (string, error) {
 	return string(customParamJSON), nil
 }
 
-func (d *DepositGateway) buildSignature(orderAmount, customerEmail, endpointID string, merchantOrderId uuid.UUID, merchantSecretKey string) string {
+func (d *DepositGateway) buildSignature(orderAmount, customerEmail, endpointID, merchantOrderId, merchantSecretKey string) string {
 	signatureString := fmt.Sprintf("%s%s%s%s%s", endpointID, merchantOrderId, orderAmount, customerEmail, merchantSecretKey)
 	hash := sha256.New()
 	hash.Write([]byte(signatureString))
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-func (d *DepositGateway) sendDepositRequest(depositReqJSON []byte) ([]byte, int, error) {
-	url := fmt.Sprintf("%s/%s/%s/", d.config.ZotaBaseUrl, PaymentGatewayDepositApiPath, d.config.ZotaEndpointId)
+func (d *DepositGateway) sendDepositRequest(ctx context.Context, depositReqJSON []byte, endpointID string) ([]byte, int, error) {
+	url := fmt.Sprintf("%s/%s/%s/", d.config.ZotaBaseUrl, PaymentGatewayDepositApiPath, endpointID)
 	d.logger.Debug("Sending deposit request to Zota server", zap.String("url", url))
 
-	reqBody := bytes.NewBuffer(depositReqJSON)
-	httpReq, err := http.NewRequest("POST", url, reqBody)
-	if err != nil {
-		d.logger.Error("Failed to create HTTP request", zap.Error(err))
-		return nil, 0, err
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	ctx, span := tracing.StartClient(ctx, "zota.DepositGateway.sendDepositRequest", http.MethodPost, url)
+	defer span.End()
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := d.client.Do(ctx, zotaapi.Request{Method: http.MethodPost, URL: url, Body: depositReqJSON})
 	if err != nil {
-		d.logger.Error("Failed to send HTTP request", zap.Error(err))
+		d.logger.Error("Failed to send deposit request", zap.Error(err))
+		tracing.Record(span, err)
 		return nil, 0, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		d.logger.Error("Failed to read depositResponse body", zap.Error(err))
-		return nil, 0, err
-	}
-
-	return respBody, resp.StatusCode, nil
+	tracing.RecordResponse(span, resp.StatusCode)
+	return resp.Body, resp.StatusCode, nil
 }
 
-func (d *DepositGateway) handleDepositResponse(respBody []byte, req shared.Request) (*shared.Response, error) {
+func (d *DepositGateway) handleDepositResponse(respBody []byte, statusCode int, req shared.Request) (*shared.Response, error) {
 	var depositResponse DepositResponse
-	if err := json.Unmarshal(respBody, &depositResponse); err != nil {
-		d.logger.Error("Failed to unmarshal depositResponse body", zap.Error(err))
+	if err := zotaapi.DecodeResponse(statusCode, respBody, &depositResponse); err != nil {
+		d.logger.Error("Zota did not accept the deposit request", zap.Int("statusCode", statusCode), zap.Error(err))
 		return nil, err
 	}
 
+	if depositResponse.Data == nil {
+		return nil, apperror.GatewayError("malformed payment gateway response", fmt.Errorf("no data in depositResponse"))
+	}
+
 	response := shared.Response{
 		ClientRequest:         req.ClientRequest,
 		OrderID:               depositResponse.Data.MerchantOrderID,
 		PaymentGatewayOrderID: depositResponse.Data.OrderID,
+		DepositUrl:            depositResponse.Data.DepositUrl,
 	}
 
 	d.logger.Info("Successfully processed deposit request",
@@ -1,6 +1,7 @@
 package zota
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -9,11 +10,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
 	"zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	"zota-dev-challenge/internal/redact"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 var (
@@ -28,15 +36,13 @@ func setup(t *testing.T) {
 	cfg := &config.Config{
 		ZotaBaseUrl:            "https://example.com",
 		ZotaEndpointId:         "testEndpoint",
+		ZotaEndpointIds:        map[string]string{"USD": "testEndpoint", "EUR": "eurEndpoint"},
 		ZotaAPISecretKey:       "testSecret",
 		ZotaDepositCallBackUrl: "https://example.com/callback",
 		ZotaDepositRedirectUrl: "https://example.com/redirect",
 	}
 
-	depositGateway = &DepositGateway{
-		logger: logger,
-		config: cfg,
-	}
+	depositGateway = NewDepositGateway(logger, cfg, zotaapi.NewClient(logger, cfg), metrics.New())
 
 	requestPayload = shared.Request{
 		ClientRequest: shared.ClientRequest{
@@ -55,6 +61,7 @@ func setup(t *testing.T) {
 			CustomerIp:          "127.0.0.1",
 			CheckoutUrl:         "https://example.com/checkout",
 		},
+		Amount: money.FromMinor(10000, "USD"),
 	}
 }
 
@@ -80,17 +87,87 @@ func TestDeposit_Success(t *testing.T) {
 
 	depositGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := depositGateway.Deposit(requestPayload)
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
 	require.NoError(t, err)
 	assert.Equal(t, depositResponse.Data.MerchantOrderID, response.OrderID)
 	assert.Equal(t, depositResponse.Data.OrderID, response.PaymentGatewayOrderID)
+	assert.Equal(t, depositResponse.Data.DepositUrl, response.DepositUrl)
+}
+
+func TestDeposit_LogsAreRedacted(t *testing.T) {
+	setup(t)
+
+	core, logs := observer.New(zap.DebugLevel)
+	depositGateway.logger = zap.New(redact.New("testSecret").Core(core))
+	requestPayload.CustomerIp = "203.0.113.7"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"code":"200","data":{"merchantOrderID":"merchantOrder123","orderID":"123123","customerEmail":"test@example.com"}}`)
+	}))
+	defer server.Close()
+	depositGateway.config.ZotaBaseUrl = server.URL
+
+	_, err := depositGateway.Deposit(context.Background(), requestPayload)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, logs.All())
+	var logged strings.Builder
+	for _, entry := range logs.All() {
+		fmt.Fprintf(&logged, "%s %v\n", entry.Message, entry.ContextMap())
+	}
+	for _, pii := range []string{"test@example.com", "1234567890", "John", "Doe", "123 Main St", "10001", "203.0.113.7"} {
+		assert.NotContains(t, logged.String(), pii)
+	}
+	assert.Contains(t, logged.String(), "t***@example.com")
+	assert.Contains(t, logged.String(), "******7890")
+}
+
+func TestDeposit_UsesCurrencyEndpoint(t *testing.T) {
+	setup(t)
+
+	depositResponseJSON, _ := json.Marshal(DepositResponse{
+		Code: "200",
+		Data: &DepositResponseData{MerchantOrderID: "merchantOrder123", OrderID: "123123"},
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/deposit/request/eurEndpoint/", r.URL.Path)
+
+		var depositReq DepositRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&depositReq))
+		expectedSignature := depositGateway.buildSignature(depositReq.OrderAmount, depositReq.CustomerEmail, "eurEndpoint",
+			depositReq.MerchantOrderID, "testSecret")
+		assert.Equal(t, expectedSignature, depositReq.Signature)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(depositResponseJSON)
+	}))
+	defer server.Close()
+
+	depositGateway.config.ZotaBaseUrl = server.URL
+	requestPayload.OrderCurrency = "EUR"
+	requestPayload.Amount = money.FromMinor(10000, "EUR")
+
+	_, err := depositGateway.Deposit(context.Background(), requestPayload)
+	require.NoError(t, err)
+}
+
+func TestDeposit_UnsupportedCurrency(t *testing.T) {
+	setup(t)
+
+	requestPayload.OrderCurrency = "GBP"
+
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
+	assert.Error(t, err)
+	assert.Nil(t, response)
 }
 
 func TestDeposit_BuildDepositReqError(t *testing.T) {
 	setup(t)
 
 	badRequest := shared.Request{}
-	response, err := depositGateway.Deposit(badRequest)
+	response, err := depositGateway.Deposit(context.Background(), badRequest)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -105,7 +182,7 @@ func TestDeposit_HTTPRequestError(t *testing.T) {
 
 	depositGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := depositGateway.Deposit(requestPayload)
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -120,7 +197,7 @@ func TestDeposit_NonOKHTTPResponse(t *testing.T) {
 
 	depositGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := depositGateway.Deposit(requestPayload)
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -136,7 +213,7 @@ func TestDeposit_UnmarshalResponseError(t *testing.T) {
 
 	depositGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := depositGateway.Deposit(requestPayload)
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -151,6 +228,30 @@ func TestBuildDepositReq(t *testing.T) {
 	assert.Equal(t, "USD", depositReq.OrderCurrency)
 	assert.Equal(t, "test@example.com", depositReq.CustomerEmail)
 	assert.NotEmpty(t, depositReq.Signature)
+	assert.NotEmpty(t, depositReq.MerchantOrderID)
+}
+
+func TestBuildDepositReq_SignsNormalizedAmount(t *testing.T) {
+	setup(t)
+
+	requestPayload.OrderAmount = "100"
+	requestPayload.MerchantOrderID = "merchantOrder123"
+
+	depositReq, err := depositGateway.buildDepositReq(requestPayload)
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", depositReq.OrderAmount)
+	assert.Equal(t, depositGateway.buildSignature("100.00", "test@example.com", "testEndpoint", "merchantOrder123", "testSecret"),
+		depositReq.Signature)
+}
+
+func TestBuildDepositReq_UsesMerchantOrderID(t *testing.T) {
+	setup(t)
+
+	requestPayload.MerchantOrderID = "merchantOrder123"
+
+	depositReq, err := depositGateway.buildDepositReq(requestPayload)
+	require.NoError(t, err)
+	assert.Equal(t, "merchantOrder123", depositReq.MerchantOrderID)
 }
 
 func TestMarshalCustomParam(t *testing.T) {
@@ -168,7 +269,7 @@ func TestBuildSignature(t *testing.T) {
 	orderAmount := "100.00"
 	customerEmail := "test@example.com"
 	endpointID := "testEndpoint"
-	merchantOrderId := uuid.New()
+	merchantOrderId := uuid.New().String()
 	merchantSecretKey := "testSecret"
 
 	signature := depositGateway.buildSignature(orderAmount, customerEmail, endpointID, merchantOrderId, merchantSecretKey)
@@ -194,8 +295,48 @@ func TestHandleDepositResponse(t *testing.T) {
 	}
 	depositResponseJSON, _ := json.Marshal(depositResponse)
 
-	response, err := depositGateway.handleDepositResponse(depositResponseJSON, requestPayload)
+	response, err := depositGateway.handleDepositResponse(depositResponseJSON, http.StatusOK, requestPayload)
 	require.NoError(t, err)
 	assert.Equal(t, depositResponse.Data.MerchantOrderID, response.OrderID)
 	assert.Equal(t, depositResponse.Data.OrderID, response.PaymentGatewayOrderID)
 }
+
+func TestDeposit_ErrorEnvelope(t *testing.T) {
+	setup(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"400","message":"endpoint currency mismatch"}`))
+	}))
+	defer server.Close()
+
+	depositGateway.config.ZotaBaseUrl = server.URL
+
+	response, err := depositGateway.Deposit(context.Background(), requestPayload)
+	assert.Nil(t, response)
+	appErr := apperror.From(err)
+	assert.Equal(t, apperror.CodeGatewayRejected, appErr.Code)
+	assert.Equal(t, &apperror.GatewayFailure{Code: "400", Message: "endpoint currency mismatch"}, appErr.Gateway)
+
+	var zotaErr *zotaapi.Error
+	require.ErrorAs(t, err, &zotaErr)
+	assert.Equal(t, "endpoint currency mismatch", zotaErr.Message)
+}
+
+func TestHandleDepositResponse_ErrorCodeWithoutData(t *testing.T) {
+	setup(t)
+
+	// Zota can answer HTTP 200 with an error code and no data
+	response, err := depositGateway.handleDepositResponse([]byte(`{"code":"400","message":"invalid signature"}`),
+		http.StatusOK, requestPayload)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeGatewayRejected, apperror.From(err).Code)
+}
+
+func TestHandleDepositResponse_NoData(t *testing.T) {
+	setup(t)
+
+	response, err := depositGateway.handleDepositResponse([]byte(`{"code":"200"}`), http.StatusOK, requestPayload)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeGatewayError, apperror.From(err).Code)
+}
@@ -5,6 +5,7 @@
 package zota
 
 import (
+	context "context"
 	reflect "reflect"
 	shared "zota-dev-challenge/internal/deposit/shared"
 
@@ -35,16 +36,16 @@ func (m *MockDepositPaymentGateway) EXPECT() *MockDepositPaymentGatewayMockRecor
 }
 
 // Deposit mocks base method.
-func (m *MockDepositPaymentGateway) Deposit(req shared.Request) (*shared.Response, error) {
+func (m *MockDepositPaymentGateway) Deposit(ctx context.Context, req shared.Request) (*shared.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deposit", req)
+	ret := m.ctrl.Call(m, "Deposit", ctx, req)
 	ret0, _ := ret[0].(*shared.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Deposit indicates an expected call of Deposit.
-func (mr *MockDepositPaymentGatewayMockRecorder) Deposit(req interface{}) *gomock.Call {
+func (mr *MockDepositPaymentGatewayMockRecorder) Deposit(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockDepositPaymentGateway)(nil).Deposit), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deposit", reflect.TypeOf((*MockDepositPaymentGateway)(nil).Deposit), ctx, req)
 }
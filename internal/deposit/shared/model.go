@@ -1,5 +1,10 @@
 package shared
 
+import (
+	"context"
+	"zota-dev-challenge/internal/money"
+)
+
 /*
 // ClientRequest - represents the expected request body for the deposit endpoint in the merchant server
 // for the sake of simplicity we will accept all the required fields as req body
@@ -17,33 +22,38 @@ type ClientRequest struct {
 type ClientRequest struct {
 	UserId              string `json:"userId" validate:"required"`
 	OrderAmount         string `json:"orderAmount" validate:"required"`
-	OrderCurrency       string `json:"orderCurrency" validate:"required"`
-	CustomerEmail       string `json:"customerEmail" validate:"required,email"`
-	CustomerFirstName   string `json:"customerFirstName" validate:"required"`
-	CustomerLastName    string `json:"customerLastName" validate:"required"`
-	CustomerAddress     string `json:"customerAddress" validate:"required"`
+	OrderCurrency       string `json:"orderCurrency" validate:"required,iso4217"`
+	CustomerEmail       string `json:"customerEmail" validate:"required,email" log:"email"`
+	CustomerFirstName   string `json:"customerFirstName" validate:"required" log:"mask"`
+	CustomerLastName    string `json:"customerLastName" validate:"required" log:"mask"`
+	CustomerAddress     string `json:"customerAddress" validate:"required" log:"mask"`
 	CustomerCountryCode string `json:"customerCountryCode" validate:"required"`
-	CustomerCity        string `json:"customerCity" validate:"required"`
-	CustomerZipCode     string `json:"customerZipCode" validate:"required"`
-	CustomerPhone       string `json:"customerPhone" validate:"required"`
-	CustomerIp          string `json:"customerIp" validate:"required"`
+	CustomerCity        string `json:"customerCity" validate:"required" log:"mask"`
+	CustomerZipCode     string `json:"customerZipCode" validate:"required" log:"mask"`
+	CustomerPhone       string `json:"customerPhone" validate:"required" log:"phone"`
+	CustomerIp          string `json:"customerIp" validate:"required" log:"hash"`
 	CheckoutUrl         string `json:"checkoutUrl" validate:"url"`
 	Language            string `json:"language"`
-	CustomerState       string `json:"customerState"`
-	CustomerBankCode    string `json:"customerBankCode"`
-}
+	CustomerState       string `json:"customerState" log:"mask"`
+	CustomerBankCode    string `json:"customerBankCode" log:"mask"`
+} //@name DepositRequest
 
 // Request - service level model
 type Request struct {
 	ClientRequest
+	MerchantOrderID string
+	// Amount - OrderAmount parsed and normalized to the currency's minor units
+	Amount money.Amount
 }
 
 type Response struct {
 	ClientRequest         ClientRequest `json:"request"`
 	OrderID               string        `json:"orderId"`
 	PaymentGatewayOrderID string        `json:"paymentGatewayOrderId"`
-}
+	DepositUrl            string        `json:"depositUrl"`
+	Environment           string        `json:"environment"` // sandbox or live
+} //@name DepositResponse
 
 type DepositPaymentGateway interface {
-	Deposit(req Request) (*Response, error)
+	Deposit(ctx context.Context, req Request) (*Response, error)
 }
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+	"zota-dev-challenge/internal/config"
+	ledgerShared "zota-dev-challenge/internal/ledger/shared"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+// Names of the readiness checks, part of the /readyz answer
+const (
+	CheckConfig     = "config"
+	CheckOrderStore = "orderStore"
+	CheckLedger     = "ledger"
+	CheckZota       = "zota"
+)
+
+// Check - a dependency the service needs to process payments, a nil error means it is usable
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Checker - decides whether the service is ready to take payment traffic
+type Checker struct {
+	logger  *zap.Logger
+	checks  []Check
+	timeout time.Duration
+	ready   atomic.Bool
+}
+
+func NewChecker(logger *zap.Logger, config *config.Config, orders orderShared.OrderRepository,
+	ledger ledgerShared.LedgerRepository, client *zotaapi.Client) *Checker {
+	zotaProbe := Cached(config.HealthZotaProbeTTL, func(ctx context.Context) error {
+		if err := client.Probe(ctx, config.ZotaBaseUrl); err != nil {
+			return fmt.Errorf("zota is unreachable: %w", err)
+		}
+		return nil
+	})
+
+	return NewCheckerWith(logger, config.HealthCheckTimeout,
+		Check{Name: CheckConfig, Check: func(ctx context.Context) error { return config.Validate() }},
+		Check{Name: CheckOrderStore, Check: orders.Ping},
+		Check{Name: CheckLedger, Check: ledger.Ping},
+		Check{Name: CheckZota, Check: zotaProbe},
+	)
+}
+
+// NewCheckerWith builds a checker from any checks, it starts not ready
+func NewCheckerWith(logger *zap.Logger, timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{logger: logger, checks: checks, timeout: timeout}
+}
+
+// SetReady marks whether the service takes traffic at all, it is unset while starting and draining
+func (c *Checker) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+func (c *Checker) IsReady() bool {
+	return c.ready.Load()
+}
+
+// Run executes every check concurrently and returns the failures by check name
+func (c *Checker) Run(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results := make(map[string]error, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			err := check.Check(ctx)
+			if err != nil {
+				c.logger.Warn("Readiness check failed", zap.String("check", check.Name), zap.Error(err))
+			}
+
+			mu.Lock()
+			results[check.Name] = err
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	return results
+}
+
+// Cached runs the check at most once per ttl and answers with the last result in between,
+// so a busy probe endpoint does not hammer the dependency
+func Cached(ttl time.Duration, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var mu sync.Mutex
+	var checkedAt time.Time
+	var lastErr error
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return lastErr
+		}
+
+		lastErr = check(ctx)
+		// a probe cut short by the caller says nothing about the dependency, so it is not cached
+		if ctx.Err() == nil {
+			checkedAt = time.Now()
+		}
+		return lastErr
+	}
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), time.Second,
+		Check{Name: "up", Check: func(ctx context.Context) error { return nil }},
+		Check{Name: "down", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+	)
+
+	results := checker.Run(context.Background())
+	assert.Len(t, results, 2)
+	assert.NoError(t, results["up"])
+	assert.EqualError(t, results["down"], "connection refused")
+}
+
+func TestChecker_RunIsBoundedByTimeout(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), 10*time.Millisecond,
+		Check{Name: "slow", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	results := checker.Run(context.Background())
+	assert.ErrorIs(t, results["slow"], context.DeadlineExceeded)
+}
+
+func TestChecker_StartsNotReady(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), time.Second)
+	assert.False(t, checker.IsReady())
+
+	checker.SetReady(true)
+	assert.True(t, checker.IsReady())
+}
+
+func TestCached(t *testing.T) {
+	var calls atomic.Int32
+	check := Cached(time.Hour, func(ctx context.Context) error {
+		calls.Add(1)
+		return errors.New("timeout")
+	})
+
+	assert.EqualError(t, check(context.Background()), "timeout")
+	assert.EqualError(t, check(context.Background()), "timeout")
+	assert.Equal(t, int32(1), calls.Load(), "the result must be reused within the ttl")
+}
+
+func TestCached_CancelledProbeIsNotCached(t *testing.T) {
+	var calls atomic.Int32
+	check := Cached(time.Hour, func(ctx context.Context) error {
+		calls.Add(1)
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, check(ctx))
+	assert.NoError(t, check(context.Background()))
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"encoding/json"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting down"
+)
+
+// Report - the answer of the health endpoints
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler answers as long as the process serves requests, restarting it would not fix a dependency
+func LivenessHandler(logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, logger, http.StatusOK, Report{Status: StatusOK})
+	}
+}
+
+// ReadinessHandler answers 200 only when every check passed, so load balancers only route payments to usable instances
+func ReadinessHandler(checker *Checker, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !checker.IsReady() {
+			writeReport(w, logger, http.StatusServiceUnavailable, Report{Status: StatusShuttingDown})
+			return
+		}
+
+		report := Report{Status: StatusOK, Checks: make(map[string]string)}
+		status := http.StatusOK
+		for name, err := range checker.Run(r.Context()) {
+			if err != nil {
+				report.Checks[name] = err.Error()
+				report.Status = StatusUnavailable
+				status = http.StatusServiceUnavailable
+				continue
+			}
+			report.Checks[name] = StatusOK
+		}
+		writeReport(w, logger, status, report)
+	}
+}
+
+func writeReport(w http.ResponseWriter, logger *zap.Logger, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Error("Failed to encode health report", zap.Error(err))
+	}
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveReport(t *testing.T, handler http.HandlerFunc) (int, Report) {
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	return rr.Code, report
+}
+
+func TestLivenessHandler(t *testing.T) {
+	status, report := serveReport(t, LivenessHandler(zap.NewNop()))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, StatusOK, report.Status)
+}
+
+func TestReadinessHandler_Ready(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), time.Second,
+		Check{Name: CheckOrderStore, Check: func(ctx context.Context) error { return nil }})
+	checker.SetReady(true)
+
+	status, report := serveReport(t, ReadinessHandler(checker, zap.NewNop()))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, Report{Status: StatusOK, Checks: map[string]string{CheckOrderStore: StatusOK}}, report)
+}
+
+func TestReadinessHandler_FailedCheck(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), time.Second,
+		Check{Name: CheckOrderStore, Check: func(ctx context.Context) error { return nil }},
+		Check{Name: CheckZota, Check: func(ctx context.Context) error { return errors.New("zota is unreachable") }})
+	checker.SetReady(true)
+
+	status, report := serveReport(t, ReadinessHandler(checker, zap.NewNop()))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, "zota is unreachable", report.Checks[CheckZota])
+	assert.Equal(t, StatusOK, report.Checks[CheckOrderStore])
+}
+
+func TestReadinessHandler_Draining(t *testing.T) {
+	checker := NewCheckerWith(zap.NewNop(), time.Second,
+		Check{Name: CheckOrderStore, Check: func(ctx context.Context) error {
+			t.Error("checks must not run while draining")
+			return nil
+		}})
+
+	status, report := serveReport(t, ReadinessHandler(checker, zap.NewNop()))
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusShuttingDown, report.Status)
+}
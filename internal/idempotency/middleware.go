@@ -0,0 +1,152 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	maxBodySize  = 1 << 20
+)
+
+// replayedHeaders - the response headers stored with the response, everything else is per request
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Middleware answers repeats of a request carrying the same Idempotency-Key with the first response.
+// A key reused with a different body is rejected, a repeat arriving while the first request is still
+// in flight waits for its response. Keys are scoped to the caller's client certificate, without mutual TLS
+// every caller shares one key space.
+func Middleware(store Store, ttl time.Duration, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				apperror.Write(w, r, logger, apperror.Validation("request validation failed", apperror.FieldError{
+					Field: HeaderKey, Rule: "max", Message: "must be at most 255 characters"}))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				apperror.Write(w, r, logger, apperror.InvalidRequest("invalid request body", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := fingerprint(r, body)
+			// the same key sent by two callers names two different requests
+			callerID := caller(r)
+			scopedKey := callerID + "\n" + key
+
+			for {
+				record, claimed := store.Begin(scopedKey, fingerprint)
+				if claimed {
+					serveFirst(w, r, next, store, scopedKey, ttl)
+					return
+				}
+
+				if record.Fingerprint != fingerprint {
+					logger.Warn("Idempotency key reused with a different request", zap.String("key", key), zap.String("caller", callerID))
+					apperror.Write(w, r, logger, apperror.Conflict("idempotency key was already used for a different request"))
+					return
+				}
+
+				select {
+				case <-record.Done():
+				case <-r.Context().Done():
+					return
+				}
+
+				if record.Response != nil {
+					replay(w, record.Response)
+					return
+				}
+				// the first request was abandoned, try to claim the key again
+			}
+		})
+	}
+}
+
+// serveFirst runs the handler for the claimed key and stores whatever it answered
+func serveFirst(w http.ResponseWriter, r *http.Request, next http.Handler, store Store, key string, ttl time.Duration) {
+	recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	completed := false
+	// a panicking handler answered nothing worth replaying, the key is released for a retry
+	defer func() {
+		if !completed {
+			store.Abandon(key)
+		}
+	}()
+
+	// the response is kept even when the client went away, its retry is the very duplicate to answer
+	next.ServeHTTP(recorder, r)
+
+	header := make(http.Header)
+	for _, name := range replayedHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	store.Complete(key, &Response{StatusCode: recorder.statusCode, Header: header, Body: recorder.body.Bytes()}, ttl)
+	completed = true
+}
+
+func replay(w http.ResponseWriter, response *Response) {
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// caller identifies who sent the request by its verified client certificate, empty without mutual TLS
+func caller(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.String()
+}
+
+// fingerprint - the same key must always come with the same request
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
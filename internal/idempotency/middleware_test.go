@@ -0,0 +1,197 @@
+package idempotency
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+)
+
+type middlewareTestSuite struct {
+	store   *MemoryStore
+	calls   atomic.Int32
+	release chan struct{}
+	handler http.Handler
+}
+
+func (s *middlewareTestSuite) setup(t *testing.T) {
+	s.store = NewMemoryStore()
+	s.release = make(chan struct{})
+	close(s.release)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-s.release
+		call := s.calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Call", "per-request")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]int32{"call": call})
+	})
+	s.handler = Middleware(s.store, time.Hour, zap.NewNop())(next)
+}
+
+func (s *middlewareTestSuite) do(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/deposit", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	rr := httptest.NewRecorder()
+	s.handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMiddleware_ReplaysFirstResponse(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+
+	first := s.do("key-1", `{"orderAmount":"10.00"}`)
+	second := s.do("key-1", `{"orderAmount":"10.00"}`)
+
+	assert.Equal(t, int32(1), s.calls.Load())
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Empty(t, second.Header().Get("X-Call"))
+	assert.Equal(t, "true", second.Header().Get(HeaderReplayed))
+	assert.Empty(t, first.Header().Get(HeaderReplayed))
+}
+
+func TestMiddleware_ScopesKeysToTheClientCertificate(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+
+	send := func(commonName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/deposit", strings.NewReader(`{"orderAmount":"10.00"}`))
+		req.Header.Set(HeaderKey, "key-1")
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}}}
+		rr := httptest.NewRecorder()
+		s.handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	send("shop")
+	crm := send("crm")
+	assert.Equal(t, int32(2), s.calls.Load(), "another caller's key must not be replayed")
+	assert.Empty(t, crm.Header().Get(HeaderReplayed))
+
+	assert.Equal(t, "true", send("shop").Header().Get(HeaderReplayed))
+	assert.Equal(t, int32(2), s.calls.Load())
+}
+
+func TestMiddleware_ReplaysErrors(t *testing.T) {
+	store := NewMemoryStore()
+	calls := 0
+	handler := Middleware(store, time.Hour, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		apperror.Write(w, r, zap.NewNop(), apperror.GatewayUnavailable("payment gateway is unavailable", nil))
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/api/v1/deposit", strings.NewReader(`{}`))
+		req.Header.Set(HeaderKey, "key-1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddleware_WithoutKey(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+
+	s.do("", `{}`)
+	s.do("", `{}`)
+
+	assert.Equal(t, int32(2), s.calls.Load())
+}
+
+func TestMiddleware_DifferentBodyConflicts(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+
+	s.do("key-1", `{"orderAmount":"10.00"}`)
+	rr := s.do("key-1", `{"orderAmount":"99.00"}`)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var response apperror.Response
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, apperror.CodeConflict, response.Error.Code)
+	assert.Equal(t, int32(1), s.calls.Load())
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+
+	rr := s.do(strings.Repeat("k", maxKeyLength+1), `{}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, int32(0), s.calls.Load())
+}
+
+func TestMiddleware_ConcurrentDuplicatesWaitForFirst(t *testing.T) {
+	s := &middlewareTestSuite{}
+	s.setup(t)
+	s.release = make(chan struct{})
+
+	const duplicates = 5
+	responses := make([]*httptest.ResponseRecorder, duplicates)
+	var wg sync.WaitGroup
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = s.do("key-1", `{}`)
+		}(i)
+	}
+
+	// let every duplicate reach the store before the first request finishes
+	assert.Eventually(t, func() bool {
+		s.store.mu.Lock()
+		defer s.store.mu.Unlock()
+		return len(s.store.records) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), s.calls.Load())
+	for _, rr := range responses {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"call":1}`, rr.Body.String())
+	}
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	store := NewMemoryStore()
+	panics := true
+	handler := Middleware(store, time.Hour, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v1/deposit", strings.NewReader(`{}`))
+	req.Header.Set(HeaderKey, "key-1")
+	assert.Panics(t, func() { handler.ServeHTTP(httptest.NewRecorder(), req) })
+
+	panics = false
+	req = httptest.NewRequest("POST", "/api/v1/deposit", strings.NewReader(`{}`))
+	req.Header.Set(HeaderKey, "key-1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
@@ -0,0 +1,108 @@
+package idempotency
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Response - what the first request with a key was answered with
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Record - the state of an idempotency key, Response is set once Done is closed
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	done        chan struct{}
+	expiresAt   time.Time
+}
+
+// Done is closed when the first request finished or was abandoned, an abandoned record has no Response
+func (r *Record) Done() <-chan struct{} {
+	return r.done
+}
+
+type Store interface {
+	// Begin claims the key for the caller, an already known key returns its record unclaimed
+	Begin(key, fingerprint string) (record *Record, claimed bool)
+	// Complete stores the response of the claimed key for ttl and releases the waiting duplicates
+	Complete(key string, response *Response, ttl time.Duration)
+	// Abandon forgets the claimed key without a response, so it can be retried
+	Abandon(key string)
+}
+
+// evictionInterval - how often the expired keys are swept
+const evictionInterval = time.Minute
+
+// MemoryStore keeps the keys in process, duplicates are only recognized by the same instance
+type MemoryStore struct {
+	mu          sync.Mutex
+	records     map[string]*Record
+	lastEvicted time.Time
+	now         func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(key, fingerprint string) (*Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastEvicted) >= evictionInterval {
+		s.evictExpired(now)
+		s.lastEvicted = now
+	}
+
+	if record, ok := s.records[key]; ok && !isExpired(record, now) {
+		return record, false
+	}
+
+	record := &Record{Fingerprint: fingerprint, done: make(chan struct{})}
+	s.records[key] = record
+	return record, true
+}
+
+func (s *MemoryStore) Complete(key string, response *Response, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || record.Response != nil {
+		return
+	}
+	record.Response = response
+	record.expiresAt = s.now().Add(ttl)
+	close(record.done)
+}
+
+func (s *MemoryStore) Abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || record.Response != nil {
+		return
+	}
+	delete(s.records, key)
+	close(record.done)
+}
+
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for key, record := range s.records {
+		if isExpired(record, now) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// isExpired - completed records live for their TTL, in-flight ones never expire
+func isExpired(record *Record, now time.Time) bool {
+	return record.Response != nil && !now.Before(record.expiresAt)
+}
@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_ExpiresCompletedKeys(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	_, claimed := store.Begin("key-1", "fp")
+	assert.True(t, claimed)
+	store.Complete("key-1", &Response{StatusCode: http.StatusOK}, time.Hour)
+
+	record, claimed := store.Begin("key-1", "fp")
+	assert.False(t, claimed)
+	assert.Equal(t, http.StatusOK, record.Response.StatusCode)
+
+	now = now.Add(time.Hour)
+	_, claimed = store.Begin("key-1", "other")
+	assert.True(t, claimed)
+}
+
+func TestMemoryStore_InFlightKeysNeverExpire(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	store.Begin("key-1", "fp")
+	now = now.Add(48 * time.Hour)
+
+	record, claimed := store.Begin("key-1", "fp")
+	assert.False(t, claimed)
+	assert.Nil(t, record.Response)
+}
+
+func TestMemoryStore_Abandon(t *testing.T) {
+	store := NewMemoryStore()
+
+	record, _ := store.Begin("key-1", "fp")
+	store.Abandon("key-1")
+
+	select {
+	case <-record.Done():
+	default:
+		t.Fatal("abandoning must release the waiting duplicates")
+	}
+	assert.Nil(t, record.Response)
+
+	_, claimed := store.Begin("key-1", "fp")
+	assert.True(t, claimed)
+}
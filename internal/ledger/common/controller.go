@@ -0,0 +1,91 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/tracing"
+)
+
+const (
+	// defaultHistoryLimit - how many transactions are listed unless the request asks for another number
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// BalanceHandler
+// @Summary Show a user's wallet balances
+// @Description shows what the user holds per currency, credited from approved deposits
+// @Tags wallet
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param currency query string false "Only the balance in the currency, zero when the user holds none"
+// @Success 200 {array} shared.Balance
+// @Security AdminToken
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Router /users/{userId}/balances [get]
+func BalanceHandler(service ServiceInterface, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "ledger.BalanceHandler")
+		defer span.End()
+
+		balances, err := service.Balances(ctx, chi.URLParam(r, "userId"), r.URL.Query().Get("currency"))
+		tracing.Record(span, err)
+		if err != nil {
+			apperror.Write(w, r, logger, err)
+			return
+		}
+		writeJSON(w, logger, balances)
+	}
+}
+
+// HistoryHandler
+// @Summary List a user's wallet transactions
+// @Description lists the ledger transactions of the user newest first, with their double-entry entries
+// @Tags wallet
+// @Produce json
+// @Param userId path string true "User ID"
+// @Param currency query string false "Only transactions in the currency"
+// @Param limit query int false "Most transactions listed, defaults to 50, at most 500"
+// @Success 200 {array} shared.Transaction
+// @Security AdminToken
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Router /users/{userId}/transactions [get]
+func HistoryHandler(service ServiceInterface, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "ledger.HistoryHandler")
+		defer span.End()
+
+		limit := defaultHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 || parsed > maxHistoryLimit {
+				apperror.Write(w, r, logger, apperror.InvalidRequest("limit must be a number from 1 to 500", err))
+				return
+			}
+			limit = parsed
+		}
+
+		history, err := service.History(ctx, chi.URLParam(r, "userId"), r.URL.Query().Get("currency"), limit)
+		tracing.Record(span, err)
+		if err != nil {
+			apperror.Write(w, r, logger, err)
+			return
+		}
+		writeJSON(w, logger, history)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/money"
+)
+
+type controllerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockService *MockServiceInterface
+	router      *chi.Mux
+}
+
+func (s *controllerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockService = NewMockServiceInterface(s.mockCtrl)
+	logger, _ := zap.NewDevelopment()
+
+	s.router = chi.NewRouter()
+	s.router.Get("/users/{userId}/balances", BalanceHandler(s.mockService, logger))
+	s.router.Get("/users/{userId}/transactions", HistoryHandler(s.mockService, logger))
+}
+
+func (s *controllerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *controllerTestSuite) serve(t *testing.T, target string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodGet, target, nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, request)
+	return rr
+}
+
+func TestBalanceHandler_Success(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().Balances(gomock.Any(), "user123", "USD").
+		Return([]shared.Balance{{UserID: "user123", Currency: "USD", Amount: money.FromMinor(12550, "USD")}}, nil)
+
+	rr := s.serve(t, "/users/user123/balances?currency=USD")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"userId":"user123","currency":"USD","amount":"125.50"}]`, rr.Body.String())
+}
+
+func TestBalanceHandler_ServiceError(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().Balances(gomock.Any(), "user123", "dollars").
+		Return(nil, apperror.Validation("request validation failed"))
+
+	rr := s.serve(t, "/users/user123/balances?currency=dollars")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHistoryHandler_Success(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().History(gomock.Any(), "user123", "", 20).
+		Return([]shared.Transaction{{Reference: "deposit:merchantOrder123"}}, nil)
+
+	rr := s.serve(t, "/users/user123/transactions?limit=20")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var history []shared.Transaction
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&history))
+	require.Len(t, history, 1)
+	assert.Equal(t, "deposit:merchantOrder123", history[0].Reference)
+}
+
+func TestHistoryHandler_DefaultLimit(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().History(gomock.Any(), "user123", "EUR", defaultHistoryLimit).Return([]shared.Transaction{}, nil)
+
+	rr := s.serve(t, "/users/user123/transactions?currency=EUR")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[]`, rr.Body.String())
+}
+
+func TestHistoryHandler_InvalidLimit(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	for _, limit := range []string{"abc", "0", "501"} {
+		rr := s.serve(t, "/users/user123/transactions?limit="+limit)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, limit)
+	}
+}
+
+func TestHistoryHandler_ServiceError(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().History(gomock.Any(), "user123", "", defaultHistoryLimit).Return(nil, errors.New("disk full"))
+
+	rr := s.serve(t, "/users/user123/transactions")
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/money"
+)
+
+// MemoryRepository - keeps the ledger in process, balances are lost on restart
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	transactions []shared.Transaction
+	references   map[string]struct{}
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{references: make(map[string]struct{})}
+}
+
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryRepository) Post(transaction *shared.Transaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.references[transaction.Reference]; ok {
+		return shared.ErrDuplicateReference
+	}
+	m.references[transaction.Reference] = struct{}{}
+
+	posted := *transaction
+	posted.Entries = append([]shared.Entry(nil), transaction.Entries...)
+	m.transactions = append(m.transactions, posted)
+	return nil
+}
+
+func (m *MemoryRepository) HasReference(reference string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.references[reference]
+	return ok, nil
+}
+
+func (m *MemoryRepository) Balances(account string) ([]money.Amount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	balances := make(map[string]money.Amount)
+	for _, transaction := range m.transactions {
+		for _, entry := range transaction.Entries {
+			if entry.Account != account {
+				continue
+			}
+
+			balance, ok := balances[transaction.Currency]
+			if !ok {
+				balance = money.FromMinor(0, transaction.Currency)
+			}
+			amount := entry.Amount
+			if entry.Direction == shared.Debit {
+				amount = amount.Neg()
+			}
+
+			var err error
+			if balances[transaction.Currency], err = balance.Add(amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	amounts := make([]money.Amount, 0, len(balances))
+	for _, balance := range balances {
+		amounts = append(amounts, balance)
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].Currency() < amounts[j].Currency() })
+	return amounts, nil
+}
+
+func (m *MemoryRepository) History(account, currency string, limit int) ([]shared.Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var history []shared.Transaction
+	// posted in order, so walking backwards gives the newest first
+	for i := len(m.transactions) - 1; i >= 0 && len(history) < limit; i-- {
+		transaction := m.transactions[i]
+		if (currency == "" || transaction.Currency == currency) && touches(transaction, account) {
+			transaction.Entries = append([]shared.Entry(nil), transaction.Entries...)
+			history = append(history, transaction)
+		}
+	}
+	return history, nil
+}
+
+func touches(transaction shared.Transaction, account string) bool {
+	for _, entry := range transaction.Entries {
+		if entry.Account == account {
+			return true
+		}
+	}
+	return false
+}
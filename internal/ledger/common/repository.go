@@ -0,0 +1,42 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/ledger/shared"
+	order "zota-dev-challenge/internal/order/common"
+)
+
+const defaultSQLiteDSN = "ledger.db"
+
+// NewRepository keeps the ledger in the kind of store the orders are kept in, in-memory by default
+func NewRepository(lc fx.Lifecycle, logger *zap.Logger, config *config.Config) (shared.LedgerRepository, error) {
+	switch config.OrderStore {
+	case "", order.StoreMemory:
+		logger.Info("Using in-memory ledger")
+		return NewMemoryRepository(), nil
+	case order.StoreSQLite:
+		dsn := config.LedgerStoreDSN
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+
+		repository, err := NewSQLiteRepository(dsn)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return repository.Close()
+			},
+		})
+
+		logger.Info("Using SQLite ledger", zap.String("dsn", dsn))
+		return repository, nil
+	default:
+		return nil, fmt.Errorf("unknown ledger store %q", config.OrderStore)
+	}
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/money"
+)
+
+func newTestTransaction(reference, userID, amount, currency string, createdAt time.Time) *shared.Transaction {
+	value, _ := money.Parse(amount, currency)
+	return &shared.Transaction{
+		ID:              reference + "-id",
+		Reference:       reference,
+		Kind:            shared.KindDeposit,
+		UserID:          userID,
+		MerchantOrderID: reference,
+		Currency:        currency,
+		Amount:          value,
+		Entries: []shared.Entry{
+			{Account: shared.ZotaAccount, Direction: shared.Debit, Amount: value},
+			{Account: shared.UserAccount(userID), Direction: shared.Credit, Amount: value},
+		},
+		CreatedAt: createdAt,
+	}
+}
+
+// testRepository - the behaviour every LedgerRepository implementation must share
+func testRepository(t *testing.T, repository shared.LedgerRepository) {
+	require.NoError(t, repository.Ping(context.Background()))
+	now := time.Now().UTC()
+
+	require.NoError(t, repository.Post(newTestTransaction("deposit:1", "user123", "100.00", "USD", now)))
+	require.NoError(t, repository.Post(newTestTransaction("deposit:2", "user123", "25.50", "USD", now.Add(time.Second))))
+	require.NoError(t, repository.Post(newTestTransaction("deposit:3", "user123", "1000", "JPY", now.Add(2*time.Second))))
+	require.NoError(t, repository.Post(newTestTransaction("deposit:4", "other", "7.00", "USD", now.Add(3*time.Second))))
+
+	assert.ErrorIs(t, repository.Post(newTestTransaction("deposit:1", "user123", "100.00", "USD", now)),
+		shared.ErrDuplicateReference, "a reference is posted once")
+
+	posted, err := repository.HasReference("deposit:1")
+	require.NoError(t, err)
+	assert.True(t, posted)
+	posted, err = repository.HasReference("deposit:5")
+	require.NoError(t, err)
+	assert.False(t, posted)
+
+	unbalanced := newTestTransaction("deposit:5", "user123", "10.00", "USD", now)
+	unbalanced.Entries[0].Amount = money.FromMinor(900, "USD")
+	assert.ErrorIs(t, repository.Post(unbalanced), shared.ErrUnbalanced)
+
+	balances, err := repository.Balances(shared.UserAccount("user123"))
+	require.NoError(t, err)
+	require.Len(t, balances, 2)
+	assert.Equal(t, "JPY", balances[0].Currency())
+	assert.Equal(t, "1000", balances[0].String())
+	assert.Equal(t, "USD", balances[1].Currency())
+	assert.Equal(t, "125.50", balances[1].String())
+
+	settlement, err := repository.Balances(shared.ZotaAccount)
+	require.NoError(t, err)
+	require.Len(t, settlement, 2)
+	assert.Equal(t, "-132.50", settlement[1].String(), "every credit has its debit")
+
+	history, err := repository.History(shared.UserAccount("user123"), "", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "deposit:3", history[0].Reference, "newest transactions come first")
+	assert.Equal(t, "deposit:1", history[2].Reference)
+	assert.Equal(t, "100.00", history[2].Amount.String())
+	assert.True(t, now.Equal(history[2].CreatedAt))
+	require.Len(t, history[2].Entries, 2)
+	assert.Equal(t, shared.Credit, history[2].Entries[1].Direction)
+
+	history, err = repository.History(shared.UserAccount("user123"), "USD", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "deposit:2", history[0].Reference)
+
+	history, err = repository.History(shared.UserAccount("nobody"), "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, NewMemoryRepository())
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repository, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "ledger.db"))
+	require.NoError(t, err)
+	defer repository.Close()
+
+	testRepository(t, repository)
+}
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id                TEXT PRIMARY KEY,
+    reference         TEXT    NOT NULL UNIQUE,
+    kind              TEXT    NOT NULL,
+    user_id           TEXT    NOT NULL,
+    merchant_order_id TEXT    NOT NULL DEFAULT '',
+    currency          TEXT    NOT NULL,
+    amount_minor      INTEGER NOT NULL,
+    requested_amount  TEXT    NOT NULL DEFAULT '',
+    created_at        INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id TEXT    NOT NULL REFERENCES ledger_transactions (id),
+    account        TEXT    NOT NULL,
+    direction      TEXT    NOT NULL CHECK (direction IN ('debit', 'credit')),
+    currency       TEXT    NOT NULL,
+    amount_minor   INTEGER NOT NULL CHECK (amount_minor > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries (account, currency);
@@ -0,0 +1,231 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/money"
+	orderCommon "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+var errNoUser = errors.New("order has no user to credit")
+
+type ServiceInterface interface {
+	Balances(ctx context.Context, userID, currency string) ([]shared.Balance, error)
+	History(ctx context.Context, userID, currency string, limit int) ([]shared.Transaction, error)
+}
+
+type Service struct {
+	logger *zap.Logger
+	config *config.Config
+	ledger shared.LedgerRepository
+	orders orderShared.OrderRepository
+	now    func() time.Time
+}
+
+// NewService subscribes to the state machine, so deposits are credited however they got approved.
+// A credit that failed is retried by Sweep.
+func NewService(logger *zap.Logger, config *config.Config, ledger shared.LedgerRepository, orders orderShared.OrderRepository,
+	stateMachine *orderCommon.StateMachine) *Service {
+	s := &Service{logger: logger, config: config, ledger: ledger, orders: orders, now: time.Now}
+	stateMachine.OnTransition(s.onTransition)
+	return s
+}
+
+func (s *Service) onTransition(order orderShared.Order, transition orderShared.Transition) {
+	if transition.To != orderShared.StatusApproved || order.Type == orderShared.TypePayout {
+		return
+	}
+
+	if _, err := s.CreditDeposit(order, transition); err != nil && !errors.Is(err, shared.ErrDuplicateReference) {
+		s.logger.Error("Failed to credit approved deposit", zap.String("merchantOrderID", order.MerchantOrderID),
+			zap.String("userID", order.UserID), zap.Error(err))
+	}
+}
+
+// CreditDeposit credits the user with the amount Zota settled for the approved deposit, falling back to the
+// requested amount when Zota did not report one. A deposit is credited once, again fails with ErrDuplicateReference.
+func (s *Service) CreditDeposit(order orderShared.Order, transition orderShared.Transition) (*shared.Transaction, error) {
+	if order.UserID == "" {
+		return nil, errNoUser
+	}
+
+	settled := transition.Amount
+	if settled == "" {
+		settled = order.Amount
+	}
+	amount, err := money.ParsePositive(settled, order.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settled amount %q: %w", settled, err)
+	}
+
+	transaction := &shared.Transaction{
+		ID:              uuid.New().String(),
+		Reference:       depositReference(order.MerchantOrderID),
+		Kind:            shared.KindDeposit,
+		UserID:          order.UserID,
+		MerchantOrderID: order.MerchantOrderID,
+		Currency:        amount.Currency(),
+		Amount:          amount,
+		Entries: []shared.Entry{
+			{Account: shared.ZotaAccount, Direction: shared.Debit, Amount: amount},
+			{Account: shared.UserAccount(order.UserID), Direction: shared.Credit, Amount: amount},
+		},
+		CreatedAt: transition.CreatedAt,
+	}
+	if requested, err := money.Parse(order.Amount, order.Currency); err != nil || !requested.Equal(amount) {
+		transaction.RequestedAmount = order.Amount
+		s.logger.Warn("Zota settled another amount than requested", zap.String("merchantOrderID", order.MerchantOrderID),
+			zap.String("requested", order.Amount), zap.String("settled", amount.String()))
+	}
+
+	err = s.ledger.Post(transaction)
+	if errors.Is(err, shared.ErrDuplicateReference) {
+		s.logger.Info("Deposit was already credited", zap.String("merchantOrderID", order.MerchantOrderID))
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Credited deposit",
+		zap.String("merchantOrderID", order.MerchantOrderID),
+		zap.String("userID", order.UserID),
+		zap.String("amount", amount.String()),
+		zap.String("currency", amount.Currency()))
+	return transaction, nil
+}
+
+// Sweep credits the approved deposits of the last LedgerSweepWindow that are missing from the ledger, because posting
+// failed or the process stopped between storing the approval and crediting it. It returns how many it credited.
+// Orders imported from Zota's report never went through the state machine and are left alone.
+func (s *Service) Sweep(ctx context.Context) (int, error) {
+	approved, err := s.orders.ListByStatus(orderShared.StatusApproved, s.now().Add(-s.config.LedgerSweepWindow))
+	if err != nil {
+		s.logger.Error("Failed to list approved orders", zap.Error(err))
+		return 0, err
+	}
+
+	credited := 0
+	for _, order := range approved {
+		if err := ctx.Err(); err != nil {
+			return credited, err
+		}
+		if order.Type == orderShared.TypePayout || order.UserID == "" {
+			continue
+		}
+
+		posted, err := s.ledger.HasReference(depositReference(order.MerchantOrderID))
+		if err != nil {
+			s.logger.Error("Failed to look up deposit credit", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+			return credited, err
+		}
+		if posted {
+			continue
+		}
+
+		approval, ok, err := s.approval(order.MerchantOrderID)
+		if err != nil || !ok {
+			continue
+		}
+
+		// the listener may credit it at the same time, the reference keeps it to a single credit
+		if _, err := s.CreditDeposit(order, approval); err != nil {
+			if !errors.Is(err, shared.ErrDuplicateReference) {
+				s.logger.Error("Failed to credit approved deposit", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+			}
+			continue
+		}
+		credited++
+	}
+
+	if credited > 0 {
+		s.logger.Warn("Credited approved deposits missing from the ledger", zap.Int("credited", credited))
+	}
+	return credited, nil
+}
+
+// approval returns the transition that approved the order, it carries the settled amount
+func (s *Service) approval(merchantOrderID string) (orderShared.Transition, bool, error) {
+	transitions, err := s.orders.ListTransitions(merchantOrderID)
+	if err != nil {
+		s.logger.Error("Failed to read order transitions", zap.String("merchantOrderID", merchantOrderID), zap.Error(err))
+		return orderShared.Transition{}, false, err
+	}
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].To == orderShared.StatusApproved {
+			return transitions[i], true, nil
+		}
+	}
+	return orderShared.Transition{}, false, nil
+}
+
+func depositReference(merchantOrderID string) string {
+	return shared.KindDeposit + ":" + merchantOrderID
+}
+
+// Balances returns the user's balance per currency, or only in the given currency, which is zero before any deposit
+func (s *Service) Balances(ctx context.Context, userID, currency string) ([]shared.Balance, error) {
+	currency, err := validate(userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := s.ledger.Balances(shared.UserAccount(userID))
+	if err != nil {
+		s.logger.Error("Failed to read balances", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+
+	balances := make([]shared.Balance, 0, len(amounts))
+	for _, amount := range amounts {
+		if currency == "" || amount.Currency() == currency {
+			balances = append(balances, shared.Balance{UserID: userID, Currency: amount.Currency(), Amount: amount})
+		}
+	}
+	if currency != "" && len(balances) == 0 {
+		balances = append(balances, shared.Balance{UserID: userID, Currency: currency, Amount: money.FromMinor(0, currency)})
+	}
+	return balances, nil
+}
+
+// History returns up to limit transactions of the user, newest first
+func (s *Service) History(ctx context.Context, userID, currency string, limit int) ([]shared.Transaction, error) {
+	currency, err := validate(userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.ledger.History(shared.UserAccount(userID), currency, limit)
+	if err != nil {
+		s.logger.Error("Failed to read transaction history", zap.String("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	if history == nil {
+		history = []shared.Transaction{}
+	}
+	return history, nil
+}
+
+// validate checks the user and returns the currency filter in upper case
+func validate(userID, currency string) (string, error) {
+	if strings.TrimSpace(userID) == "" {
+		return "", apperror.Validation("request validation failed",
+			apperror.FieldError{Field: "userId", Rule: "required", Message: "is required"})
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency != "" && len(currency) != 3 {
+		return "", apperror.Validation("request validation failed",
+			apperror.FieldError{Field: "currency", Rule: "iso4217", Message: "must be a 3-letter currency code"})
+	}
+	return currency, nil
+}
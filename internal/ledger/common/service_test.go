@@ -0,0 +1,188 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+type serviceTestSuite struct {
+	orders       *order.MemoryRepository
+	ledger       *MemoryRepository
+	failing      *failingLedger
+	stateMachine *order.StateMachine
+	service      *Service
+}
+
+func (s *serviceTestSuite) setup(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s.orders = order.NewMemoryRepository()
+	s.ledger = NewMemoryRepository()
+	s.failing = &failingLedger{LedgerRepository: s.ledger}
+	s.stateMachine = order.NewStateMachine(logger, s.orders, metrics.New())
+	s.service = NewService(logger, &config.Config{LedgerSweepWindow: time.Hour}, s.failing, s.orders, s.stateMachine)
+}
+
+// failingLedger fails the next posts, as a ledger database that cannot be reached would
+type failingLedger struct {
+	shared.LedgerRepository
+	failures int
+}
+
+func (l *failingLedger) Post(transaction *shared.Transaction) error {
+	if l.failures > 0 {
+		l.failures--
+		return errors.New("ledger store unavailable")
+	}
+	return l.LedgerRepository.Post(transaction)
+}
+
+func (s *serviceTestSuite) createOrder(t *testing.T, merchantOrderID, orderType string) {
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID: merchantOrderID,
+		Type:            orderType,
+		UserID:          "user123",
+		Amount:          "100.00",
+		Currency:        "USD",
+		Status:          orderShared.StatusPending,
+	}))
+}
+
+func (s *serviceTestSuite) balance(t *testing.T) string {
+	balances, err := s.service.Balances(context.Background(), "user123", "usd")
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	return balances[0].Amount.String()
+}
+
+func TestService_CreditsApprovedDepositOnce(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	s.createOrder(t, "merchantOrder123", orderShared.TypeSale)
+
+	_, err := s.stateMachine.Settle("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "", "100.00")
+	require.NoError(t, err)
+	// the status poll reporting the approval again is a no-op, a replayed credit would be rejected anyway
+	_, err = s.stateMachine.Settle("merchantOrder123", orderShared.StatusApproved, orderShared.SourceStatusPoll, "", "100.00")
+	require.NoError(t, err)
+
+	order, err := s.orders.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	transitions, err := s.orders.ListTransitions("merchantOrder123")
+	require.NoError(t, err)
+	_, err = s.service.CreditDeposit(*order, transitions[len(transitions)-1])
+	assert.ErrorIs(t, err, shared.ErrDuplicateReference)
+
+	assert.Equal(t, "100.00", s.balance(t))
+
+	history, err := s.service.History(context.Background(), "user123", "", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "deposit:merchantOrder123", history[0].Reference)
+	assert.Empty(t, history[0].RequestedAmount)
+}
+
+func TestService_CreditsSettledAmount(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	s.createOrder(t, "merchantOrder123", orderShared.TypeSale)
+
+	_, err := s.stateMachine.Settle("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "", "90.00")
+	require.NoError(t, err)
+
+	assert.Equal(t, "90.00", s.balance(t))
+
+	history, err := s.service.History(context.Background(), "user123", "USD", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "100.00", history[0].RequestedAmount)
+}
+
+func TestService_FallsBackToRequestedAmount(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	s.createOrder(t, "merchantOrder123", orderShared.TypeSale)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceAdmin, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "100.00", s.balance(t))
+}
+
+func TestService_IgnoresDeclinedDepositsAndPayouts(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	s.createOrder(t, "declined", orderShared.TypeSale)
+	s.createOrder(t, "payout", orderShared.TypePayout)
+
+	_, err := s.stateMachine.Settle("declined", orderShared.StatusDeclined, orderShared.SourceCallback, "insufficient funds", "100.00")
+	require.NoError(t, err)
+	_, err = s.stateMachine.Settle("payout", orderShared.StatusApproved, orderShared.SourceCallback, "", "100.00")
+	require.NoError(t, err)
+
+	assert.Equal(t, "0.00", s.balance(t), "the currency asked for is listed with a zero balance")
+
+	balances, err := s.service.Balances(context.Background(), "user123", "")
+	require.NoError(t, err)
+	assert.Empty(t, balances)
+}
+
+func TestService_ValidatesRequest(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+
+	_, err := s.service.Balances(context.Background(), " ", "")
+	assert.Equal(t, apperror.CodeValidation, apperror.From(err).Code)
+
+	_, err = s.service.History(context.Background(), "user123", "dollars", 10)
+	assert.Equal(t, apperror.CodeValidation, apperror.From(err).Code)
+}
+
+func TestService_SweepCreditsMissedDeposits(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	s.createOrder(t, "merchantOrder123", orderShared.TypeSale)
+	s.createOrder(t, "payout123", orderShared.TypePayout)
+
+	// the ledger is down when the approval arrives, the order is approved all the same
+	s.failing.failures = 1
+	_, err := s.stateMachine.Settle("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "", "90.00")
+	require.NoError(t, err)
+	_, err = s.stateMachine.Settle("payout123", orderShared.StatusApproved, orderShared.SourceCallback, "", "")
+	require.NoError(t, err)
+	history, err := s.service.History(context.Background(), "user123", "", 10)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	credited, err := s.service.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, credited, "payouts are not credited")
+	assert.Equal(t, "90.00", s.balance(t), "the sweep credits the settled amount")
+
+	credited, err = s.service.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, credited, "a deposit is credited once")
+	assert.Equal(t, "90.00", s.balance(t))
+}
+
+func TestService_SweepSkipsImportedOrders(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	// imported from Zota's report as approved, without a transition
+	require.NoError(t, s.orders.Create(&orderShared.Order{MerchantOrderID: "imported", Type: orderShared.TypeSale,
+		UserID: "user123", Amount: "100.00", Currency: "USD", Status: orderShared.StatusApproved}))
+
+	credited, err := s.service.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, credited)
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/ledger/shared"
+	"zota-dev-challenge/internal/money"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+const transactionColumns = `t.id, t.reference, t.kind, t.user_id, t.merchant_order_id, t.currency, t.amount_minor, t.requested_amount, t.created_at`
+
+// SQLiteRepository - keeps the ledger in an embedded SQLite database
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRepository(dsn string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	// SQLite allows a single writer, serializing access also makes the duplicate check and the insert atomic
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate ledger: %w", err)
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+func (s *SQLiteRepository) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteRepository) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteRepository) Post(transaction *shared.Transaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM ledger_transactions WHERE reference = ?`, transaction.Reference).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up ledger reference %s: %w", transaction.Reference, err)
+	}
+	if exists > 0 {
+		return shared.ErrDuplicateReference
+	}
+
+	_, err = tx.Exec(`INSERT INTO ledger_transactions (id, reference, kind, user_id, merchant_order_id, currency, amount_minor,
+		requested_amount, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		transaction.ID, transaction.Reference, transaction.Kind, transaction.UserID, transaction.MerchantOrderID,
+		transaction.Currency, transaction.Amount.Minor(), transaction.RequestedAmount, transaction.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to insert ledger transaction %s: %w", transaction.Reference, err)
+	}
+
+	for _, entry := range transaction.Entries {
+		_, err = tx.Exec(`INSERT INTO ledger_entries (transaction_id, account, direction, currency, amount_minor) VALUES (?, ?, ?, ?, ?)`,
+			transaction.ID, entry.Account, entry.Direction, transaction.Currency, entry.Amount.Minor())
+		if err != nil {
+			return fmt.Errorf("failed to insert ledger entry of %s: %w", transaction.Reference, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteRepository) HasReference(reference string) (bool, error) {
+	var exists int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM ledger_transactions WHERE reference = ?`, reference).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up ledger reference %s: %w", reference, err)
+	}
+	return exists > 0, nil
+}
+
+func (s *SQLiteRepository) Balances(account string) ([]money.Amount, error) {
+	rows, err := s.db.Query(`SELECT currency, SUM(CASE direction WHEN 'credit' THEN amount_minor ELSE -amount_minor END)
+		FROM ledger_entries WHERE account = ? GROUP BY currency ORDER BY currency`, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum the balances of %s: %w", account, err)
+	}
+	defer rows.Close()
+
+	var balances []money.Amount
+	for rows.Next() {
+		var currency string
+		var minor int64
+		if err := rows.Scan(&currency, &minor); err != nil {
+			return nil, err
+		}
+		balances = append(balances, money.FromMinor(minor, currency))
+	}
+	return balances, rows.Err()
+}
+
+func (s *SQLiteRepository) History(account, currency string, limit int) ([]shared.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM ledger_transactions t
+		WHERE EXISTS (SELECT 1 FROM ledger_entries e WHERE e.transaction_id = t.id AND e.account = ?)`
+	args := []any{account}
+	if currency != "" {
+		query += ` AND t.currency = ?`
+		args = append(args, currency)
+	}
+	query += ` ORDER BY t.created_at DESC, t.rowid DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %w", account, err)
+	}
+
+	var history []shared.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		history = append(history, *transaction)
+	}
+	// the connection is needed for the entries
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadEntries(history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (s *SQLiteRepository) loadEntries(transactions []shared.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*shared.Transaction, len(transactions))
+	args := make([]any, 0, len(transactions))
+	for i := range transactions {
+		byID[transactions[i].ID] = &transactions[i]
+		args = append(args, transactions[i].ID)
+	}
+
+	rows, err := s.db.Query(`SELECT transaction_id, account, direction, currency, amount_minor FROM ledger_entries
+		WHERE transaction_id IN (?`+strings.Repeat(", ?", len(args)-1)+`) ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID, currency string
+		var entry shared.Entry
+		var minor int64
+		if err := rows.Scan(&transactionID, &entry.Account, &entry.Direction, &currency, &minor); err != nil {
+			return err
+		}
+		entry.Amount = money.FromMinor(minor, currency)
+
+		transaction := byID[transactionID]
+		transaction.Entries = append(transaction.Entries, entry)
+	}
+	return rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTransaction(row scanner) (*shared.Transaction, error) {
+	var transaction shared.Transaction
+	var amountMinor, createdAt int64
+	if err := row.Scan(&transaction.ID, &transaction.Reference, &transaction.Kind, &transaction.UserID,
+		&transaction.MerchantOrderID, &transaction.Currency, &amountMinor, &transaction.RequestedAmount, &createdAt); err != nil {
+		return nil, err
+	}
+	transaction.Amount = money.FromMinor(amountMinor, transaction.Currency)
+	transaction.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &transaction, nil
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+	"zota-dev-challenge/internal/config"
+)
+
+// Sweeper - credits approved deposits the ledger missed, once on start and then on a schedule
+type Sweeper struct {
+	logger  *zap.Logger
+	config  *config.Config
+	service *Service
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSweeper(logger *zap.Logger, config *config.Config, service *Service) *Sweeper {
+	return &Sweeper{logger: logger, config: config, service: service}
+}
+
+// Start sweeps in the background until Stop is called, the first sweep covers what a previous process left behind
+func (s *Sweeper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.config.LedgerSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			// Sweep logged why it failed, the next sweep tries again
+			_, _ = s.service.Sweep(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a sweep in flight and waits for it to wind down or for the context to expire
+func (s *Sweeper) Stop(ctx context.Context) error {
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
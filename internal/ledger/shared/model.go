@@ -0,0 +1,104 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+	"zota-dev-challenge/internal/money"
+)
+
+var (
+	// ErrDuplicateReference - a transaction with the reference was already posted, posting it again would count it twice
+	ErrDuplicateReference = errors.New("ledger transaction already posted")
+	// ErrUnbalanced - the debits of a transaction do not equal its credits
+	ErrUnbalanced = errors.New("ledger transaction does not balance")
+)
+
+// Transaction kinds
+const (
+	KindDeposit = "deposit"
+)
+
+// Entry directions
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// ZotaAccount - the funds Zota holds for us, deposits are debited to it
+const ZotaAccount = "zota:settlement"
+
+// UserAccount - the wallet of a user, deposits are credited to it
+func UserAccount(userID string) string {
+	return "user:" + userID
+}
+
+// Entry - one side of a transaction
+type Entry struct {
+	Account   string       `json:"account"`
+	Direction string       `json:"direction"`
+	Amount    money.Amount `json:"amount" swaggertype:"string"`
+} //@name LedgerEntry
+
+// Transaction - a balanced set of entries, all in the transaction's currency
+type Transaction struct {
+	ID string `json:"id"`
+	// Reference - what the transaction records, unique so nothing is posted twice, e.g. deposit:<merchantOrderId>
+	Reference       string       `json:"reference"`
+	Kind            string       `json:"kind"`
+	UserID          string       `json:"userId"`
+	MerchantOrderID string       `json:"merchantOrderId"`
+	Currency        string       `json:"currency"`
+	Amount          money.Amount `json:"amount" swaggertype:"string"`
+	// RequestedAmount - set when Zota settled another amount than the order asked for
+	RequestedAmount string    `json:"requestedAmount,omitempty"`
+	Entries         []Entry   `json:"entries"`
+	CreatedAt       time.Time `json:"createdAt"`
+} //@name LedgerTransaction
+
+// Balance - what a user holds in a currency
+type Balance struct {
+	UserID   string       `json:"userId"`
+	Currency string       `json:"currency"`
+	Amount   money.Amount `json:"amount" swaggertype:"string"`
+} //@name Balance
+
+// Validate checks the transaction balances: every entry is positive, in the currency, and debits equal credits
+func (t *Transaction) Validate() error {
+	debits, credits := money.FromMinor(0, t.Currency), money.FromMinor(0, t.Currency)
+	for _, entry := range t.Entries {
+		if entry.Amount.Currency() != t.Currency || entry.Amount.Minor() <= 0 {
+			return ErrUnbalanced
+		}
+
+		var err error
+		switch entry.Direction {
+		case Debit:
+			debits, err = debits.Add(entry.Amount)
+		case Credit:
+			credits, err = credits.Add(entry.Amount)
+		default:
+			return ErrUnbalanced
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(t.Entries) < 2 || !debits.Equal(credits) {
+		return ErrUnbalanced
+	}
+	return nil
+}
+
+type LedgerRepository interface {
+	// Post stores a balanced transaction atomically, it fails with ErrDuplicateReference when the reference is taken
+	Post(transaction *Transaction) error
+	// HasReference reports whether a transaction with the reference was posted
+	HasReference(reference string) (bool, error)
+	// Balances returns the credits minus the debits of the account per currency, only currencies with entries
+	Balances(account string) ([]money.Amount, error)
+	// History returns up to limit transactions with entries on the account, newest first, any currency when empty
+	History(account, currency string, limit int) ([]Transaction, error)
+	// Ping reports whether the store can be reached
+	Ping(ctx context.Context) error
+}
@@ -2,12 +2,35 @@ package internal
 
 import (
 	"go.uber.org/zap"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/redact"
 )
 
-func InitLogger() *zap.Logger {
-	logger, err := zap.NewProduction()
+// InitLogger logs JSON in live and readable lines in the sandbox, LOG_LEVEL applies to both.
+// Customer data and secrets are redacted unless LOG_UNREDACTED is set, which the configuration only allows in the sandbox.
+func InitLogger(config *config.Config) (*zap.Logger, error) {
+	loggerConfig := zap.NewProductionConfig()
+	if config.Profile.DevelopmentLogger {
+		loggerConfig = zap.NewDevelopmentConfig()
+	}
+	loggerConfig.Level = zap.NewAtomicLevelAt(config.LogLevel)
+
+	options := []zap.Option{zap.Fields(zap.String("environment", config.Environment))}
+	if !config.LogUnredacted {
+		secrets := []string{config.ZotaAPISecretKey}
+		for _, subscription := range config.Webhooks {
+			secrets = append(secrets, subscription.Secret)
+		}
+		redactor := redact.New(secrets...)
+		options = append(options, zap.WrapCore(redactor.Core))
+	}
+
+	logger, err := loggerConfig.Build(options...)
 	if err != nil {
-		panic("Error initializing zap logger: " + err.Error())
+		return nil, err
+	}
+	if config.LogUnredacted {
+		logger.Warn("Logging customer data and signatures unredacted, LOG_UNREDACTED must not stay on")
 	}
-	return logger
+	return logger, nil
 }
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(Middleware(m))
+	r.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/orders/{id}", http.MethodGet, "202")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404")))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.SetPendingOrders(3)
+
+	rr := httptest.NewRecorder()
+	Handler(m).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, _ := io.ReadAll(rr.Body)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, string(body), "merchant_pending_orders 3")
+	assert.Contains(t, string(body), "go_goroutines")
+}
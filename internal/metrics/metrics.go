@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"strconv"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+)
+
+const namespace = "merchant"
+
+// Zota operations, the operation label of the Zota call metrics
+const (
+	OperationDeposit = "deposit"
+	OperationStatus  = "status"
+	OperationPayout  = "payout"
+	OperationReport  = "report"
+)
+
+// Outcomes of a Zota call, derived from the error the gateway returned
+const (
+	OutcomeSuccess     = "success"
+	OutcomeRejected    = "rejected"
+	OutcomeError       = "error"
+	OutcomeUnavailable = "unavailable"
+)
+
+// Metrics - the collectors of the service, kept in their own registry so tests can create as many as they need
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	zotaCalls       *prometheus.CounterVec
+	zotaDuration    *prometheus.HistogramVec
+	depositRequests *prometheus.CounterVec
+	deposits        *prometheus.CounterVec
+	pendingOrders   prometheus.Gauge
+	webhooks        *prometheus.CounterVec
+	discrepancies   *prometheus.GaugeVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "API requests by route, method and HTTP status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "API request latency by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		zotaCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "zota_calls_total",
+			Help:      "Zota API calls by operation, outcome and the error code Zota answered with.",
+		}, []string{"operation", "outcome", "code"}),
+		zotaDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "zota_call_duration_seconds",
+			Help:      "Zota API latency by operation and outcome, retries included.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"operation", "outcome"}),
+		depositRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deposit_requests_total",
+			Help:      "Deposit requests by currency and whether Zota accepted them.",
+		}, []string{"currency", "outcome"}),
+		deposits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deposits_total",
+			Help:      "Deposits that reached a final status, by currency and status.",
+		}, []string{"currency", "status"}),
+		pendingOrders: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pending_orders",
+			Help:      "Orders the poller checks, without a final status, counted by its last full pass.",
+		}),
+		webhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_attempts_total",
+			Help:      "Webhook delivery attempts by subscription and outcome: delivered, failed or dead.",
+		}, []string{"subscription", "outcome"}),
+		discrepancies: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "reconciliation_discrepancies",
+			Help:      "Differences between the local orders and Zota's orders report found by the last reconciliation, by kind.",
+		}, []string{"kind"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.zotaCalls, m.zotaDuration,
+		m.depositRequests, m.deposits, m.pendingOrders,
+		m.webhooks, m.discrepancies,
+	)
+	return m
+}
+
+// ObserveHTTPRequest records an answered API request, route is the pattern so IDs in the path do not explode the labels
+func (m *Metrics) ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, statusLabel(status)).Inc()
+	m.httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ObserveZotaCall records a finished Zota call, err is what the gateway returned
+func (m *Metrics) ObserveZotaCall(operation string, started time.Time, err error) {
+	outcome, code := classify(err)
+	m.zotaCalls.WithLabelValues(operation, outcome, code).Inc()
+	m.zotaDuration.WithLabelValues(operation, outcome).Observe(time.Since(started).Seconds())
+}
+
+// ObserveDepositRequest records whether Zota accepted a deposit in the currency
+func (m *Metrics) ObserveDepositRequest(currency string, err error) {
+	outcome, _ := classify(err)
+	m.depositRequests.WithLabelValues(currency, outcome).Inc()
+}
+
+// ObserveFinalDeposit records a deposit that reached a final status
+func (m *Metrics) ObserveFinalDeposit(currency, status string) {
+	m.deposits.WithLabelValues(currency, status).Inc()
+}
+
+// SetPendingOrders reports the size of the pending order backlog
+func (m *Metrics) SetPendingOrders(count int) {
+	m.pendingOrders.Set(float64(count))
+}
+
+// ObserveWebhookAttempt records an attempt to deliver a webhook, dead is the last failed one
+func (m *Metrics) ObserveWebhookAttempt(subscription, outcome string) {
+	m.webhooks.WithLabelValues(subscription, outcome).Inc()
+}
+
+// SetReconciliationDiscrepancies reports the discrepancies of the last reconciliation by kind, kinds not found are zero
+func (m *Metrics) SetReconciliationDiscrepancies(kinds []string, counts map[string]int) {
+	for _, kind := range kinds {
+		m.discrepancies.WithLabelValues(kind).Set(float64(counts[kind]))
+	}
+}
+
+// classify maps a gateway error to the outcome label and Zota's own error code, if Zota answered with one
+func classify(err error) (outcome, code string) {
+	if err == nil {
+		return OutcomeSuccess, ""
+	}
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		return OutcomeError, ""
+	}
+	if appErr.Gateway != nil {
+		code = appErr.Gateway.Code
+	}
+
+	switch appErr.Code {
+	case apperror.CodeGatewayRejected:
+		return OutcomeRejected, code
+	case apperror.CodeGatewayUnavailable:
+		return OutcomeUnavailable, code
+	default:
+		return OutcomeError, code
+	}
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		// nothing was written, net/http answers 200
+		status = 200
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+)
+
+func TestClassify(t *testing.T) {
+	rejected := apperror.GatewayRejected("payment gateway rejected the request", errors.New("invalid signature"))
+	rejected.Gateway = &apperror.GatewayFailure{Code: "401", Message: "invalid signature"}
+
+	tests := []struct {
+		name    string
+		err     error
+		outcome string
+		code    string
+	}{
+		{"success", nil, OutcomeSuccess, ""},
+		{"rejected with Zota code", rejected, OutcomeRejected, "401"},
+		{"unavailable", apperror.GatewayUnavailable("payment gateway is unreachable", errors.New("timeout")), OutcomeUnavailable, ""},
+		{"malformed", apperror.GatewayError("malformed payment gateway response", errors.New("eof")), OutcomeError, ""},
+		{"untyped", errors.New("boom"), OutcomeError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, code := classify(tt.err)
+			assert.Equal(t, tt.outcome, outcome)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
+
+func TestObserveZotaCall(t *testing.T) {
+	m := New()
+	rejected := apperror.GatewayRejected("payment gateway rejected the request", nil)
+	rejected.Gateway = &apperror.GatewayFailure{Code: "400"}
+
+	m.ObserveZotaCall(OperationDeposit, time.Now(), nil)
+	m.ObserveZotaCall(OperationDeposit, time.Now(), rejected)
+	m.ObserveZotaCall(OperationStatus, time.Now(), nil)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.zotaCalls.WithLabelValues(OperationDeposit, OutcomeSuccess, "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.zotaCalls.WithLabelValues(OperationDeposit, OutcomeRejected, "400")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.zotaCalls.WithLabelValues(OperationStatus, OutcomeSuccess, "")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.zotaDuration))
+}
+
+func TestDepositMetrics(t *testing.T) {
+	m := New()
+	m.ObserveDepositRequest("EUR", nil)
+	m.ObserveFinalDeposit("EUR", "DECLINED")
+	m.SetPendingOrders(7)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.depositRequests.WithLabelValues("EUR", OutcomeSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.deposits.WithLabelValues("EUR", "DECLINED")))
+	assert.Equal(t, 7.0, testutil.ToFloat64(m.pendingOrders))
+}
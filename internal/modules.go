@@ -1,29 +1,76 @@
 package internal
 
 import (
-	"github.com/go-playground/validator/v10"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	callback "zota-dev-challenge/internal/callback/common"
+	zotaCallback "zota-dev-challenge/internal/callback/common/zota"
+	callbackShared "zota-dev-challenge/internal/callback/shared"
 	"zota-dev-challenge/internal/config"
 	deposit "zota-dev-challenge/internal/deposit/common"
 	zotaDeposit "zota-dev-challenge/internal/deposit/common/zota"
 	depositShared "zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/health"
+	"zota-dev-challenge/internal/idempotency"
+	ledger "zota-dev-challenge/internal/ledger/common"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	payout "zota-dev-challenge/internal/payout/common"
+	zotaPayout "zota-dev-challenge/internal/payout/common/zota"
+	payoutShared "zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/reconciliation"
+	report "zota-dev-challenge/internal/report/common"
+	zotaReport "zota-dev-challenge/internal/report/common/zota"
+	reportShared "zota-dev-challenge/internal/report/shared"
 	status "zota-dev-challenge/internal/status/common"
 	zotaStatus "zota-dev-challenge/internal/status/common/zota"
 	statusShared "zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/webhook"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 var AppModules = fx.Options(
-	fx.Provide(func(logger *zap.Logger, config *config.Config) statusShared.StatusPaymentGateway {
-		return zotaStatus.NewStatusGateway(logger, config)
+	fx.Invoke(tracing.Setup),
+	fx.Provide(metrics.New),
+	fx.Provide(zotaapi.NewClient),
+	fx.Provide(func(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) statusShared.StatusPaymentGateway {
+		return zotaStatus.NewStatusGateway(logger, config, client, metrics)
 	}),
-	fx.Provide(func(logger *zap.Logger, config *config.Config) depositShared.DepositPaymentGateway {
-		return zotaDeposit.NewDepositGateway(logger, config)
+	fx.Provide(func(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) depositShared.DepositPaymentGateway {
+		return zotaDeposit.NewDepositGateway(logger, config, client, metrics)
 	}),
+	fx.Provide(func(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) payoutShared.PayoutPaymentGateway {
+		return zotaPayout.NewPayoutGateway(logger, config, client, metrics)
+	}),
+	fx.Provide(func(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) reportShared.OrdersReportPaymentGateway {
+		return zotaReport.NewReportGateway(logger, config, client, metrics)
+	}),
+	fx.Provide(func(logger *zap.Logger, config *config.Config) callbackShared.CallbackPaymentGateway {
+		return zotaCallback.NewCallbackGateway(logger, config)
+	}),
+	fx.Provide(func() idempotency.Store {
+		return idempotency.NewMemoryStore()
+	}),
+	fx.Provide(order.NewRepository),
+	fx.Provide(order.NewStateMachine),
+	fx.Provide(ledger.NewRepository),
+	fx.Provide(ledger.NewService),
+	fx.Provide(ledger.NewSweeper),
+	fx.Provide(webhook.NewStore),
+	fx.Provide(webhook.NewDispatcher),
 	fx.Provide(status.NewService),
+	fx.Provide(status.NewPoller),
 	fx.Provide(deposit.NewService),
+	fx.Provide(payout.NewService),
+	fx.Provide(callback.NewService),
+	fx.Provide(report.NewService),
+	fx.Provide(reconciliation.NewReconciler),
+	fx.Provide(reconciliation.NewJob),
+	fx.Provide(health.NewChecker),
 	fx.Provide(config.New),
-	fx.Provide(validator.New),
+	fx.Provide(InitValidator),
 	fx.Provide(InitRouterV1),
+	fx.Provide(InitServer),
 	fx.Provide(InitLogger),
 )
@@ -0,0 +1,243 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrNegativeAmount    = errors.New("amount must not be negative")
+	ErrNonPositiveAmount = errors.New("amount must be positive")
+	ErrTooPrecise        = errors.New("amount has more decimal places than the currency allows")
+	ErrCurrencyMismatch  = errors.New("amounts are in different currencies")
+)
+
+// defaultMinorUnits - most ISO 4217 currencies have cents
+const defaultMinorUnits = 2
+
+// minorUnits - ISO 4217 currencies whose minor unit is not 1/100
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimal places of the currency
+func MinorUnits(currency string) int {
+	if units, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return units
+	}
+	return defaultMinorUnits
+}
+
+// Amount - a monetary amount kept as an integer number of minor units, e.g. cents
+type Amount struct {
+	minor    int64
+	exponent int
+	currency string
+	// set - false for the zero Amount and an amount decoded from an empty string, which have no value to format
+	set bool
+}
+
+// Parse reads a decimal amount like "10.5" in the currency, rejecting negative and over-precise values
+func Parse(value, currency string) (Amount, error) {
+	exponent := MinorUnits(currency)
+	minor, err := parseMinor(strings.TrimSpace(value), exponent)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{minor: minor, exponent: exponent, currency: strings.ToUpper(currency), set: true}, nil
+}
+
+// ParsePositive is Parse that also rejects zero, as required for order amounts
+func ParsePositive(value, currency string) (Amount, error) {
+	amount, err := Parse(value, currency)
+	if err != nil {
+		return Amount{}, err
+	}
+	if amount.minor == 0 {
+		return Amount{}, ErrNonPositiveAmount
+	}
+	return amount, nil
+}
+
+// FromMinor builds an amount from minor units, e.g. FromMinor(1050, "USD") is 10.50 USD
+func FromMinor(minor int64, currency string) Amount {
+	return Amount{minor: minor, exponent: MinorUnits(currency), currency: strings.ToUpper(currency), set: true}
+}
+
+func parseMinor(value string, exponent int) (int64, error) {
+	if value == "" {
+		return 0, ErrInvalidAmount
+	}
+	if strings.HasPrefix(value, "-") {
+		return 0, ErrNegativeAmount
+	}
+
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+
+	// trailing zeros do not add precision, "10.500" is a valid USD amount
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return 0, fmt.Errorf("%w: %q", ErrTooPrecise, value)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	return minor, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units
+func (a Amount) Minor() int64 {
+	return a.minor
+}
+
+// Currency returns the ISO 4217 currency code, empty when the amount was decoded without one
+func (a Amount) Currency() string {
+	return a.currency
+}
+
+func (a Amount) IsZero() bool {
+	return a.minor == 0
+}
+
+// String formats the amount with the currency's decimal places, e.g. "10.50", an unset amount is empty
+func (a Amount) String() string {
+	if !a.set {
+		return ""
+	}
+
+	sign := ""
+	minor := a.minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if a.exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= a.exponent {
+		digits = strings.Repeat("0", a.exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-a.exponent] + "." + digits[len(digits)-a.exponent:]
+}
+
+// Equal reports whether both amounts have the same value in the same currency, regardless of formatting
+func (a Amount) Equal(b Amount) bool {
+	return a.currency == b.currency && a.Cmp(b) == 0
+}
+
+// Cmp compares the values of the amounts: -1 when a < b, 0 when equal and 1 when a > b
+func (a Amount) Cmp(b Amount) int {
+	left, right := a.minor, b.minor
+	if a.exponent < b.exponent {
+		scaled, ok := rescale(left, b.exponent-a.exponent)
+		if !ok {
+			// beyond the int64 range, so past any amount of the other side
+			return sign(left)
+		}
+		left = scaled
+	}
+	if b.exponent < a.exponent {
+		scaled, ok := rescale(right, a.exponent-b.exponent)
+		if !ok {
+			return -sign(right)
+		}
+		right = scaled
+	}
+
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rescale multiplies minor by 10 for each of the digits, it reports false when the result does not fit an int64
+func rescale(minor int64, digits int) (int64, bool) {
+	for ; digits > 0; digits-- {
+		if minor > math.MaxInt64/10 || minor < math.MinInt64/10 {
+			return 0, false
+		}
+		minor *= 10
+	}
+	return minor, true
+}
+
+func sign(minor int64) int {
+	switch {
+	case minor < 0:
+		return -1
+	case minor > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Add sums two amounts of the same currency
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a.currency != b.currency {
+		return Amount{}, ErrCurrencyMismatch
+	}
+	if (b.minor > 0 && a.minor > math.MaxInt64-b.minor) || (b.minor < 0 && a.minor < math.MinInt64-b.minor) {
+		return Amount{}, fmt.Errorf("%w: overflow", ErrInvalidAmount)
+	}
+	return Amount{minor: a.minor + b.minor, exponent: a.exponent, currency: a.currency, set: true}, nil
+}
+
+// Neg returns the amount with the opposite sign
+func (a Amount) Neg() Amount {
+	return Amount{minor: -a.minor, exponent: a.exponent, currency: a.currency, set: a.set}
+}
+
+// MarshalJSON writes the amount as a decimal string, the way Zota expects amounts
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON reads a decimal string, the currency is not known here so the decimal places are kept as sent
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == "" {
+		*a = Amount{}
+		return nil
+	}
+
+	_, fraction, _ := strings.Cut(value, ".")
+	minor, err := parseMinor(value, len(fraction))
+	if err != nil {
+		return err
+	}
+	*a = Amount{minor: minor, exponent: len(fraction), set: true}
+	return nil
+}
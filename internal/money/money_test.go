@@ -0,0 +1,109 @@
+package money
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		currency string
+		minor    int64
+		str      string
+	}{
+		{"10", "USD", 1000, "10.00"},
+		{"10.5", "USD", 1050, "10.50"},
+		{"10.500", "USD", 1050, "10.50"},
+		{"0.01", "usd", 1, "0.01"},
+		{"0", "USD", 0, "0.00"},
+		{"1500", "JPY", 1500, "1500"},
+		{"1.234", "KWD", 1234, "1.234"},
+	} {
+		amount, err := Parse(tc.value, tc.currency)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.minor, amount.Minor(), tc.value)
+		assert.Equal(t, tc.str, amount.String(), tc.value)
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for value, expectedErr := range map[string]error{
+		"":                     ErrInvalidAmount,
+		"abc":                  ErrInvalidAmount,
+		"1,5":                  ErrInvalidAmount,
+		"1.":                   ErrInvalidAmount,
+		".5":                   ErrInvalidAmount,
+		"+5":                   ErrInvalidAmount,
+		"1e3":                  ErrInvalidAmount,
+		"-5":                   ErrNegativeAmount,
+		"10.123":               ErrTooPrecise,
+		"99999999999999999999": ErrInvalidAmount,
+	} {
+		_, err := Parse(value, "USD")
+		assert.ErrorIs(t, err, expectedErr, value)
+	}
+
+	_, err := Parse("1.5", "JPY")
+	assert.ErrorIs(t, err, ErrTooPrecise)
+}
+
+func TestParsePositive(t *testing.T) {
+	_, err := ParsePositive("0.00", "USD")
+	assert.ErrorIs(t, err, ErrNonPositiveAmount)
+
+	amount, err := ParsePositive("0.01", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), amount.Minor())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := FromMinor(1050, "USD")
+	b, _ := Parse("10.5", "USD")
+	assert.True(t, a.Equal(b))
+	assert.Equal(t, 1, FromMinor(1051, "USD").Cmp(a))
+
+	sum, err := a.Add(FromMinor(50, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, "11.00", sum.String())
+	assert.Equal(t, "-10.50", a.Neg().String())
+
+	_, err = a.Add(FromMinor(50, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestCmp_BeyondTheRescaleRange(t *testing.T) {
+	// a whole amount this large overflows an int64 once written in cents
+	var large Amount
+	require.NoError(t, json.Unmarshal([]byte(`"92233720368547759"`), &large))
+	cents := FromMinor(math.MaxInt64, "USD")
+
+	assert.Equal(t, 1, large.Cmp(cents))
+	assert.Equal(t, -1, cents.Cmp(large))
+	assert.Equal(t, -1, large.Neg().Cmp(cents.Neg()))
+	assert.Equal(t, 1, cents.Neg().Cmp(large.Neg()))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(FromMinor(5, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, `"0.05"`, string(data))
+
+	var amount Amount
+	require.NoError(t, json.Unmarshal([]byte(`"10.50"`), &amount))
+	assert.Equal(t, "10.50", amount.String())
+	assert.Equal(t, 0, amount.Cmp(FromMinor(1050, "USD")))
+
+	require.NoError(t, json.Unmarshal([]byte(`""`), &amount))
+	assert.True(t, amount.IsZero())
+	assert.Empty(t, amount.String(), "an empty amount stays unset")
+
+	require.NoError(t, json.Unmarshal([]byte(`"0"`), &amount))
+	assert.Equal(t, "0", amount.String())
+	data, err = json.Marshal(amount)
+	require.NoError(t, err)
+	assert.Equal(t, `"0"`, string(data))
+}
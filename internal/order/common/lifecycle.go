@@ -0,0 +1,109 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/order/shared"
+)
+
+// maxTransitionAttempts - how many times a transition is re-evaluated when the order changes concurrently
+const maxTransitionAttempts = 3
+
+// Listener - told about every status change once it is stored, with the order as it is after the change
+type Listener func(order shared.Order, transition shared.Transition)
+
+// StateMachine - the only way to change an order status, it enforces the order lifecycle
+type StateMachine struct {
+	logger  *zap.Logger
+	orders  shared.OrderRepository
+	metrics *metrics.Metrics
+
+	mu        sync.RWMutex
+	listeners []Listener
+}
+
+func NewStateMachine(logger *zap.Logger, orders shared.OrderRepository, metrics *metrics.Metrics) *StateMachine {
+	return &StateMachine{logger: logger, orders: orders, metrics: metrics}
+}
+
+// OnTransition registers a listener, it runs on the caller's goroutine so it must not block
+func (m *StateMachine) OnTransition(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// Transition moves the order to the given status, moving to the current status is a no-op
+func (m *StateMachine) Transition(merchantOrderID, to, source, reason string) (*shared.Order, error) {
+	return m.Settle(merchantOrderID, to, source, reason, "")
+}
+
+// Settle is Transition for answers of the payment gateway that report the amount it settled,
+// which may differ from the requested amount. The amount is recorded with the transition.
+func (m *StateMachine) Settle(merchantOrderID, to, source, reason, amount string) (*shared.Order, error) {
+	for attempt := 1; ; attempt++ {
+		order, err := m.orders.FindByMerchantOrderID(merchantOrderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if order.Status == to {
+			return order, nil
+		}
+
+		if !shared.CanTransition(order.Status, to) {
+			m.logger.Warn("Rejected order status transition",
+				zap.String("merchantOrderID", merchantOrderID),
+				zap.String("from", order.Status),
+				zap.String("to", to),
+				zap.String("source", source))
+			return order, fmt.Errorf("%w: %s -> %s", shared.ErrIllegalTransition, order.Status, to)
+		}
+
+		transition := &shared.Transition{
+			MerchantOrderID: merchantOrderID,
+			From:            order.Status,
+			To:              to,
+			Source:          source,
+			Reason:          reason,
+			Amount:          amount,
+		}
+		err = m.orders.UpdateStatus(transition)
+		if errors.Is(err, shared.ErrStaleOrder) && attempt < maxTransitionAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m.logger.Info("Order status changed",
+			zap.String("merchantOrderID", merchantOrderID),
+			zap.String("from", transition.From),
+			zap.String("to", transition.To),
+			zap.String("source", source))
+
+		// every way an order settles goes through here, so the deposit outcomes are counted once
+		if order.Type == shared.TypeSale && shared.IsFinal(to) {
+			m.metrics.ObserveFinalDeposit(order.Currency, to)
+		}
+
+		order.Status = to
+		if reason != "" {
+			order.ErrorMessage = reason
+		}
+		order.UpdatedAt = transition.CreatedAt
+		m.notify(*order, *transition)
+		return order, nil
+	}
+}
+
+func (m *StateMachine) notify(order shared.Order, transition shared.Transition) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, listener := range m.listeners {
+		listener(order, transition)
+	}
+}
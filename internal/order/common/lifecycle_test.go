@@ -0,0 +1,136 @@
+package common
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/order/shared"
+)
+
+type stateMachineTestSuite struct {
+	orders       *MemoryRepository
+	metrics      *metrics.Metrics
+	stateMachine *StateMachine
+}
+
+func (s *stateMachineTestSuite) setup(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s.orders = NewMemoryRepository()
+	s.metrics = metrics.New()
+	s.stateMachine = NewStateMachine(logger, s.orders, s.metrics)
+
+	require.NoError(t, s.orders.Create(newTestOrder()))
+}
+
+func TestTransition_FollowsLifecycle(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	order, err := s.stateMachine.Transition("merchantOrder123", shared.StatusPending, shared.SourceStatusPoll, "")
+	require.NoError(t, err)
+	assert.Equal(t, shared.StatusPending, order.Status)
+
+	order, err = s.stateMachine.Transition("merchantOrder123", shared.StatusApproved, shared.SourceCallback, "")
+	require.NoError(t, err)
+	assert.Equal(t, shared.StatusApproved, order.Status)
+
+	transitions, err := s.orders.ListTransitions("merchantOrder123")
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, shared.SourceStatusPoll, transitions[0].Source)
+	assert.Equal(t, shared.SourceCallback, transitions[1].Source)
+}
+
+func TestTransition_CountsFinalDeposits(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", shared.StatusPending, shared.SourceStatusPoll, "")
+	require.NoError(t, err)
+	count, err := testutil.GatherAndCount(s.metrics.Registry, "merchant_deposits_total")
+	require.NoError(t, err)
+	assert.Zero(t, count, "a pending deposit is not settled yet")
+
+	_, err = s.stateMachine.Transition("merchantOrder123", shared.StatusApproved, shared.SourceCallback, "")
+	require.NoError(t, err)
+	assert.NoError(t, testutil.GatherAndCompare(s.metrics.Registry, strings.NewReader(`
+# HELP merchant_deposits_total Deposits that reached a final status, by currency and status.
+# TYPE merchant_deposits_total counter
+merchant_deposits_total{currency="USD",status="APPROVED"} 1
+`), "merchant_deposits_total"))
+}
+
+func TestTransition_NotifiesListeners(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	var notified []shared.Transition
+	s.stateMachine.OnTransition(func(order shared.Order, transition shared.Transition) {
+		assert.Equal(t, transition.To, order.Status, "listeners see the order after the change")
+		notified = append(notified, transition)
+	})
+
+	_, err := s.stateMachine.Transition("merchantOrder123", shared.StatusApproved, shared.SourceCallback, "")
+	require.NoError(t, err)
+	_, err = s.stateMachine.Transition("merchantOrder123", shared.StatusApproved, shared.SourceStatusPoll, "")
+	require.NoError(t, err)
+	_, err = s.stateMachine.Transition("merchantOrder123", shared.StatusPending, shared.SourceStatusPoll, "")
+	require.ErrorIs(t, err, shared.ErrIllegalTransition)
+
+	require.Len(t, notified, 1, "no-ops and rejected transitions are not changes")
+	assert.Equal(t, shared.StatusCreated, notified[0].From)
+	assert.Equal(t, shared.SourceCallback, notified[0].Source)
+	assert.False(t, notified[0].CreatedAt.IsZero())
+}
+
+func TestTransition_SameStatusIsNoop(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	order, err := s.stateMachine.Transition("merchantOrder123", shared.StatusCreated, shared.SourceStatusPoll, "")
+	require.NoError(t, err)
+	assert.Equal(t, shared.StatusCreated, order.Status)
+
+	transitions, err := s.orders.ListTransitions("merchantOrder123")
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+}
+
+func TestTransition_RejectsIllegalTransition(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", shared.StatusApproved, shared.SourceCallback, "")
+	require.NoError(t, err)
+
+	_, err = s.stateMachine.Transition("merchantOrder123", shared.StatusPending, shared.SourceStatusPoll, "")
+	assert.ErrorIs(t, err, shared.ErrIllegalTransition)
+
+	_, err = s.stateMachine.Transition("merchantOrder123", "SOMETHING", shared.SourceAdmin, "")
+	assert.ErrorIs(t, err, shared.ErrIllegalTransition)
+
+	stored, err := s.orders.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	assert.Equal(t, shared.StatusApproved, stored.Status)
+}
+
+func TestTransition_OrderNotFound(t *testing.T) {
+	s := &stateMachineTestSuite{}
+	s.setup(t)
+
+	_, err := s.stateMachine.Transition("missing", shared.StatusApproved, shared.SourceCallback, "")
+	assert.ErrorIs(t, err, shared.ErrOrderNotFound)
+}
+
+func TestIsFinal(t *testing.T) {
+	for _, status := range []string{shared.StatusApproved, shared.StatusDeclined, shared.StatusFiltered, shared.StatusError} {
+		assert.True(t, shared.IsFinal(status), status)
+	}
+	for _, status := range []string{shared.StatusCreated, shared.StatusPending, shared.StatusProcessing, shared.StatusUnknown, "SOMETHING"} {
+		assert.False(t, shared.IsFinal(status), status)
+	}
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"zota-dev-challenge/internal/order/shared"
+)
+
+// MemoryRepository - keeps the orders in memory, everything is lost on restart
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	orders      map[string]shared.Order
+	transitions map[string][]shared.Transition
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		orders:      make(map[string]shared.Order),
+		transitions: make(map[string][]shared.Transition),
+	}
+}
+
+func (m *MemoryRepository) Create(order *shared.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.orders[order.MerchantOrderID]; ok {
+		return fmt.Errorf("order %s already exists", order.MerchantOrderID)
+	}
+
+	now := time.Now().UTC()
+	// an imported order keeps the time it was created at the payment gateway
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+	order.UpdatedAt = now
+	m.orders[order.MerchantOrderID] = *order
+	return nil
+}
+
+func (m *MemoryRepository) Update(order *shared.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.orders[order.MerchantOrderID]
+	if !ok {
+		return shared.ErrOrderNotFound
+	}
+
+	// like the SQLite store, the stored status, environment and creation time are kept and the caller's order is left as is
+	order.UpdatedAt = time.Now().UTC()
+	updated := *order
+	updated.Status = existing.Status
+	updated.Environment = existing.Environment
+	updated.CreatedAt = existing.CreatedAt
+	m.orders[order.MerchantOrderID] = updated
+	return nil
+}
+
+func (m *MemoryRepository) UpdateStatus(transition *shared.Transition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.orders[transition.MerchantOrderID]
+	if !ok {
+		return shared.ErrOrderNotFound
+	}
+	if existing.Status != transition.From {
+		return shared.ErrStaleOrder
+	}
+
+	transition.CreatedAt = time.Now().UTC()
+	existing.Status = transition.To
+	if transition.Reason != "" {
+		existing.ErrorMessage = transition.Reason
+	}
+	existing.UpdatedAt = transition.CreatedAt
+	m.orders[transition.MerchantOrderID] = existing
+	m.transitions[transition.MerchantOrderID] = append(m.transitions[transition.MerchantOrderID], *transition)
+	return nil
+}
+
+func (m *MemoryRepository) FindByMerchantOrderID(merchantOrderID string) (*shared.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, ok := m.orders[merchantOrderID]
+	if !ok {
+		return nil, shared.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (m *MemoryRepository) ListTransitions(merchantOrderID string) ([]shared.Transition, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]shared.Transition(nil), m.transitions[merchantOrderID]...), nil
+}
+
+func (m *MemoryRepository) ListNonFinal(after shared.Cursor, limit int) ([]shared.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []shared.Order
+	for _, order := range m.orders {
+		if !shared.IsFinal(order.Status) && (order.PaymentGatewayOrderID != "" || order.Status == shared.StatusUnknown) &&
+			after.Before(&order) {
+			orders = append(orders, order)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Cursor().Before(&orders[j])
+	})
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (m *MemoryRepository) ListCreatedBetween(from, to time.Time) ([]shared.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []shared.Order
+	for _, order := range m.orders {
+		if !order.CreatedAt.Before(from) && order.CreatedAt.Before(to) {
+			orders = append(orders, order)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+func (m *MemoryRepository) ListByStatus(status string, since time.Time) ([]shared.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var orders []shared.Order
+	for _, order := range m.orders {
+		if order.Status == status && !order.UpdatedAt.Before(since) {
+			orders = append(orders, order)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].UpdatedAt.Before(orders[j].UpdatedAt)
+	})
+	return orders, nil
+}
+
+// Ping - the memory is always there
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
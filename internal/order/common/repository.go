@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/order/shared"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreSQLite = "sqlite"
+
+	defaultSQLiteDSN = "orders.db"
+)
+
+// NewRepository picks the order store implementation configured with ORDER_STORE, in-memory by default
+func NewRepository(lc fx.Lifecycle, logger *zap.Logger, config *config.Config) (shared.OrderRepository, error) {
+	switch config.OrderStore {
+	case "", StoreMemory:
+		logger.Info("Using in-memory order store")
+		return NewMemoryRepository(), nil
+	case StoreSQLite:
+		dsn := config.OrderStoreDSN
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+
+		repository, err := NewSQLiteRepository(dsn)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return repository.Close()
+			},
+		})
+
+		logger.Info("Using SQLite order store", zap.String("dsn", dsn))
+		return repository, nil
+	default:
+		return nil, fmt.Errorf("unknown order store %q", config.OrderStore)
+	}
+}
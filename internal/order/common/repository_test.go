@@ -0,0 +1,157 @@
+package common
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/order/shared"
+)
+
+func newTestOrder() *shared.Order {
+	return &shared.Order{
+		MerchantOrderID: "merchantOrder123",
+		Type:            shared.TypeSale,
+		UserID:          "user123",
+		Amount:          "100.00",
+		Currency:        "USD",
+		Status:          shared.StatusCreated,
+		Environment:     "sandbox",
+	}
+}
+
+// testRepository - the behaviour every OrderRepository implementation must share
+func testRepository(t *testing.T, repository shared.OrderRepository) {
+	require.NoError(t, repository.Ping(context.Background()))
+
+	order := newTestOrder()
+	require.NoError(t, repository.Create(order))
+	assert.False(t, order.CreatedAt.IsZero())
+	assert.Error(t, repository.Create(newTestOrder()), "duplicate merchant order ID must be rejected")
+
+	stored, err := repository.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	assert.Equal(t, "user123", stored.UserID)
+	assert.Equal(t, "100.00", stored.Amount)
+	assert.Equal(t, "USD", stored.Currency)
+	assert.Equal(t, shared.StatusCreated, stored.Status)
+	assert.Equal(t, "sandbox", stored.Environment)
+	assert.True(t, order.CreatedAt.Equal(stored.CreatedAt))
+
+	stored.PaymentGatewayOrderID = "order123"
+	stored.Status = shared.StatusApproved
+	stored.Environment = "live"
+	stored.CreatedAt = stored.CreatedAt.Add(-time.Hour)
+	require.NoError(t, repository.Update(stored))
+	assert.Equal(t, shared.StatusApproved, stored.Status, "Update must leave the caller's order as is")
+
+	updated, err := repository.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	assert.Equal(t, "order123", updated.PaymentGatewayOrderID)
+	assert.Equal(t, shared.StatusCreated, updated.Status, "Update must not change the status")
+	assert.Equal(t, "sandbox", updated.Environment, "Update must not change the environment")
+	assert.True(t, order.CreatedAt.Equal(updated.CreatedAt), "Update must not change the creation time")
+	assert.False(t, updated.UpdatedAt.Before(updated.CreatedAt))
+
+	require.NoError(t, repository.UpdateStatus(&shared.Transition{
+		MerchantOrderID: "merchantOrder123",
+		From:            shared.StatusCreated,
+		To:              shared.StatusDeclined,
+		Source:          shared.SourceCallback,
+		Reason:          "insufficient funds",
+		Amount:          "90.00",
+	}))
+	assert.ErrorIs(t, repository.UpdateStatus(&shared.Transition{
+		MerchantOrderID: "merchantOrder123",
+		From:            shared.StatusCreated,
+		To:              shared.StatusApproved,
+	}), shared.ErrStaleOrder)
+	assert.ErrorIs(t, repository.UpdateStatus(&shared.Transition{MerchantOrderID: "missing"}), shared.ErrOrderNotFound)
+
+	declined, err := repository.FindByMerchantOrderID("merchantOrder123")
+	require.NoError(t, err)
+	assert.Equal(t, shared.StatusDeclined, declined.Status)
+	assert.Equal(t, "insufficient funds", declined.ErrorMessage)
+
+	transitions, err := repository.ListTransitions("merchantOrder123")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, shared.StatusCreated, transitions[0].From)
+	assert.Equal(t, shared.StatusDeclined, transitions[0].To)
+	assert.Equal(t, shared.SourceCallback, transitions[0].Source)
+	assert.Equal(t, "insufficient funds", transitions[0].Reason)
+	assert.Equal(t, "90.00", transitions[0].Amount)
+
+	_, err = repository.FindByMerchantOrderID("missing")
+	assert.ErrorIs(t, err, shared.ErrOrderNotFound)
+
+	missing := newTestOrder()
+	missing.MerchantOrderID = "missing"
+	assert.ErrorIs(t, repository.Update(missing), shared.ErrOrderNotFound)
+
+	for _, id := range []string{"pending1", "unsent", "pending2", "pending3", "lost"} {
+		pending := newTestOrder()
+		pending.MerchantOrderID = id
+		switch id {
+		case "lost":
+			pending.Status = shared.StatusUnknown
+		case "unsent":
+		default:
+			pending.PaymentGatewayOrderID = "order-" + id
+		}
+		require.NoError(t, repository.Create(pending))
+	}
+	nonFinal, err := repository.ListNonFinal(shared.Cursor{}, 2)
+	require.NoError(t, err)
+	require.Len(t, nonFinal, 2)
+	assert.Equal(t, "pending1", nonFinal[0].MerchantOrderID, "oldest orders come first")
+	assert.Equal(t, "pending2", nonFinal[1].MerchantOrderID, "created orders without a payment gateway order ID are left out")
+	nonFinal, err = repository.ListNonFinal(nonFinal[1].Cursor(), 3)
+	require.NoError(t, err)
+	require.Len(t, nonFinal, 2, "declined orders are final")
+	assert.Equal(t, "pending3", nonFinal[0].MerchantOrderID, "the listing continues after the cursor")
+	assert.Equal(t, "lost", nonFinal[1].MerchantOrderID, "unknown orders are listed without a payment gateway order ID")
+
+	pending3, err := repository.FindByMerchantOrderID("pending3")
+	require.NoError(t, err)
+	created, err := repository.ListCreatedBetween(order.CreatedAt, pending3.CreatedAt)
+	require.NoError(t, err)
+	require.Len(t, created, 4, "the end of the range is excluded")
+	assert.Equal(t, "merchantOrder123", created[0].MerchantOrderID, "oldest orders come first")
+
+	created, err = repository.ListCreatedBetween(pending3.CreatedAt.Add(time.Second), pending3.CreatedAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	declinedOrders, err := repository.ListByStatus(shared.StatusDeclined, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, declinedOrders, 1)
+	assert.Equal(t, "merchantOrder123", declinedOrders[0].MerchantOrderID)
+	declinedOrders, err = repository.ListByStatus(shared.StatusDeclined, declinedOrders[0].UpdatedAt.Add(time.Nanosecond))
+	require.NoError(t, err)
+	assert.Empty(t, declinedOrders, "orders that changed before since are left out")
+
+	importedAt := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	imported := newTestOrder()
+	imported.MerchantOrderID = "imported"
+	imported.CreatedAt = importedAt
+	require.NoError(t, repository.Create(imported))
+	created, err = repository.ListCreatedBetween(importedAt, importedAt.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, created, 1, "an order created with a time keeps it")
+	assert.Equal(t, "imported", created[0].MerchantOrderID)
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, NewMemoryRepository())
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repository, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"))
+	require.NoError(t, err)
+	defer repository.Close()
+
+	testRepository(t, repository)
+}
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS orders (
+    merchant_order_id        TEXT PRIMARY KEY,
+    payment_gateway_order_id TEXT    NOT NULL DEFAULT '',
+    type                     TEXT    NOT NULL,
+    user_id                  TEXT    NOT NULL,
+    amount                   TEXT    NOT NULL,
+    currency                 TEXT    NOT NULL,
+    status                   TEXT    NOT NULL,
+    error_message            TEXT    NOT NULL DEFAULT '',
+    environment              TEXT    NOT NULL DEFAULT '',
+    created_at               INTEGER NOT NULL,
+    updated_at               INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+
+CREATE TABLE IF NOT EXISTS order_transitions (
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    merchant_order_id TEXT    NOT NULL REFERENCES orders (merchant_order_id),
+    from_status       TEXT    NOT NULL,
+    to_status         TEXT    NOT NULL,
+    source            TEXT    NOT NULL,
+    reason            TEXT    NOT NULL DEFAULT '',
+    created_at        INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_transitions_order ON order_transitions (merchant_order_id);
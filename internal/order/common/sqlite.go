@@ -0,0 +1,290 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/order/shared"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+const orderColumns = `merchant_order_id, payment_gateway_order_id, type, user_id, amount, currency, status, error_message, environment, created_at, updated_at`
+
+// addedColumns - columns introduced after the first schema, added to existing databases on start
+var addedColumns = []struct{ table, column, definition string }{
+	{"orders", "environment", `TEXT NOT NULL DEFAULT ''`},
+	{"order_transitions", "amount", `TEXT NOT NULL DEFAULT ''`},
+}
+
+// SQLiteRepository - keeps the orders in an embedded SQLite database
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRepository(dsn string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open order store: %w", err)
+	}
+	// SQLite allows a single writer, serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate order store: %w", err)
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	for _, added := range addedColumns {
+		exists, err := hasColumn(db, added.table, added.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, added.table, added.column, added.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	return count > 0, err
+}
+
+func (s *SQLiteRepository) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteRepository) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteRepository) Create(order *shared.Order) error {
+	now := time.Now().UTC()
+	// an imported order keeps the time it was created at the payment gateway
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now
+	}
+	order.UpdatedAt = now
+
+	_, err := s.db.Exec(`INSERT INTO orders (`+orderColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.MerchantOrderID, order.PaymentGatewayOrderID, order.Type, order.UserID, order.Amount, order.Currency,
+		order.Status, order.ErrorMessage, order.Environment, order.CreatedAt.UnixNano(), order.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to insert order %s: %w", order.MerchantOrderID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteRepository) Update(order *shared.Order) error {
+	order.UpdatedAt = time.Now().UTC()
+
+	res, err := s.db.Exec(`UPDATE orders SET payment_gateway_order_id = ?, type = ?, user_id = ?, amount = ?, currency = ?,
+		error_message = ?, updated_at = ? WHERE merchant_order_id = ?`,
+		order.PaymentGatewayOrderID, order.Type, order.UserID, order.Amount, order.Currency,
+		order.ErrorMessage, order.UpdatedAt.UnixNano(), order.MerchantOrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order %s: %w", order.MerchantOrderID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return shared.ErrOrderNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteRepository) UpdateStatus(transition *shared.Transition) error {
+	transition.CreatedAt = time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE orders SET status = ?, error_message = CASE WHEN ? = '' THEN error_message ELSE ? END, updated_at = ?
+		WHERE merchant_order_id = ? AND status = ?`,
+		transition.To, transition.Reason, transition.Reason, transition.CreatedAt.UnixNano(), transition.MerchantOrderID, transition.From)
+	if err != nil {
+		return fmt.Errorf("failed to update order %s status: %w", transition.MerchantOrderID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// tell a missing order apart from one whose status moved on
+		if _, err := findByMerchantOrderID(tx, transition.MerchantOrderID); err != nil {
+			return err
+		}
+		return shared.ErrStaleOrder
+	}
+
+	_, err = tx.Exec(`INSERT INTO order_transitions (merchant_order_id, from_status, to_status, source, reason, amount, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		transition.MerchantOrderID, transition.From, transition.To, transition.Source, transition.Reason, transition.Amount,
+		transition.CreatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to record order %s transition: %w", transition.MerchantOrderID, err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteRepository) FindByMerchantOrderID(merchantOrderID string) (*shared.Order, error) {
+	return findByMerchantOrderID(s.db, merchantOrderID)
+}
+
+func (s *SQLiteRepository) ListNonFinal(after shared.Cursor, limit int) ([]shared.Order, error) {
+	final := shared.FinalStatuses()
+	args := make([]any, 0, len(final)+5)
+	for _, status := range final {
+		args = append(args, status)
+	}
+	// the zero time has no Unix time, the zero cursor starts before every order
+	afterCreatedAt := int64(math.MinInt64)
+	if !after.CreatedAt.IsZero() {
+		afterCreatedAt = after.CreatedAt.UnixNano()
+	}
+	args = append(args, shared.StatusUnknown, afterCreatedAt, afterCreatedAt, after.MerchantOrderID, limit)
+
+	// orders without a payment gateway order ID are only looked up when their outcome was lost
+	rows, err := s.db.Query(`SELECT `+orderColumns+` FROM orders
+		WHERE status NOT IN (?`+strings.Repeat(", ?", len(final)-1)+`) AND (payment_gateway_order_id <> '' OR status = ?)
+		AND (created_at > ? OR (created_at = ? AND merchant_order_id > ?))
+		ORDER BY created_at, merchant_order_id LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list non-final orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []shared.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func (s *SQLiteRepository) ListCreatedBetween(from, to time.Time) ([]shared.Order, error) {
+	rows, err := s.db.Query(`SELECT `+orderColumns+` FROM orders WHERE created_at >= ? AND created_at < ? ORDER BY created_at`,
+		from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders created between %s and %s: %w", from, to, err)
+	}
+	defer rows.Close()
+
+	var orders []shared.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func (s *SQLiteRepository) ListByStatus(status string, since time.Time) ([]shared.Order, error) {
+	rows, err := s.db.Query(`SELECT `+orderColumns+` FROM orders WHERE status = ? AND updated_at >= ? ORDER BY updated_at`,
+		status, since.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s orders: %w", status, err)
+	}
+	defer rows.Close()
+
+	var orders []shared.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func (s *SQLiteRepository) ListTransitions(merchantOrderID string) ([]shared.Transition, error) {
+	rows, err := s.db.Query(`SELECT merchant_order_id, from_status, to_status, source, reason, amount, created_at
+		FROM order_transitions WHERE merchant_order_id = ? ORDER BY id`, merchantOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order %s transitions: %w", merchantOrderID, err)
+	}
+	defer rows.Close()
+
+	var transitions []shared.Transition
+	for rows.Next() {
+		var transition shared.Transition
+		var createdAt int64
+		if err := rows.Scan(&transition.MerchantOrderID, &transition.From, &transition.To, &transition.Source,
+			&transition.Reason, &transition.Amount, &createdAt); err != nil {
+			return nil, err
+		}
+		transition.CreatedAt = time.Unix(0, createdAt).UTC()
+		transitions = append(transitions, transition)
+	}
+	return transitions, rows.Err()
+}
+
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func findByMerchantOrderID(q querier, merchantOrderID string) (*shared.Order, error) {
+	row := q.QueryRow(`SELECT `+orderColumns+` FROM orders WHERE merchant_order_id = ?`, merchantOrderID)
+
+	order, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, shared.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order %s: %w", merchantOrderID, err)
+	}
+	return order, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(row scanner) (*shared.Order, error) {
+	var order shared.Order
+	var createdAt, updatedAt int64
+	err := row.Scan(&order.MerchantOrderID, &order.PaymentGatewayOrderID, &order.Type, &order.UserID, &order.Amount,
+		&order.Currency, &order.Status, &order.ErrorMessage, &order.Environment, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	order.CreatedAt = time.Unix(0, createdAt).UTC()
+	order.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return &order, nil
+}
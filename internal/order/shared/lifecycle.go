@@ -0,0 +1,71 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+// transitions - the allowed status changes, final statuses have no outgoing transitions
+var transitions = map[string][]string{
+	StatusCreated:    {StatusPending, StatusProcessing, StatusApproved, StatusDeclined, StatusFiltered, StatusUnknown, StatusError},
+	StatusPending:    {StatusProcessing, StatusApproved, StatusDeclined, StatusFiltered, StatusUnknown, StatusError},
+	StatusProcessing: {StatusApproved, StatusDeclined, StatusFiltered, StatusUnknown, StatusError},
+	StatusUnknown:    {StatusPending, StatusProcessing, StatusApproved, StatusDeclined, StatusFiltered, StatusError},
+	StatusApproved:   {},
+	StatusDeclined:   {},
+	StatusFiltered:   {},
+	StatusError:      {},
+}
+
+// IsKnownStatus reports whether the status is part of the order lifecycle
+func IsKnownStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// IsFinal reports whether the order can no longer change its status
+func IsFinal(status string) bool {
+	next, ok := transitions[status]
+	return ok && len(next) == 0
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// FinalStatuses lists the statuses an order can no longer leave
+func FinalStatuses() []string {
+	var final []string
+	for status, next := range transitions {
+		if len(next) == 0 {
+			final = append(final, status)
+		}
+	}
+	sort.Strings(final)
+	return final
+}
+
+// FailureStatus - the status of an order whose payment gateway call failed.
+// Only a call the payment gateway certainly did not accept fails the order for good: it was rejected, never sent,
+// or the request could not even be built, which the gateways report with errors that are not *apperror.Error.
+// Any other call, e.g. answered with 503 or cut off after it was sent, may have created the order, so its outcome is unknown.
+func FailureStatus(err error) string {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return StatusUnknown
+	}
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code == apperror.CodeGatewayRejected || zotaapi.NotSent(err) {
+		return StatusError
+	}
+	return StatusUnknown
+}
@@ -0,0 +1,115 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrIllegalTransition - the requested status change is not allowed by the order lifecycle
+	ErrIllegalTransition = errors.New("illegal order status transition")
+	// ErrStaleOrder - the order status changed since it was read, the transition must be re-evaluated
+	ErrStaleOrder = errors.New("order status changed concurrently")
+)
+
+const (
+	TypeSale   = "SALE"
+	TypePayout = "PAYOUT"
+)
+
+// Order statuses as reported by Zota
+const (
+	StatusCreated    = "CREATED"
+	StatusPending    = "PENDING"
+	StatusProcessing = "PROCESSING"
+	StatusApproved   = "APPROVED"
+	StatusDeclined   = "DECLINED"
+	StatusFiltered   = "FILTERED"
+	StatusUnknown    = "UNKNOWN"
+	StatusError      = "ERROR"
+)
+
+// Sources of a status transition
+const (
+	SourceDeposit    = "deposit"
+	SourcePayout     = "payout"
+	SourceStatusPoll = "status_poll"
+	SourceCallback   = "callback"
+	SourceAdmin      = "admin"
+	// SourceReconciliation - corrected after comparing the order with Zota's orders report
+	SourceReconciliation = "reconciliation"
+)
+
+// Order - an order created by the merchant server at the payment gateway
+type Order struct {
+	MerchantOrderID       string    `json:"merchantOrderId"`
+	PaymentGatewayOrderID string    `json:"paymentGatewayOrderId"`
+	Type                  string    `json:"type"`
+	UserID                string    `json:"userId"`
+	Amount                string    `json:"amount"`
+	Currency              string    `json:"currency"`
+	Status                string    `json:"status"`
+	ErrorMessage          string    `json:"errorMessage,omitempty"`
+	Environment           string    `json:"environment"` // the Zota environment (sandbox or live) the order was created in
+	CreatedAt             time.Time `json:"createdAt"`
+	UpdatedAt             time.Time `json:"updatedAt"`
+}
+
+// InEnvironment reports whether the order belongs to the environment, orders stored before environments were recorded belong to all
+func (o *Order) InEnvironment(environment string) bool {
+	return o.Environment == "" || o.Environment == environment
+}
+
+// Cursor - where a listing of orders oldest first continues, the zero cursor starts with the oldest order
+type Cursor struct {
+	CreatedAt       time.Time
+	MerchantOrderID string
+}
+
+// Cursor returns the cursor that continues a listing after the order
+func (o *Order) Cursor() Cursor {
+	return Cursor{CreatedAt: o.CreatedAt, MerchantOrderID: o.MerchantOrderID}
+}
+
+// Before reports whether the order comes before the cursor position in a listing oldest first
+func (c Cursor) Before(order *Order) bool {
+	return c.CreatedAt.Before(order.CreatedAt) ||
+		c.CreatedAt.Equal(order.CreatedAt) && c.MerchantOrderID < order.MerchantOrderID
+}
+
+// Transition - a recorded change of an order status
+type Transition struct {
+	MerchantOrderID string    `json:"merchantOrderId"`
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	Source          string    `json:"source"`
+	Reason          string    `json:"reason,omitempty"`
+	Amount          string    `json:"amount,omitempty"` // what the payment gateway settled, when it reported it with the status
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type OrderRepository interface {
+	// Create stores a new order, it is created now unless order.CreatedAt is set
+	Create(order *Order) error
+	// Update stores everything but the status, which only changes through UpdateStatus
+	Update(order *Order) error
+	// UpdateStatus moves the order from transition.From to transition.To and records the transition,
+	// a non-empty reason becomes the order error message.
+	// It fails with ErrStaleOrder when the stored status is no longer transition.From
+	UpdateStatus(transition *Transition) error
+	FindByMerchantOrderID(merchantOrderID string) (*Order, error)
+	// ListNonFinal returns up to limit orders that can still change their status, oldest first, continuing after the cursor.
+	// Paging on from the last order returned visits every order in turn. Orders without a payment gateway order ID are
+	// only listed when UNKNOWN: Zota may have accepted them before the answer was lost, they are looked up by merchant
+	// order ID. A CREATED order without one is still being sent, or never was.
+	ListNonFinal(after Cursor, limit int) ([]Order, error)
+	// ListCreatedBetween returns the orders created from from until before to, oldest first
+	ListCreatedBetween(from, to time.Time) ([]Order, error)
+	// ListByStatus returns the orders in the status that last changed at or after since, oldest change first
+	ListByStatus(status string, since time.Time) ([]Order, error)
+	ListTransitions(merchantOrderID string) ([]Transition, error)
+	// Ping reports whether the store can be reached
+	Ping(ctx context.Context) error
+}
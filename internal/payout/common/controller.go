@@ -0,0 +1,63 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"net/http"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+// Handler
+// ClientRequest represents the request payload for a payout.
+// @Summary payout example
+// @Schemes
+// @Description handle payout (withdrawal) to the customer's bank account
+// @Tags payout
+// @Accept json
+// @Produce json
+// @Param payoutRequest body shared.ClientRequest true "Payout ClientRequest"
+// @Success 200 {object} shared.Response "Payout Successful"
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 422 {object} apperror.Response "Unsupported currency or rejected by the payment gateway"
+// @Failure 502 {object} apperror.Response "Unexpected payment gateway response"
+// @Failure 503 {object} apperror.Response "Payment gateway unavailable"
+// @Router /payout [post]
+func Handler(service ServiceInterface, logger *zap.Logger, validator *validator.Validate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "payout.Handler")
+		defer span.End()
+
+		var req shared.ClientRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Failed to decode request body", zap.Error(err))
+			apperror.Write(w, r, logger, apperror.InvalidRequest("invalid request body", err))
+			return
+		}
+
+		//validate request
+		if err := validator.Struct(req); err != nil {
+			logger.Error("Failed to validate request", zap.Error(err))
+			apperror.Write(w, r, logger, apperror.FromValidation(err))
+			return
+		}
+
+		res, err := service.ProcessPayout(ctx, &req)
+		tracing.Record(span, err)
+		if err != nil {
+			logger.Error("Failed to process payout", zap.Error(err))
+			apperror.Write(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			logger.Error("Failed to encode response", zap.Error(err))
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	}
+}
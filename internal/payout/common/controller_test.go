@@ -0,0 +1,103 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zota-dev-challenge/internal/payout/shared"
+)
+
+type controllerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockService *MockServiceInterface
+	logger      *zap.Logger
+	validate    *validator.Validate
+	request     shared.ClientRequest
+}
+
+func (s *controllerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockService = NewMockServiceInterface(s.mockCtrl)
+	s.logger, _ = zap.NewDevelopment()
+	s.validate = validator.New()
+
+	s.request = shared.ClientRequest{
+		UserId:                    "user123",
+		OrderAmount:               "100.00",
+		OrderCurrency:             "USD",
+		CustomerEmail:             "test@example.com",
+		CustomerFirstName:         "John",
+		CustomerLastName:          "Doe",
+		CustomerPhone:             "1234567890",
+		CustomerIp:                "127.0.0.1",
+		CustomerBankAccountNumber: "100200300",
+		CustomerBankAccountName:   "John Doe",
+	}
+}
+
+func (s *controllerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *controllerTestSuite) serve(body []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/v1/payout", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	Handler(s.mockService, s.logger, s.validate).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestHandler_Success(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessPayout(gomock.Any(), &s.request).
+		Return(&shared.Response{ClientRequest: s.request, OrderID: "1", PaymentGatewayOrderID: "11"}, nil)
+
+	payloadBytes, _ := json.Marshal(s.request)
+	rr := s.serve(payloadBytes)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response shared.Response
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "11", response.PaymentGatewayOrderID)
+}
+
+func TestHandler_InvalidJSON(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	assert.Equal(t, http.StatusBadRequest, s.serve([]byte("{invalid json")).Code)
+}
+
+func TestHandler_MissingBankAccount(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.CustomerBankAccountNumber = ""
+	payloadBytes, _ := json.Marshal(s.request)
+
+	assert.Equal(t, http.StatusBadRequest, s.serve(payloadBytes).Code)
+}
+
+func TestHandler_ServiceError(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().ProcessPayout(gomock.Any(), gomock.Any()).Return(nil, errors.New("service error"))
+
+	payloadBytes, _ := json.Marshal(s.request)
+	assert.Equal(t, http.StatusInternalServerError, s.serve(payloadBytes).Code)
+}
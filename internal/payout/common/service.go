@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+type ServiceInterface interface {
+	ProcessPayout(ctx context.Context, r *shared.ClientRequest) (*shared.Response, error)
+}
+
+type Service struct {
+	logger        *zap.Logger
+	config        *config.Config
+	payoutGateway shared.PayoutPaymentGateway
+	orders        orderShared.OrderRepository
+	stateMachine  *order.StateMachine
+}
+
+func NewService(logger *zap.Logger, config *config.Config, payoutGateway shared.PayoutPaymentGateway,
+	orders orderShared.OrderRepository, stateMachine *order.StateMachine) *Service {
+	return &Service{logger: logger, config: config, payoutGateway: payoutGateway, orders: orders, stateMachine: stateMachine}
+}
+
+func (s *Service) ProcessPayout(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "payout.Service.ProcessPayout", tracing.AttrCurrency.String(req.OrderCurrency))
+	res, err := s.processPayout(ctx, req)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s *Service) processPayout(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	amount, err := s.validate(req)
+	if err != nil {
+		s.logger.Error("Invalid payout request", zap.Error(err))
+		return nil, err
+	}
+
+	serviceModel := shared.Request{
+		ClientRequest:   *req,
+		MerchantOrderID: uuid.New().String(),
+		Amount:          amount,
+	}
+	tracing.Annotate(ctx, tracing.AttrMerchantOrderID.String(serviceModel.MerchantOrderID))
+
+	newOrder := &orderShared.Order{
+		MerchantOrderID: serviceModel.MerchantOrderID,
+		Type:            orderShared.TypePayout,
+		UserID:          req.UserId,
+		Amount:          amount.String(),
+		Currency:        req.OrderCurrency,
+		Status:          orderShared.StatusCreated,
+		Environment:     s.config.Environment,
+	}
+	if err := s.orders.Create(newOrder); err != nil {
+		s.logger.Error("Failed to store order", zap.Error(err))
+		return nil, err
+	}
+
+	payoutRes, err := s.payoutGateway.Payout(ctx, serviceModel)
+	if err != nil {
+		s.logger.Error("Failed to process payout", zap.Error(err))
+		if _, transitionErr := s.stateMachine.Transition(newOrder.MerchantOrderID, orderShared.FailureStatus(err),
+			orderShared.SourcePayout, err.Error()); transitionErr != nil {
+			s.logger.Error("Failed to mark order as failed", zap.String("merchantOrderID", newOrder.MerchantOrderID), zap.Error(transitionErr))
+		}
+		return nil, err
+	}
+
+	newOrder.PaymentGatewayOrderID = payoutRes.PaymentGatewayOrderID
+	tracing.Annotate(ctx, tracing.AttrZotaOrderID.String(payoutRes.PaymentGatewayOrderID))
+	if err := s.orders.Update(newOrder); err != nil {
+		// the payout itself already went through the payment gateway, so a store failure is only logged
+		s.logger.Error("Failed to update order", zap.String("merchantOrderID", newOrder.MerchantOrderID), zap.Error(err))
+	}
+
+	return &shared.Response{
+		ClientRequest:         *req,
+		OrderID:               payoutRes.OrderID,
+		PaymentGatewayOrderID: payoutRes.PaymentGatewayOrderID,
+		Environment:           newOrder.Environment,
+	}, nil
+}
+
+func (s *Service) validate(req *shared.ClientRequest) (money.Amount, error) {
+	//Zota assigns an endpoint per currency, only the currencies with a payout endpoint can be paid out
+	if _, ok := s.config.PayoutEndpointID(req.OrderCurrency); !ok {
+		s.logger.Error("Unsupported currency", zap.String("currency", req.OrderCurrency))
+		return money.Amount{}, apperror.UnsupportedCurrency(req.OrderCurrency)
+	}
+
+	amount, err := money.ParsePositive(req.OrderAmount, req.OrderCurrency)
+	if err != nil {
+		return money.Amount{}, apperror.InvalidField("orderAmount", "amount", err)
+	}
+	return amount, nil
+}
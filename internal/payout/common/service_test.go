@@ -0,0 +1,183 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/payout/common/zota"
+	"zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+type serviceTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockGateway *zota.MockPayoutPaymentGateway
+	logger      *zap.Logger
+	orders      *order.MemoryRepository
+	service     *Service
+	request     shared.ClientRequest
+}
+
+func (s *serviceTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockGateway = zota.NewMockPayoutPaymentGateway(s.mockCtrl)
+	s.logger, _ = zap.NewDevelopment()
+
+	cfg := &config.Config{ZotaEndpointIds: map[string]string{"USD": "usdEndpoint", "EUR": "eurEndpoint"}}
+
+	s.orders = order.NewMemoryRepository()
+	s.service = NewService(s.logger, cfg, s.mockGateway, s.orders, order.NewStateMachine(s.logger, s.orders, metrics.New()))
+
+	s.request = shared.ClientRequest{
+		UserId:                    "user123",
+		OrderAmount:               "100.00",
+		OrderCurrency:             "USD",
+		CustomerEmail:             "test@example.com",
+		CustomerFirstName:         "John",
+		CustomerLastName:          "Doe",
+		CustomerPhone:             "1234567890",
+		CustomerIp:                "127.0.0.1",
+		CustomerBankAccountNumber: "100200300",
+		CustomerBankAccountName:   "John Doe",
+	}
+}
+
+func (s *serviceTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func TestProcessPayout_Success(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Payout(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		assert.Equal(t, s.request, req.ClientRequest)
+		merchantOrderID = req.MerchantOrderID
+		return &shared.Response{ClientRequest: s.request, OrderID: req.MerchantOrderID, PaymentGatewayOrderID: "gateway123"}, nil
+	})
+
+	response, err := s.service.ProcessPayout(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, merchantOrderID, response.OrderID)
+	assert.Equal(t, "gateway123", response.PaymentGatewayOrderID)
+
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.TypePayout, stored.Type)
+	assert.Equal(t, "gateway123", stored.PaymentGatewayOrderID)
+}
+
+func TestProcessPayout_ConfiguredCurrency(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.OrderCurrency = "EUR"
+	s.request.OrderAmount = "50.5"
+	s.mockGateway.EXPECT().Payout(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		assert.Equal(t, "EUR", req.Amount.Currency())
+		assert.Equal(t, "50.50", req.Amount.String())
+		return &shared.Response{ClientRequest: s.request, OrderID: req.MerchantOrderID, PaymentGatewayOrderID: "gateway123"}, nil
+	})
+
+	response, err := s.service.ProcessPayout(context.Background(), &s.request)
+	require.NoError(t, err)
+
+	stored, err := s.orders.FindByMerchantOrderID(response.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", stored.Currency)
+	assert.Equal(t, "50.50", stored.Amount)
+}
+
+func TestProcessPayout_InvalidCurrency(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.OrderCurrency = "GBP"
+
+	response, err := s.service.ProcessPayout(context.Background(), &s.request)
+	assert.Equal(t, apperror.CodeUnsupportedCurrency, apperror.From(err).Code)
+	assert.Nil(t, response)
+}
+
+func TestProcessPayout_InvalidAmount(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	for amount, expectedErr := range map[string]error{
+		"abc":    money.ErrInvalidAmount,
+		"0":      money.ErrNonPositiveAmount,
+		"-5":     money.ErrNegativeAmount,
+		"10.123": money.ErrTooPrecise,
+	} {
+		s.request.OrderAmount = amount
+
+		response, err := s.service.ProcessPayout(context.Background(), &s.request)
+		assert.ErrorIs(t, err, expectedErr, amount)
+		assert.Nil(t, response)
+	}
+}
+
+func TestProcessPayout_PayoutError(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	expectedError := errors.New("payout error")
+
+	var merchantOrderID string
+	s.mockGateway.EXPECT().Payout(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+		merchantOrderID = req.MerchantOrderID
+		return nil, expectedError
+	})
+
+	response, err := s.service.ProcessPayout(context.Background(), &s.request)
+	assert.Equal(t, expectedError, err)
+	assert.Nil(t, response)
+
+	stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusError, stored.Status)
+}
+
+func TestProcessPayout_UnavailableLeavesOutcomeUnknown(t *testing.T) {
+	s := &serviceTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	for _, expectedError := range []error{
+		apperror.GatewayUnavailable("payment gateway is unavailable", &zotaapi.Error{HTTPStatus: http.StatusServiceUnavailable, Code: "503"}),
+		apperror.GatewayUnavailable("payment gateway is unreachable", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}),
+	} {
+		var merchantOrderID string
+		s.mockGateway.EXPECT().Payout(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, req shared.Request) (*shared.Response, error) {
+			merchantOrderID = req.MerchantOrderID
+			return nil, expectedError
+		})
+
+		_, err := s.service.ProcessPayout(context.Background(), &s.request)
+		assert.ErrorIs(t, err, expectedError)
+
+		// the payout may have reached Zota, a later callback or status poll settles it
+		stored, err := s.orders.FindByMerchantOrderID(merchantOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, orderShared.StatusUnknown, stored.Status, expectedError.Error())
+	}
+}
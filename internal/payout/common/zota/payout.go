@@ -0,0 +1,220 @@
+package zota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+const PaymentGatewayPayoutApiPath = "api/v1/payout/request"
+
+type PayoutRequest struct {
+	MerchantOrderID           string `json:"merchantOrderID"`
+	MerchantOrderDesc         string `json:"merchantOrderDesc"`
+	OrderAmount               string `json:"orderAmount"`
+	OrderCurrency             string `json:"orderCurrency"`
+	CustomerEmail             string `json:"customerEmail"`
+	CustomerFirstName         string `json:"customerFirstName"`
+	CustomerLastName          string `json:"customerLastName"`
+	CustomerPhone             string `json:"customerPhone"`
+	CustomerIP                string `json:"customerIP"`
+	CustomerCountryCode       string `json:"customerCountryCode"`
+	CallbackUrl               string `json:"callbackUrl"`
+	CustomerBankCode          string `json:"customerBankCode"`
+	CustomerBankAccountNumber string `json:"customerBankAccountNumber"`
+	CustomerBankAccountName   string `json:"customerBankAccountName"`
+	CustomerBankBranch        string `json:"customerBankBranch"`
+	CustomerBankAddress       string `json:"customerBankAddress"`
+	CustomerBankZipCode       string `json:"customerBankZipCode"`
+	CustomerBankRoutingNumber string `json:"customerBankRoutingNumber"`
+	CustomerBankProvince      string `json:"customerBankProvince"`
+	CustomerBankArea          string `json:"customerBankArea"`
+	CustomParam               string `json:"customParam"`
+	Signature                 string `json:"signature"`
+}
+
+type CustomParam struct {
+	UserId string `json:"UserId"`
+}
+
+type PayoutResponse struct {
+	Code    string              `json:"code"`
+	Message string              `json:"message,omitempty"`
+	Data    *PayoutResponseData `json:"data,omitempty"`
+}
+
+type PayoutResponseData struct {
+	MerchantOrderID string `json:"merchantOrderID"`
+	OrderID         string `json:"orderID"`
+}
+
+type PayoutGateway struct {
+	logger  *zap.Logger
+	config  *config.Config
+	client  *zotaapi.Client
+	metrics *metrics.Metrics
+}
+
+func NewPayoutGateway(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) *PayoutGateway {
+	return &PayoutGateway{logger: logger, config: config, client: client, metrics: metrics}
+}
+
+func (p *PayoutGateway) Payout(ctx context.Context, req shared.Request) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "zota.PayoutGateway.Payout",
+		tracing.AttrMerchantOrderID.String(req.MerchantOrderID), tracing.AttrCurrency.String(req.OrderCurrency))
+	res, err := p.payout(ctx, req)
+	if res != nil {
+		span.SetAttributes(tracing.AttrZotaOrderID.String(res.PaymentGatewayOrderID))
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
+func (p *PayoutGateway) payout(ctx context.Context, req shared.Request) (*shared.Response, error) {
+	p.logger.Info("Processing payout with Zota", zap.String("merchantOrderID", req.MerchantOrderID))
+
+	payoutReq, err := p.buildPayoutReq(req)
+	if err != nil {
+		p.logger.Error("Failed to build payout request", zap.Error(err))
+		return nil, err
+	}
+
+	payoutReqJSON, err := json.Marshal(payoutReq)
+	if err != nil {
+		p.logger.Error("Failed to marshal payout request", zap.Error(err))
+		return nil, err
+	}
+
+	endpointID, err := p.endpointID(payoutReq.OrderCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := zotaapi.WithTimeout(ctx, p.config.ZotaPayoutTimeout)
+	defer cancel()
+
+	// the latency includes the retries, it is what the caller waited for
+	started := time.Now()
+	respBody, statusCode, err := p.sendPayoutRequest(ctx, payoutReqJSON, endpointID)
+	if err != nil {
+		p.metrics.ObserveZotaCall(metrics.OperationPayout, started, err)
+		return nil, err
+	}
+
+	response, err := p.handlePayoutResponse(respBody, statusCode, req)
+	p.metrics.ObserveZotaCall(metrics.OperationPayout, started, err)
+	return response, err
+}
+
+// endpointID - Zota assigns an endpoint per currency, it is part of both the URL and the signature
+func (p *PayoutGateway) endpointID(currency string) (string, error) {
+	endpointID, ok := p.config.PayoutEndpointID(currency)
+	if !ok {
+		return "", fmt.Errorf("no Zota payout endpoint configured for currency %q", currency)
+	}
+	return endpointID, nil
+}
+
+func (p *PayoutGateway) buildPayoutReq(req shared.Request) (PayoutRequest, error) {
+	merchantOrderId := req.MerchantOrderID
+	if merchantOrderId == "" {
+		merchantOrderId = uuid.New().String()
+	}
+
+	endpointID, err := p.endpointID(req.OrderCurrency)
+	if err != nil {
+		return PayoutRequest{}, err
+	}
+
+	orderAmount := req.Amount.String()
+	signature := p.buildSignature(endpointID, merchantOrderId, orderAmount, req.CustomerEmail,
+		req.CustomerBankAccountNumber, p.config.ZotaAPISecretKey)
+
+	customParamJSON, err := json.Marshal(CustomParam{UserId: req.UserId})
+	if err != nil {
+		p.logger.Error("Failed to marshal custom param", zap.Error(err))
+		return PayoutRequest{}, err
+	}
+
+	return PayoutRequest{
+		MerchantOrderID:           merchantOrderId,
+		MerchantOrderDesc:         "Payout",
+		OrderAmount:               orderAmount,
+		OrderCurrency:             req.OrderCurrency,
+		CustomerEmail:             req.CustomerEmail,
+		CustomerFirstName:         req.CustomerFirstName,
+		CustomerLastName:          req.CustomerLastName,
+		CustomerPhone:             req.CustomerPhone,
+		CustomerIP:                req.CustomerIp,
+		CustomerCountryCode:       req.CustomerCountryCode,
+		CallbackUrl:               p.config.ZotaPayoutCallBackUrl,
+		CustomerBankCode:          req.CustomerBankCode,
+		CustomerBankAccountNumber: req.CustomerBankAccountNumber,
+		CustomerBankAccountName:   req.CustomerBankAccountName,
+		CustomerBankBranch:        req.CustomerBankBranch,
+		CustomerBankAddress:       req.CustomerBankAddress,
+		CustomerBankZipCode:       req.CustomerBankZipCode,
+		CustomerBankRoutingNumber: req.CustomerBankRoutingNumber,
+		CustomerBankProvince:      req.CustomerBankProvince,
+		CustomerBankArea:          req.CustomerBankArea,
+		CustomParam:               string(customParamJSON),
+		Signature:                 signature,
+	}, nil
+}
+
+func (p *PayoutGateway) buildSignature(endpointID, merchantOrderId, orderAmount, customerEmail, customerBankAccountNumber, merchantSecretKey string) string {
+	signatureString := fmt.Sprintf("%s%s%s%s%s%s", endpointID, merchantOrderId, orderAmount, customerEmail, customerBankAccountNumber, merchantSecretKey)
+	hash := sha256.New()
+	hash.Write([]byte(signatureString))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (p *PayoutGateway) sendPayoutRequest(ctx context.Context, payoutReqJSON []byte, endpointID string) ([]byte, int, error) {
+	url := fmt.Sprintf("%s/%s/%s/", p.config.ZotaBaseUrl, PaymentGatewayPayoutApiPath, endpointID)
+	p.logger.Debug("Sending payout request to Zota server", zap.String("url", url))
+
+	ctx, span := tracing.StartClient(ctx, "zota.PayoutGateway.sendPayoutRequest", http.MethodPost, url)
+	defer span.End()
+
+	resp, err := p.client.Do(ctx, zotaapi.Request{Method: http.MethodPost, URL: url, Body: payoutReqJSON})
+	if err != nil {
+		p.logger.Error("Failed to send payout request", zap.Error(err))
+		tracing.Record(span, err)
+		return nil, 0, err
+	}
+	tracing.RecordResponse(span, resp.StatusCode)
+	return resp.Body, resp.StatusCode, nil
+}
+
+func (p *PayoutGateway) handlePayoutResponse(respBody []byte, statusCode int, req shared.Request) (*shared.Response, error) {
+	var payoutResponse PayoutResponse
+	if err := zotaapi.DecodeResponse(statusCode, respBody, &payoutResponse); err != nil {
+		p.logger.Error("Zota did not accept the payout request", zap.Int("statusCode", statusCode), zap.Error(err))
+		return nil, err
+	}
+
+	if payoutResponse.Data == nil {
+		return nil, apperror.GatewayError("malformed payment gateway response", fmt.Errorf("no data in payoutResponse"))
+	}
+
+	p.logger.Info("Successfully processed payout request",
+		zap.String("merchantOrderID", payoutResponse.Data.MerchantOrderID),
+		zap.String("orderID", payoutResponse.Data.OrderID))
+	return &shared.Response{
+		ClientRequest:         req.ClientRequest,
+		OrderID:               payoutResponse.Data.MerchantOrderID,
+		PaymentGatewayOrderID: payoutResponse.Data.OrderID,
+	}, nil
+}
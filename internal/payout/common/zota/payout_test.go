@@ -0,0 +1,164 @@
+package zota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	"zota-dev-challenge/internal/payout/shared"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+type payoutGatewayTestSuite struct {
+	logger        *zap.Logger
+	config        *config.Config
+	payoutGateway *PayoutGateway
+	request       shared.Request
+}
+
+func (s *payoutGatewayTestSuite) setup(t *testing.T) {
+	s.logger, _ = zap.NewDevelopment()
+
+	s.config = &config.Config{
+		ZotaBaseUrl:           "https://example.com",
+		ZotaEndpointIds:       map[string]string{"USD": "testEndpoint", "EUR": "eurEndpoint"},
+		ZotaPayoutEndpointIds: map[string]string{"USD": "payoutEndpoint"},
+		ZotaAPISecretKey:      "testSecret",
+		ZotaPayoutCallBackUrl: "https://example.com/callback/payout",
+	}
+
+	s.payoutGateway = NewPayoutGateway(s.logger, s.config, zotaapi.NewClient(s.logger, s.config), metrics.New())
+
+	s.request = shared.Request{
+		ClientRequest: shared.ClientRequest{
+			UserId:                    "user123",
+			OrderAmount:               "100.00",
+			OrderCurrency:             "USD",
+			CustomerEmail:             "test@example.com",
+			CustomerFirstName:         "John",
+			CustomerLastName:          "Doe",
+			CustomerPhone:             "1234567890",
+			CustomerIp:                "127.0.0.1",
+			CustomerBankAccountNumber: "100200300",
+			CustomerBankAccountName:   "John Doe",
+		},
+		MerchantOrderID: "merchantOrder123",
+		Amount:          money.FromMinor(10000, "USD"),
+	}
+}
+
+func TestPayout_Success(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	payoutResponse := PayoutResponse{
+		Code: "200",
+		Data: &PayoutResponseData{
+			MerchantOrderID: "merchantOrder123",
+			OrderID:         "order123",
+		},
+	}
+	payoutResponseJSON, _ := json.Marshal(payoutResponse)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/payout/request/payoutEndpoint/", r.URL.Path)
+
+		var payoutReq PayoutRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payoutReq))
+		assert.Equal(t, "merchantOrder123", payoutReq.MerchantOrderID)
+		assert.Equal(t, "100200300", payoutReq.CustomerBankAccountNumber)
+		assert.Equal(t, "https://example.com/callback/payout", payoutReq.CallbackUrl)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(payoutResponseJSON)
+	}))
+	defer server.Close()
+
+	s.config.ZotaBaseUrl = server.URL
+
+	response, err := s.payoutGateway.Payout(context.Background(), s.request)
+	require.NoError(t, err)
+	assert.Equal(t, "merchantOrder123", response.OrderID)
+	assert.Equal(t, "order123", response.PaymentGatewayOrderID)
+}
+
+func TestPayout_NonOKHTTPResponse(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	s.config.ZotaBaseUrl = server.URL
+
+	response, err := s.payoutGateway.Payout(context.Background(), s.request)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
+
+func TestPayout_NoData(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"code":"200"}`))
+	}))
+	defer server.Close()
+
+	s.config.ZotaBaseUrl = server.URL
+
+	response, err := s.payoutGateway.Payout(context.Background(), s.request)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
+
+func TestBuildPayoutReq_FallsBackToDepositEndpoint(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	// no payout endpoint is configured for EUR, its deposit endpoint serves the payouts
+	s.request.OrderCurrency = "EUR"
+	s.request.Amount = money.FromMinor(10000, "EUR")
+
+	payoutReq, err := s.payoutGateway.buildPayoutReq(s.request)
+	require.NoError(t, err)
+	expected := s.payoutGateway.buildSignature("eurEndpoint", "merchantOrder123", "100.00", "test@example.com", "100200300", "testSecret")
+	assert.Equal(t, expected, payoutReq.Signature)
+	assert.Contains(t, payoutReq.CustomParam, `"UserId":"user123"`)
+}
+
+func TestBuildPayoutReq_UnconfiguredCurrency(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	s.request.OrderCurrency = "GBP"
+	s.request.Amount = money.FromMinor(10000, "GBP")
+
+	_, err := s.payoutGateway.buildPayoutReq(s.request)
+	assert.ErrorContains(t, err, `no Zota payout endpoint configured for currency "GBP"`)
+}
+
+func TestBuildSignature(t *testing.T) {
+	s := &payoutGatewayTestSuite{}
+	s.setup(t)
+
+	signature := s.payoutGateway.buildSignature("payoutEndpoint", "merchantOrder123", "100.00", "test@example.com", "100200300", "testSecret")
+
+	expectedSignatureString := fmt.Sprintf("%s%s%s%s%s%s", "payoutEndpoint", "merchantOrder123", "100.00", "test@example.com", "100200300", "testSecret")
+	hash := sha256.New()
+	hash.Write([]byte(expectedSignatureString))
+	assert.Equal(t, hex.EncodeToString(hash.Sum(nil)), signature)
+}
@@ -0,0 +1,46 @@
+package shared
+
+import (
+	"context"
+	"zota-dev-challenge/internal/money"
+)
+
+type ClientRequest struct {
+	UserId                    string `json:"userId" validate:"required"`
+	OrderAmount               string `json:"orderAmount" validate:"required"`
+	OrderCurrency             string `json:"orderCurrency" validate:"required"`
+	CustomerEmail             string `json:"customerEmail" validate:"required,email"`
+	CustomerFirstName         string `json:"customerFirstName" validate:"required"`
+	CustomerLastName          string `json:"customerLastName" validate:"required"`
+	CustomerPhone             string `json:"customerPhone" validate:"required"`
+	CustomerIp                string `json:"customerIp" validate:"required"`
+	CustomerCountryCode       string `json:"customerCountryCode"`
+	CustomerBankCode          string `json:"customerBankCode"`
+	CustomerBankAccountNumber string `json:"customerBankAccountNumber" validate:"required"`
+	CustomerBankAccountName   string `json:"customerBankAccountName" validate:"required"`
+	CustomerBankBranch        string `json:"customerBankBranch"`
+	CustomerBankAddress       string `json:"customerBankAddress"`
+	CustomerBankZipCode       string `json:"customerBankZipCode"`
+	CustomerBankRoutingNumber string `json:"customerBankRoutingNumber"`
+	CustomerBankProvince      string `json:"customerBankProvince"`
+	CustomerBankArea          string `json:"customerBankArea"`
+} //@name PayoutRequest
+
+// Request - service level model
+type Request struct {
+	ClientRequest
+	MerchantOrderID string
+	// Amount - OrderAmount parsed and normalized to the currency's minor units
+	Amount money.Amount
+}
+
+type Response struct {
+	ClientRequest         ClientRequest `json:"request"`
+	OrderID               string        `json:"orderId"`
+	PaymentGatewayOrderID string        `json:"paymentGatewayOrderId"`
+	Environment           string        `json:"environment"` // sandbox or live
+} //@name PayoutResponse
+
+type PayoutPaymentGateway interface {
+	Payout(ctx context.Context, req Request) (*Response, error)
+}
@@ -0,0 +1,78 @@
+package reconciliation
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+	"zota-dev-challenge/internal/config"
+)
+
+// Job - reconciles the last days with Zota on a schedule and saves each discrepancy report
+type Job struct {
+	logger     *zap.Logger
+	config     *config.Config
+	reconciler *Reconciler
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	now    func() time.Time
+}
+
+func NewJob(logger *zap.Logger, config *config.Config, reconciler *Reconciler) *Job {
+	return &Job{logger: logger, config: config, reconciler: reconciler, now: time.Now}
+}
+
+// Start runs the job in the background until Stop is called
+func (j *Job) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.done = make(chan struct{})
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.config.ReconciliationInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop cancels a reconciliation in flight and waits for it to wind down or for the context to expire
+func (j *Job) Stop(ctx context.Context) error {
+	j.cancel()
+
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run reconciles the last ReconciliationDays days, today included, and saves the report.
+// Today is covered again by the next run, once its orders had time to settle.
+func (j *Job) Run(ctx context.Context) {
+	toDate := j.now().UTC()
+	fromDate := toDate.AddDate(0, 0, 1-j.config.ReconciliationDays)
+
+	report, err := j.reconciler.Reconcile(ctx, fromDate, toDate, j.config.ReconciliationAutoCorrect)
+	if err != nil {
+		// Reconcile logged why, the next run tries again
+		return
+	}
+
+	path, err := SaveReport(j.config.ReconciliationReportDir, report)
+	if err != nil {
+		j.logger.Error("Failed to save reconciliation report", zap.Error(err))
+		return
+	}
+	j.logger.Info("Saved reconciliation report", zap.String("path", path), zap.Int("discrepancies", len(report.Discrepancies)))
+}
@@ -0,0 +1,286 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	orderCommon "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	reportShared "zota-dev-challenge/internal/report/shared"
+)
+
+// Kinds of discrepancies between the local orders and Zota's orders report
+const (
+	// KindMissingLocally - Zota reports an order the local store does not know
+	KindMissingLocally = "missing_locally"
+	// KindMissingAtZota - a local order reached Zota, but Zota does not report it
+	KindMissingAtZota    = "missing_at_zota"
+	KindStatusMismatch   = "status_mismatch"
+	KindAmountMismatch   = "amount_mismatch"
+	KindCurrencyMismatch = "currency_mismatch"
+	KindOrderIDMismatch  = "order_id_mismatch"
+	// KindInvalidRecord - a row of Zota's report could not be read, its order is not compared
+	KindInvalidRecord = "invalid_record"
+)
+
+// Kinds - every kind of discrepancy, in the order they are reported
+var Kinds = []string{KindMissingLocally, KindMissingAtZota, KindStatusMismatch, KindAmountMismatch, KindCurrencyMismatch,
+	KindOrderIDMismatch, KindInvalidRecord}
+
+// Discrepancy - an order the local store and Zota disagree about
+type Discrepancy struct {
+	Kind            string `json:"kind"`
+	MerchantOrderID string `json:"merchantOrderId"`
+	OrderID         string `json:"orderId,omitempty"`
+	Local           string `json:"local,omitempty"`
+	Zota            string `json:"zota,omitempty"`
+	// Corrected - the local order was changed to what Zota reports
+	Corrected bool   `json:"corrected"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Report - the outcome of comparing the orders created in a date range
+type Report struct {
+	FromDate      time.Time     `json:"fromDate"`
+	ToDate        time.Time     `json:"toDate"`
+	StartedAt     time.Time     `json:"startedAt"`
+	FinishedAt    time.Time     `json:"finishedAt"`
+	ZotaOrders    int           `json:"zotaOrders"`
+	LocalOrders   int           `json:"localOrders"`
+	Matched       int           `json:"matched"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Counts returns the number of discrepancies by kind
+func (r *Report) Counts() map[string]int {
+	counts := make(map[string]int, len(Kinds))
+	for _, discrepancy := range r.Discrepancies {
+		counts[discrepancy.Kind]++
+	}
+	return counts
+}
+
+func (r *Report) add(discrepancy Discrepancy) {
+	r.Discrepancies = append(r.Discrepancies, discrepancy)
+}
+
+// Reconciler - proves the local orders match Zota's records, and optionally corrects the ones that do not
+type Reconciler struct {
+	logger       *zap.Logger
+	config       *config.Config
+	reportClient reportShared.OrdersReportPaymentGateway
+	orders       orderShared.OrderRepository
+	stateMachine *orderCommon.StateMachine
+	metrics      *metrics.Metrics
+	now          func() time.Time
+}
+
+func NewReconciler(logger *zap.Logger, config *config.Config, reportClient reportShared.OrdersReportPaymentGateway,
+	orders orderShared.OrderRepository, stateMachine *orderCommon.StateMachine, metrics *metrics.Metrics) *Reconciler {
+	return &Reconciler{
+		logger:       logger,
+		config:       config,
+		reportClient: reportClient,
+		orders:       orders,
+		stateMachine: stateMachine,
+		metrics:      metrics,
+		now:          time.Now,
+	}
+}
+
+// Reconcile compares the orders created from fromDate to toDate, whole UTC days with both included, with Zota's orders report.
+// With autoCorrect, non-final local orders are moved to the status Zota reports through the state machine.
+func (r *Reconciler) Reconcile(ctx context.Context, fromDate, toDate time.Time, autoCorrect bool) (*Report, error) {
+	fromDate, toDate = day(fromDate), day(toDate)
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("the range ends on %s, before it starts on %s",
+			toDate.Format(time.DateOnly), fromDate.Format(time.DateOnly))
+	}
+
+	report := &Report{FromDate: fromDate, ToDate: toDate, StartedAt: r.now().UTC(), Discrepancies: []Discrepancy{}}
+	r.logger.Info("Reconciling orders with Zota",
+		zap.String("fromDate", fromDate.Format(time.DateOnly)),
+		zap.String("toDate", toDate.Format(time.DateOnly)),
+		zap.Bool("autoCorrect", autoCorrect))
+
+	local, err := r.localOrders(fromDate, toDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	report.LocalOrders = len(local)
+
+	query := reportShared.Query{FromDate: fromDate, ToDate: toDate, DateType: reportShared.DateTypeCreated}
+	invalid, err := r.reportClient.OrdersReport(ctx, query, func(record reportShared.Record) error {
+		report.ZotaOrders++
+
+		order, ok := local[record.MerchantOrderID]
+		if ok {
+			delete(local, record.MerchantOrderID)
+		} else {
+			// the order may have been created locally just before the range started
+			found, err := r.orders.FindByMerchantOrderID(record.MerchantOrderID)
+			if errors.Is(err, orderShared.ErrOrderNotFound) {
+				report.add(Discrepancy{Kind: KindMissingLocally, MerchantOrderID: record.MerchantOrderID,
+					OrderID: record.OrderID, Zota: record.Status})
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			order = *found
+		}
+
+		matched, err := r.compare(report, order, record, autoCorrect)
+		if err != nil {
+			return err
+		}
+		if matched {
+			report.Matched++
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to reconcile orders", zap.Error(err))
+		return nil, err
+	}
+
+	// Zota reports the order of an unreadable row, it is not missing at Zota
+	for _, record := range invalid {
+		report.ZotaOrders++
+		delete(local, record.MerchantOrderID)
+		report.add(Discrepancy{Kind: KindInvalidRecord, MerchantOrderID: record.MerchantOrderID, OrderID: record.OrderID,
+			Note: fmt.Sprintf("orders report line %d: %s", record.Line, record.Error)})
+	}
+
+	// orders Zota never accepted have no Zota order ID, they are not expected in the report
+	for _, order := range local {
+		if order.PaymentGatewayOrderID != "" {
+			report.add(Discrepancy{Kind: KindMissingAtZota, MerchantOrderID: order.MerchantOrderID,
+				OrderID: order.PaymentGatewayOrderID, Local: order.Status})
+		}
+	}
+
+	report.FinishedAt = r.now().UTC()
+	r.metrics.SetReconciliationDiscrepancies(Kinds, report.Counts())
+	r.logger.Info("Reconciled orders with Zota",
+		zap.Int("zotaOrders", report.ZotaOrders),
+		zap.Int("localOrders", report.LocalOrders),
+		zap.Int("matched", report.Matched),
+		zap.Int("discrepancies", len(report.Discrepancies)))
+	return report, nil
+}
+
+// localOrders returns the orders of this environment created in [from, to) by merchant order ID
+func (r *Reconciler) localOrders(from, to time.Time) (map[string]orderShared.Order, error) {
+	orders, err := r.orders.ListCreatedBetween(from, to)
+	if err != nil {
+		r.logger.Error("Failed to list local orders", zap.Error(err))
+		return nil, err
+	}
+
+	local := make(map[string]orderShared.Order, len(orders))
+	for _, order := range orders {
+		if order.InEnvironment(r.config.Environment) {
+			local[order.MerchantOrderID] = order
+		}
+	}
+	return local, nil
+}
+
+// compare adds what differs between the local order and Zota's record to the report, it reports whether they match
+func (r *Reconciler) compare(report *Report, order orderShared.Order, record reportShared.Record, autoCorrect bool) (bool, error) {
+	matched := true
+	discrepancy := func(kind, local, zota string) Discrepancy {
+		matched = false
+		return Discrepancy{Kind: kind, MerchantOrderID: order.MerchantOrderID, OrderID: record.OrderID, Local: local, Zota: zota}
+	}
+
+	if order.PaymentGatewayOrderID != record.OrderID {
+		d := discrepancy(KindOrderIDMismatch, order.PaymentGatewayOrderID, record.OrderID)
+		// an order whose deposit call timed out never learnt its Zota order ID
+		if order.PaymentGatewayOrderID == "" && autoCorrect && !orderShared.IsFinal(order.Status) {
+			r.correctOrderID(&d, record)
+		}
+		report.add(d)
+	}
+
+	if order.Currency != record.Currency {
+		report.add(discrepancy(KindCurrencyMismatch, order.Currency, record.Currency))
+	} else {
+		settled, err := r.settledAmount(order)
+		if err != nil {
+			return false, err
+		}
+		if amount, err := money.Parse(settled, order.Currency); err != nil || !amount.Equal(record.Amount) {
+			report.add(discrepancy(KindAmountMismatch, settled, record.Amount.String()))
+		}
+	}
+
+	if order.Status != record.Status {
+		d := discrepancy(KindStatusMismatch, order.Status, record.Status)
+		switch {
+		case orderShared.IsFinal(order.Status):
+			d.Note = "the local order is final, it is not corrected"
+		case autoCorrect:
+			r.correctStatus(&d, record)
+		}
+		report.add(d)
+	}
+	return matched, nil
+}
+
+// settledAmount returns what Zota settled an approved order for, as its approval recorded it, and the requested
+// amount otherwise. Zota reports the settled amount, which may differ from the requested one.
+func (r *Reconciler) settledAmount(order orderShared.Order) (string, error) {
+	if order.Status != orderShared.StatusApproved {
+		return order.Amount, nil
+	}
+
+	transitions, err := r.orders.ListTransitions(order.MerchantOrderID)
+	if err != nil {
+		r.logger.Error("Failed to read order transitions", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+		return "", err
+	}
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].To == orderShared.StatusApproved && transitions[i].Amount != "" {
+			return transitions[i].Amount, nil
+		}
+	}
+	return order.Amount, nil
+}
+
+func (r *Reconciler) correctStatus(d *Discrepancy, record reportShared.Record) {
+	_, err := r.stateMachine.Settle(record.MerchantOrderID, record.Status, orderShared.SourceReconciliation,
+		record.ErrorMessage, record.Amount.String())
+	if err != nil {
+		r.logger.Warn("Failed to correct order status", zap.String("merchantOrderID", record.MerchantOrderID),
+			zap.String("status", record.Status), zap.Error(err))
+		d.Note = err.Error()
+		return
+	}
+	d.Corrected = true
+}
+
+func (r *Reconciler) correctOrderID(d *Discrepancy, record reportShared.Record) {
+	order, err := r.orders.FindByMerchantOrderID(record.MerchantOrderID)
+	if err == nil {
+		order.PaymentGatewayOrderID = record.OrderID
+		err = r.orders.Update(order)
+	}
+	if err != nil {
+		r.logger.Warn("Failed to correct Zota order ID", zap.String("merchantOrderID", record.MerchantOrderID), zap.Error(err))
+		d.Note = err.Error()
+		return
+	}
+	d.Corrected = true
+}
+
+// day truncates to the start of the UTC day, the report ranges are whole days
+func day(t time.Time) time.Time {
+	return time.Date(t.UTC().Year(), t.UTC().Month(), t.UTC().Day(), 0, 0, 0, 0, time.UTC)
+}
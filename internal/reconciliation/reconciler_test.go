@@ -0,0 +1,276 @@
+package reconciliation
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/report/common/zota"
+	reportShared "zota-dev-challenge/internal/report/shared"
+)
+
+type reconcilerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockGateway *zota.MockOrdersReportPaymentGateway
+	orders      *order.MemoryRepository
+	metrics     *metrics.Metrics
+	config      *config.Config
+	reconciler  *Reconciler
+	today       time.Time
+	// invalid - the rows the report skips
+	invalid []reportShared.InvalidRecord
+}
+
+func (s *reconcilerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockGateway = zota.NewMockOrdersReportPaymentGateway(s.mockCtrl)
+	logger, _ := zap.NewDevelopment()
+
+	s.orders = order.NewMemoryRepository()
+	s.metrics = metrics.New()
+	s.config = &config.Config{Environment: config.EnvironmentSandbox, ReconciliationDays: 2, ReconciliationReportDir: t.TempDir()}
+	s.reconciler = NewReconciler(logger, s.config, s.mockGateway, s.orders, order.NewStateMachine(logger, s.orders, s.metrics), s.metrics)
+	s.today = day(time.Now())
+}
+
+func (s *reconcilerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *reconcilerTestSuite) createOrder(t *testing.T, merchantOrderID, orderID, status string) {
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       merchantOrderID,
+		PaymentGatewayOrderID: orderID,
+		Type:                  orderShared.TypeSale,
+		UserID:                "user123",
+		Amount:                "100.00",
+		Currency:              "USD",
+		Status:                status,
+		Environment:           config.EnvironmentSandbox,
+	}))
+}
+
+func (s *reconcilerTestSuite) expectReport(t *testing.T, records ...reportShared.Record) {
+	s.mockGateway.EXPECT().OrdersReport(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, query reportShared.Query, handle func(reportShared.Record) error) ([]reportShared.InvalidRecord, error) {
+			assert.False(t, query.FromDate.After(s.today) || query.ToDate.Before(s.today), "the report must cover today")
+			assert.Equal(t, reportShared.DateTypeCreated, query.DateType)
+			for _, record := range records {
+				if err := handle(record); err != nil {
+					return nil, err
+				}
+			}
+			return s.invalid, nil
+		})
+}
+
+func record(merchantOrderID, orderID, status, amount, currency string) reportShared.Record {
+	value, _ := money.Parse(amount, currency)
+	return reportShared.Record{OrderID: orderID, MerchantOrderID: merchantOrderID, Type: orderShared.TypeSale,
+		Status: status, Amount: value, Currency: currency}
+}
+
+func kinds(report *Report) []string {
+	var found []string
+	for _, discrepancy := range report.Discrepancies {
+		found = append(found, discrepancy.Kind+":"+discrepancy.MerchantOrderID)
+	}
+	return found
+}
+
+func TestReconcile_FindsDiscrepancies(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "matched", "order1", orderShared.StatusApproved)
+	s.createOrder(t, "declinedAtZota", "order2", orderShared.StatusApproved)
+	s.createOrder(t, "otherAmount", "order3", orderShared.StatusApproved)
+	s.createOrder(t, "otherCurrency", "order4", orderShared.StatusApproved)
+	s.createOrder(t, "missingAtZota", "order5", orderShared.StatusPending)
+	// Zota never accepted it, so it is not expected in the report
+	s.createOrder(t, "rejected", "", orderShared.StatusError)
+	s.createOrder(t, "unreadable", "order7", orderShared.StatusApproved)
+
+	s.invalid = []reportShared.InvalidRecord{{Line: 7, MerchantOrderID: "unreadable", OrderID: "order7", Error: `invalid amount "1O0"`}}
+	s.expectReport(t,
+		record("matched", "order1", orderShared.StatusApproved, "100.00", "USD"),
+		record("declinedAtZota", "order2", orderShared.StatusDeclined, "100.00", "USD"),
+		record("otherAmount", "order3", orderShared.StatusApproved, "90.00", "USD"),
+		record("otherCurrency", "order4", orderShared.StatusApproved, "100.00", "EUR"),
+		record("missingLocally", "order6", orderShared.StatusApproved, "100.00", "USD"),
+	)
+
+	report, err := s.reconciler.Reconcile(context.Background(), s.today, s.today, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, report.ZotaOrders)
+	assert.Equal(t, 7, report.LocalOrders)
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, []string{
+		"status_mismatch:declinedAtZota",
+		"amount_mismatch:otherAmount",
+		"currency_mismatch:otherCurrency",
+		"missing_locally:missingLocally",
+		"invalid_record:unreadable",
+		"missing_at_zota:missingAtZota",
+	}, kinds(report))
+	assert.Equal(t, `orders report line 7: invalid amount "1O0"`, report.Discrepancies[4].Note)
+
+	statusMismatch := report.Discrepancies[0]
+	assert.Equal(t, orderShared.StatusApproved, statusMismatch.Local)
+	assert.Equal(t, orderShared.StatusDeclined, statusMismatch.Zota)
+	assert.False(t, statusMismatch.Corrected)
+	assert.NotEmpty(t, statusMismatch.Note, "final orders are not corrected")
+
+	assert.NoError(t, testutil.GatherAndCompare(s.metrics.Registry, strings.NewReader(`
+# HELP merchant_reconciliation_discrepancies Differences between the local orders and Zota's orders report found by the last reconciliation, by kind.
+# TYPE merchant_reconciliation_discrepancies gauge
+merchant_reconciliation_discrepancies{kind="amount_mismatch"} 1
+merchant_reconciliation_discrepancies{kind="currency_mismatch"} 1
+merchant_reconciliation_discrepancies{kind="invalid_record"} 1
+merchant_reconciliation_discrepancies{kind="missing_at_zota"} 1
+merchant_reconciliation_discrepancies{kind="missing_locally"} 1
+merchant_reconciliation_discrepancies{kind="order_id_mismatch"} 0
+merchant_reconciliation_discrepancies{kind="status_mismatch"} 1
+`), "merchant_reconciliation_discrepancies"))
+}
+
+func TestReconcile_ComparesTheSettledAmount(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	// Zota settled both deposits for less than they asked for, which the approvals recorded
+	for _, merchantOrderID := range []string{"settled", "otherSettled"} {
+		s.createOrder(t, merchantOrderID, "order-"+merchantOrderID, orderShared.StatusPending)
+		_, err := s.reconciler.stateMachine.Settle(merchantOrderID, orderShared.StatusApproved, orderShared.SourceCallback, "", "95.00")
+		require.NoError(t, err)
+	}
+
+	s.expectReport(t,
+		record("settled", "order-settled", orderShared.StatusApproved, "95.00", "USD"),
+		record("otherSettled", "order-otherSettled", orderShared.StatusApproved, "90.00", "USD"),
+	)
+
+	report, err := s.reconciler.Reconcile(context.Background(), s.today, s.today, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, []string{"amount_mismatch:otherSettled"}, kinds(report))
+	assert.Equal(t, "95.00", report.Discrepancies[0].Local, "the settled amount is compared, not the requested one")
+}
+
+func TestReconcile_AutoCorrectsNonFinalOrders(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "pending", "order1", orderShared.StatusPending)
+	// the deposit call timed out, so the order never learnt its Zota order ID
+	s.createOrder(t, "unknown", "", orderShared.StatusUnknown)
+	s.createOrder(t, "final", "order3", orderShared.StatusDeclined)
+
+	s.expectReport(t,
+		record("pending", "order1", orderShared.StatusApproved, "100.00", "USD"),
+		record("unknown", "order2", orderShared.StatusDeclined, "100.00", "USD"),
+		record("final", "order3", orderShared.StatusApproved, "100.00", "USD"),
+	)
+
+	report, err := s.reconciler.Reconcile(context.Background(), s.today, s.today, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"status_mismatch:pending",
+		"order_id_mismatch:unknown",
+		"status_mismatch:unknown",
+		"status_mismatch:final",
+	}, kinds(report))
+	assert.True(t, report.Discrepancies[0].Corrected)
+	assert.True(t, report.Discrepancies[1].Corrected)
+	assert.True(t, report.Discrepancies[2].Corrected)
+	assert.False(t, report.Discrepancies[3].Corrected, "final orders are never corrected")
+
+	pending, err := s.orders.FindByMerchantOrderID("pending")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, pending.Status)
+	transitions, err := s.orders.ListTransitions("pending")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, orderShared.SourceReconciliation, transitions[0].Source)
+	assert.Equal(t, "100.00", transitions[0].Amount)
+
+	unknown, err := s.orders.FindByMerchantOrderID("unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "order2", unknown.PaymentGatewayOrderID)
+	assert.Equal(t, orderShared.StatusDeclined, unknown.Status)
+
+	final, err := s.orders.FindByMerchantOrderID("final")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusDeclined, final.Status)
+}
+
+func TestReconcile_SkipsOrdersOfTheOtherEnvironment(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{MerchantOrderID: "live", PaymentGatewayOrderID: "order1",
+		Type: orderShared.TypeSale, Amount: "100.00", Currency: "USD", Status: orderShared.StatusApproved,
+		Environment: config.EnvironmentLive}))
+	s.expectReport(t)
+
+	report, err := s.reconciler.Reconcile(context.Background(), s.today, s.today, false)
+	require.NoError(t, err)
+	assert.Zero(t, report.LocalOrders)
+	assert.Empty(t, report.Discrepancies)
+}
+
+func TestReconcile_RejectsReversedRange(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	_, err := s.reconciler.Reconcile(context.Background(), s.today, s.today.AddDate(0, 0, -1), false)
+	assert.Error(t, err)
+}
+
+func TestJob_SavesReport(t *testing.T) {
+	s := &reconcilerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "missingAtZota", "order1", orderShared.StatusPending)
+	s.expectReport(t)
+
+	logger, _ := zap.NewDevelopment()
+	NewJob(logger, s.config, s.reconciler).Run(context.Background())
+
+	files, err := os.ReadDir(s.config.ReconciliationReportDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasPrefix(files[0].Name(), "reconciliation-"+s.today.AddDate(0, 0, -1).Format(time.DateOnly)+"-"+
+		s.today.Format(time.DateOnly)), files[0].Name())
+
+	content, err := os.ReadFile(filepath.Join(s.config.ReconciliationReportDir, files[0].Name()))
+	require.NoError(t, err)
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		reportColumns,
+		{KindMissingAtZota, "missingAtZota", "order1", orderShared.StatusPending, "", "false", ""},
+	}, rows)
+}
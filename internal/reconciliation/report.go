@@ -0,0 +1,46 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// reportColumns - the header of the discrepancy report
+var reportColumns = []string{"Kind", "Merchant Order ID", "Order ID", "Local", "Zota", "Corrected", "Note"}
+
+// WriteCSV writes the discrepancies of the report as CSV, one row each
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	writer.Write(reportColumns)
+	for _, d := range report.Discrepancies {
+		writer.Write([]string{d.Kind, d.MerchantOrderID, d.OrderID, d.Local, d.Zota, strconv.FormatBool(d.Corrected), d.Note})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SaveReport writes the discrepancy report to a new file in dir, named after the range and the time it ran
+func SaveReport(dir string, report *Report) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create the reconciliation report directory: %w", err)
+	}
+
+	name := fmt.Sprintf("reconciliation-%s-%s-%s.csv", report.FromDate.Format(time.DateOnly),
+		report.ToDate.Format(time.DateOnly), report.StartedAt.Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the reconciliation report: %w", err)
+	}
+	if err := WriteCSV(file, report); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write the reconciliation report: %w", err)
+	}
+	return path, file.Close()
+}
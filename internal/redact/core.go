@@ -0,0 +1,66 @@
+package redact
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// core redacts the fields of every entry before the wrapped core encodes them
+type core struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// Core wraps a logger core, install it with zap.WrapCore
+func (r *Redactor) Core(inner zapcore.Core) zapcore.Core {
+	return &core{Core: inner, redactor: r}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(c.redactor.fields(fields)), redactor: c.redactor}
+}
+
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Text(entry.Message)
+	return c.Core.Write(entry, c.redactor.fields(fields))
+}
+
+func (r *Redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = r.field(field)
+	}
+	return redacted
+}
+
+// field redacts a single log field, by its key when it is registered and by its content otherwise
+func (r *Redactor) field(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		if rule := lookup(field.Key); rule != Keep {
+			field.String = Apply(rule, field.String)
+		} else {
+			field.String = r.Text(field.String)
+		}
+	case zapcore.ByteStringType:
+		if body, ok := field.Interface.([]byte); ok {
+			field.Interface = r.JSON(body)
+		}
+	case zapcore.ErrorType:
+		// errors of the HTTP client carry the whole URL, signature included
+		if err, ok := field.Interface.(error); ok {
+			if message := r.Text(err.Error()); message != err.Error() {
+				return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: message}
+			}
+		}
+	case zapcore.ReflectType:
+		field.Interface = r.Value(field.Interface)
+	}
+	return field
+}
@@ -0,0 +1,44 @@
+package redact
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+func TestCore_RedactsFields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(New("topsecret").Core(core)).With(zap.String("customerEmail", "john@example.com"))
+
+	logger.Info("Signed with topsecret",
+		zap.String("url", "https://example.com/?orderID=1&signature=abc"),
+		zap.Error(errors.New(`Get "https://example.com/?signature=abc": EOF`)),
+		zap.ByteString("body", []byte(`{"customerPhone":"1234567890"}`)),
+		zap.Any("customer", struct {
+			Name string `json:"name" log:"mask"`
+		}{Name: "John"}),
+		zap.Int("attempt", 2))
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "Signed with "+Redacted, entry.Message)
+	assert.Equal(t, map[string]any{
+		"customerEmail": "j***@example.com",
+		"url":           "https://example.com/?orderID=1&signature=" + Redacted,
+		"error":         `Get "https://example.com/?signature=` + Redacted + `": EOF`,
+		"body":          `{"customerPhone":"******7890"}`,
+		"customer":      map[string]any{"name": "J***"},
+		"attempt":       int64(2),
+	}, entry.ContextMap())
+}
+
+func TestCore_KeepsCleanErrors(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	err := errors.New("connection refused")
+	zap.New(New().Core(core)).Error("Failed", zap.Error(err))
+
+	assert.Equal(t, err, logs.All()[0].Context[0].Interface, "errors without anything to redact keep their type")
+}
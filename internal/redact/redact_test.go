@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type customer struct {
+	Name      string `json:"name" log:"mask"`
+	Email     string `json:"email" log:"email"`
+	Token     string `json:"token" log:"secret"`
+	Note      string `json:"note" log:""`
+	Signature string `json:"signature"`
+	CreatedAt time.Time
+	hidden    string
+}
+
+type order struct {
+	customer
+	Customers []customer          `json:"customers"`
+	Extra     map[string]string   `json:"extra"`
+	Nested    *customer           `json:"nested,omitempty"`
+	Skipped   string              `json:"-"`
+	Lookup    map[string]customer `json:"lookup"`
+}
+
+func TestApply(t *testing.T) {
+	assert.Equal(t, "j***@example.com", Apply(Email, "john.doe@example.com"))
+	assert.Equal(t, "n***", Apply(Email, "not-an-email"))
+	assert.Equal(t, "******7890", Apply(Phone, "1234567890"))
+	assert.Equal(t, "***", Apply(Phone, "123"))
+	assert.Equal(t, "Ж***", Apply(Mask, "Жанна"))
+	assert.Equal(t, Redacted, Apply(Secret, "abc"))
+	assert.Equal(t, Apply(Hash, "127.0.0.1"), Apply(Hash, "127.0.0.1"), "the same value hashes the same")
+	assert.NotContains(t, Apply(Hash, "127.0.0.1"), "127")
+	assert.Equal(t, "", Apply(Secret, ""), "empty values stay empty so missing data remains visible")
+	assert.Equal(t, "value", Apply(Keep, "value"))
+}
+
+func TestValue_TagsAndRegistry(t *testing.T) {
+	r := New("topsecret")
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	value := r.Value(&order{
+		customer:  customer{Name: "John", Email: "john@example.com", Token: "t", Signature: "abc", CreatedAt: created, hidden: "x"},
+		Customers: []customer{{Email: "jane@example.com"}},
+		Extra:     map[string]string{"customerPhone": "1234567890", "comment": "key topsecret"},
+		Skipped:   "skipped",
+	})
+
+	assert.Equal(t, map[string]any{
+		"name":      "J***",
+		"email":     "j***@example.com",
+		"token":     Redacted,
+		"note":      "",
+		"signature": Redacted,
+		"CreatedAt": created,
+		"customers": []any{map[string]any{
+			"name": "", "email": "j***@example.com", "token": "", "note": "", "signature": "", "CreatedAt": time.Time{},
+		}},
+		"extra":  map[string]any{"customerPhone": "******7890", "comment": "key " + Redacted},
+		"nested": nil,
+		"lookup": map[string]any{},
+	}, value)
+}
+
+func TestValue_TagOverridesRegistry(t *testing.T) {
+	type payload struct {
+		// an explicit empty tag keeps a registered name in clear
+		Signature string `json:"signature" log:""`
+	}
+	assert.Equal(t, map[string]any{"signature": "abc"}, New().Value(payload{Signature: "abc"}))
+}
+
+func TestValue_NonStructs(t *testing.T) {
+	r := New("topsecret")
+	assert.Equal(t, 42, r.Value(42))
+	assert.Equal(t, "the "+Redacted, r.Value("the topsecret"))
+	assert.Nil(t, r.Value(nil))
+}
+
+func TestText(t *testing.T) {
+	r := New("topsecret")
+
+	text := r.Text(`Get "https://api.zotapay-sandbox.com/api/v1/query/order-status/?merchantID=m&Signature=abc123&timestamp=1": EOF`)
+	assert.Equal(t, `Get "https://api.zotapay-sandbox.com/api/v1/query/order-status/?merchantID=m&Signature=[REDACTED]&timestamp=1": EOF`, text)
+
+	assert.Equal(t, "https://example.com/?customerEmail=j***@example.com", r.Text("https://example.com/?customerEmail=john@example.com"))
+	assert.Equal(t, "secret is "+Redacted, r.Text("secret is topsecret"))
+	assert.Equal(t, "nothing to hide", r.Text("nothing to hide"))
+}
+
+func TestJSON(t *testing.T) {
+	r := New("topsecret")
+
+	body := r.JSON([]byte(`{"code":"200","data":{"customerEmail":"john@example.com","customerIP":"127.0.0.1","amount":10.50,
+		"items":[{"signature":"abc"}],"message":"key topsecret"}}`))
+	assert.JSONEq(t, `{"code":"200","data":{"customerEmail":"j***@example.com","customerIP":"`+Apply(Hash, "127.0.0.1")+`","amount":10.50,
+		"items":[{"signature":"[REDACTED]"}],"message":"key [REDACTED]"}}`, string(body))
+
+	assert.Equal(t, "not json "+Redacted, string(r.JSON([]byte("not json topsecret"))))
+}
+
+func TestRegister(t *testing.T) {
+	Register(map[string]Rule{"passportNumber": Secret})
+	defer delete(fields, "passportnumber")
+
+	r := New()
+	assert.Equal(t, map[string]any{"passportNumber": Redacted}, r.Value(map[string]string{"passportNumber": "X123"}))
+	assert.Equal(t, "/?passportNumber="+Redacted, r.Text("/?passportNumber=X123"))
+}
@@ -0,0 +1,174 @@
+package redact
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Redactor redacts values before they are logged, on top of the registry it scrubs known secrets wherever they appear
+type Redactor struct {
+	secrets []string
+	// query matches the registered names as query parameters, e.g. ?signature=... in a URL or an error message
+	query *regexp.Regexp
+}
+
+// New returns a redactor that also scrubs the given secrets, e.g. the API secret key, from every logged text
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	r.query = regexp.MustCompile(`(?i)([?&](?:` + strings.Join(names, "|") + `)=)([^&\s"']*)`)
+	return r
+}
+
+// Text scrubs the secrets and the registered query parameters from free text such as URLs and error messages
+func (r *Redactor) Text(text string) string {
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, Redacted)
+	}
+	return r.query.ReplaceAllStringFunc(text, func(match string) string {
+		parts := r.query.FindStringSubmatch(match)
+		name := strings.TrimRight(parts[1][1:], "=")
+		return parts[1] + Apply(lookup(name), parts[2])
+	})
+}
+
+// JSON redacts a JSON document by the registered field names, anything that is not JSON is scrubbed as text
+func (r *Redactor) JSON(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return []byte(r.Text(string(body)))
+	}
+
+	redacted, err := json.Marshal(r.document(document))
+	if err != nil {
+		return []byte(r.Text(string(body)))
+	}
+	return redacted
+}
+
+func (r *Redactor) document(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for name, field := range value {
+			value[name] = r.named(name, field)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = r.document(item)
+		}
+		return value
+	case string:
+		return r.Text(value)
+	default:
+		return value
+	}
+}
+
+// named redacts the value of a named field, strings by the registered rule and anything nested field by field
+func (r *Redactor) named(name string, value any) any {
+	text, ok := value.(string)
+	if !ok {
+		return r.document(value)
+	}
+	if rule := lookup(name); rule != Keep {
+		return Apply(rule, text)
+	}
+	return r.Text(text)
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// Value redacts a struct by its `log` tags and the registry, the result is logged in place of the struct.
+// Values that are not structs, or that marshal themselves like amounts and times, are returned unchanged.
+func (r *Redactor) Value(value any) any {
+	return r.value(reflect.ValueOf(value))
+}
+
+func (r *Redactor) value(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type().Implements(jsonMarshaler) || v.Type().Implements(textMarshaler):
+		return v.Interface()
+	case v.Kind() == reflect.Struct:
+		out := make(map[string]any)
+		r.structFields(v, out)
+		return out
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		out := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out[iter.Key().String()] = r.named(iter.Key().String(), r.value(iter.Value()))
+		}
+		return out
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = r.value(v.Index(i))
+		}
+		return items
+	case v.Kind() == reflect.String:
+		return r.Text(v.String())
+	default:
+		return v.Interface()
+	}
+}
+
+// structFields writes the fields under their JSON names, embedded structs are flattened the way encoding/json does
+func (r *Redactor) structFields(v reflect.Value, out map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.structFields(v.Field(i), out)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		rule, tagged := field.Tag.Lookup("log")
+		if !tagged {
+			rule = string(lookup(name))
+		}
+
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() == reflect.String && Rule(rule) != Keep {
+			out[name] = Apply(Rule(rule), fieldValue.String())
+			continue
+		}
+		out[name] = r.value(fieldValue)
+	}
+}
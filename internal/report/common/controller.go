@@ -0,0 +1,161 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/schema"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/report/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+// flushEvery - how many records are written before they are flushed to the client
+const flushEvery = 100
+
+var decoder = schema.NewDecoder()
+
+// Handler
+// @Summary Show Zota's orders report
+// @Description streams the orders Zota reports for the date range as a JSON array, while Zota is still sending the report.
+// @Description An error after the first record can only cut the array short, it is logged.
+// @Description The answer may take up to ZOTA_REPORT_TIMEOUT plus SERVER_WRITE_TIMEOUT.
+// @Tags reports
+// @Produce json
+// @Param fromDate query string true "First day, as 2024-01-31 in UTC"
+// @Param toDate query string true "Last day, included"
+// @Param dateType query string false "created (default) or ended, the date of the orders the range applies to"
+// @Param types query string false "Comma separated order types, such as SALE,PAYOUT"
+// @Param statuses query string false "Comma separated order statuses"
+// @Param endpointIds query string false "Comma separated Zota endpoint IDs"
+// @Success 200 {array} shared.Record
+// @Security AdminToken
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Failure 502 {object} apperror.Response "Unexpected payment gateway response"
+// @Failure 503 {object} apperror.Response "Payment gateway unavailable"
+// @Router /reports/orders [get]
+func Handler(service ServiceInterface, writeTimeout time.Duration, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extendWriteDeadline(w, writeTimeout, logger)
+		ctx, span := tracing.Start(r.Context(), "report.Handler")
+		defer span.End()
+
+		var req shared.ClientRequest
+		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
+			apperror.Write(w, r, logger, apperror.InvalidRequest("invalid query parameters", err))
+			return
+		}
+
+		// the answer starts with the first record, so errors before it still get an error response
+		stream := &recordStream{w: w, encoder: json.NewEncoder(w)}
+		err := service.OrdersReport(ctx, &req, stream.write)
+		tracing.Record(span, err)
+		if err != nil && !stream.started {
+			apperror.Write(w, r, logger, err)
+			return
+		}
+		if err != nil {
+			logger.Error("Orders report was cut short", zap.Int("records", stream.count), zap.Error(err))
+			return
+		}
+		stream.end()
+	}
+}
+
+// ImportHandler
+// @Summary Import Zota's orders report
+// @Description stores the reported orders the local store does not know yet, as Zota reports them.
+// @Description Imported orders do not credit wallets or notify webhook subscribers.
+// @Description The answer may take up to ZOTA_REPORT_TIMEOUT plus SERVER_WRITE_TIMEOUT.
+// @Tags reports
+// @Produce json
+// @Param fromDate query string true "First day, as 2024-01-31 in UTC"
+// @Param toDate query string true "Last day, included"
+// @Param dateType query string false "created (default) or ended, the date of the orders the range applies to"
+// @Param types query string false "Comma separated order types, such as SALE,PAYOUT"
+// @Param statuses query string false "Comma separated order statuses"
+// @Param endpointIds query string false "Comma separated Zota endpoint IDs"
+// @Success 200 {object} shared.ImportSummary
+// @Security AdminToken
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Failure 502 {object} apperror.Response "Unexpected payment gateway response"
+// @Failure 503 {object} apperror.Response "Payment gateway unavailable"
+// @Router /reports/orders/import [post]
+func ImportHandler(service ServiceInterface, writeTimeout time.Duration, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extendWriteDeadline(w, writeTimeout, logger)
+		ctx, span := tracing.Start(r.Context(), "report.ImportHandler")
+		defer span.End()
+
+		var req shared.ClientRequest
+		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
+			apperror.Write(w, r, logger, apperror.InvalidRequest("invalid query parameters", err))
+			return
+		}
+
+		summary, err := service.Import(ctx, &req)
+		tracing.Record(span, err)
+		if err != nil {
+			apperror.Write(w, r, logger, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(summary); err != nil {
+			logger.Error("Failed to encode response", zap.Error(err))
+		}
+	}
+}
+
+// extendWriteDeadline lets the answer take writeTimeout from now, reading Zota's report may outlast the server's write timeout
+func extendWriteDeadline(w http.ResponseWriter, writeTimeout time.Duration, logger *zap.Logger) {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logger.Warn("Failed to extend the write deadline", zap.Error(err))
+	}
+}
+
+// recordStream writes the records as a JSON array while they arrive
+type recordStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	started bool
+	count   int
+}
+
+func (s *recordStream) write(record shared.Record) error {
+	separator := ","
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/json")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+		separator = "["
+	}
+	if _, err := s.w.Write([]byte(separator)); err != nil {
+		return err
+	}
+	if err := s.encoder.Encode(record); err != nil {
+		return err
+	}
+
+	s.count++
+	if flusher, ok := s.w.(http.Flusher); ok && s.count%flushEvery == 0 {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// end closes the array, an empty report is an empty array
+func (s *recordStream) end() {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/json")
+		s.w.WriteHeader(http.StatusOK)
+		s.w.Write([]byte("["))
+	}
+	s.w.Write([]byte("]\n"))
+}
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/report/shared"
+)
+
+type controllerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockService *MockServiceInterface
+	logger      *zap.Logger
+	request     shared.ClientRequest
+}
+
+func (s *controllerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockService = NewMockServiceInterface(s.mockCtrl)
+	s.logger, _ = zap.NewDevelopment()
+	s.request = shared.ClientRequest{FromDate: "2024-01-30", ToDate: "2024-01-31", Types: "SALE"}
+}
+
+func (s *controllerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *controllerTestSuite) expectReport(err error, records ...shared.Record) {
+	s.mockService.EXPECT().OrdersReport(gomock.Any(), &s.request, gomock.Any()).DoAndReturn(
+		func(_ any, _ *shared.ClientRequest, handle func(shared.Record) error) error {
+			for _, record := range records {
+				if err := handle(record); err != nil {
+					return err
+				}
+			}
+			return err
+		})
+}
+
+func (s *controllerTestSuite) serve(handler http.HandlerFunc, method string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, "/reports/orders?fromDate=2024-01-30&toDate=2024-01-31&types=SALE", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, request)
+	return rr
+}
+
+func TestHandler_StreamsRecords(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.expectReport(nil, shared.Record{OrderID: "order1"}, shared.Record{OrderID: "order2"})
+
+	rr := s.serve(Handler(s.mockService, time.Minute, s.logger), http.MethodGet)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var records []shared.Record
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&records))
+	require.Len(t, records, 2)
+	assert.Equal(t, "order2", records[1].OrderID)
+}
+
+func TestHandler_EmptyReport(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.expectReport(nil)
+
+	rr := s.serve(Handler(s.mockService, time.Minute, s.logger), http.MethodGet)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
+
+func TestHandler_ErrorBeforeTheFirstRecord(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.expectReport(apperror.GatewayUnavailable("payment gateway unavailable", errors.New("timeout")))
+
+	rr := s.serve(Handler(s.mockService, time.Minute, s.logger), http.MethodGet)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestHandler_ErrorAfterTheFirstRecord(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.expectReport(errors.New("connection reset"), shared.Record{OrderID: "order1"})
+
+	rr := s.serve(Handler(s.mockService, time.Minute, s.logger), http.MethodGet)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var records []shared.Record
+	assert.Error(t, json.Unmarshal(rr.Body.Bytes(), &records), "a cut short report must not be valid JSON")
+}
+
+func TestHandler_OutlastsTheServerWriteTimeout(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	// Zota takes longer to send the report than the server allows for writing an answer
+	s.mockService.EXPECT().OrdersReport(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ any, _ *shared.ClientRequest, handle func(shared.Record) error) error {
+			time.Sleep(200 * time.Millisecond)
+			return handle(shared.Record{OrderID: "order1"})
+		})
+
+	server := httptest.NewUnstartedServer(Handler(s.mockService, time.Second, s.logger))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/reports/orders?fromDate=2024-01-30&toDate=2024-01-31")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var records []shared.Record
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&records))
+	assert.Len(t, records, 1)
+}
+
+func TestImportHandler_Success(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	expected := &shared.ImportSummary{Imported: 2, Existing: 1, Failures: []shared.ImportFailure{}}
+	s.mockService.EXPECT().Import(gomock.Any(), &s.request).Return(expected, nil)
+
+	rr := s.serve(ImportHandler(s.mockService, time.Minute, s.logger), http.MethodPost)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var summary shared.ImportSummary
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&summary))
+	assert.Equal(t, *expected, summary)
+}
+
+func TestImportHandler_ValidationError(t *testing.T) {
+	s := &controllerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.mockService.EXPECT().Import(gomock.Any(), &s.request).Return(nil,
+		apperror.Validation("request validation failed", apperror.FieldError{Field: "toDate", Rule: "required", Message: "is required"}))
+
+	rr := s.serve(ImportHandler(s.mockService, time.Minute, s.logger), http.MethodPost)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
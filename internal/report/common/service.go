@@ -0,0 +1,193 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/report/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+type ServiceInterface interface {
+	// OrdersReport calls handle with every order Zota reports for the request
+	OrdersReport(ctx context.Context, req *shared.ClientRequest, handle func(record shared.Record) error) error
+	// Import stores the reported orders the local store does not know yet
+	Import(ctx context.Context, req *shared.ClientRequest) (*shared.ImportSummary, error)
+}
+
+type Service struct {
+	logger       *zap.Logger
+	config       *config.Config
+	reportClient shared.OrdersReportPaymentGateway
+	orders       orderShared.OrderRepository
+}
+
+func NewService(logger *zap.Logger, config *config.Config, reportClient shared.OrdersReportPaymentGateway,
+	orders orderShared.OrderRepository) *Service {
+	return &Service{logger: logger, config: config, reportClient: reportClient, orders: orders}
+}
+
+// customParam - the custom parameter the deposits are created with
+type customParam struct {
+	UserId string `json:"UserId"`
+}
+
+func (s *Service) OrdersReport(ctx context.Context, req *shared.ClientRequest, handle func(record shared.Record) error) error {
+	ctx, span := tracing.Start(ctx, "report.Service.OrdersReport")
+	err := s.ordersReport(ctx, req, handle)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) ordersReport(ctx context.Context, req *shared.ClientRequest, handle func(record shared.Record) error) error {
+	query, err := parseQuery(req)
+	if err != nil {
+		return err
+	}
+	// the rows that cannot be read are logged by the gateway, the stream carries the orders only
+	_, err = s.reportClient.OrdersReport(ctx, query, handle)
+	return err
+}
+
+func (s *Service) Import(ctx context.Context, req *shared.ClientRequest) (*shared.ImportSummary, error) {
+	ctx, span := tracing.Start(ctx, "report.Service.Import")
+	summary, err := s.importOrders(ctx, req)
+	tracing.End(span, err)
+	return summary, err
+}
+
+// importOrders backfills the orders of the report. They are stored as Zota reports them without going through
+// the state machine, so historic orders neither credit the wallets nor notify the webhook subscribers again.
+func (s *Service) importOrders(ctx context.Context, req *shared.ClientRequest) (*shared.ImportSummary, error) {
+	query, err := parseQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &shared.ImportSummary{Failures: []shared.ImportFailure{}}
+	invalid, err := s.reportClient.OrdersReport(ctx, query, func(record shared.Record) error {
+		_, err := s.orders.FindByMerchantOrderID(record.MerchantOrderID)
+		if err == nil {
+			summary.Existing++
+			return nil
+		}
+		if !errors.Is(err, orderShared.ErrOrderNotFound) {
+			return err
+		}
+
+		order, err := s.newOrder(record)
+		if err == nil {
+			err = s.orders.Create(order)
+		}
+		if err != nil {
+			s.logger.Warn("Failed to import order", zap.String("merchantOrderID", record.MerchantOrderID), zap.Error(err))
+			summary.Failed++
+			summary.Failures = append(summary.Failures, shared.ImportFailure{
+				MerchantOrderID: record.MerchantOrderID, OrderID: record.OrderID, Error: err.Error()})
+			return nil
+		}
+		summary.Imported++
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to import orders report", zap.Int("imported", summary.Imported), zap.Error(err))
+		return nil, err
+	}
+	for _, record := range invalid {
+		summary.Failed++
+		summary.Failures = append(summary.Failures, shared.ImportFailure{MerchantOrderID: record.MerchantOrderID,
+			OrderID: record.OrderID, Error: fmt.Sprintf("orders report line %d: %s", record.Line, record.Error)})
+	}
+
+	s.logger.Info("Imported orders report",
+		zap.Int("imported", summary.Imported),
+		zap.Int("existing", summary.Existing),
+		zap.Int("failed", summary.Failed))
+	return summary, nil
+}
+
+// newOrder maps a reported order to a local one, orders whose type or status the lifecycle does not know are rejected
+func (s *Service) newOrder(record shared.Record) (*orderShared.Order, error) {
+	if record.Type != orderShared.TypeSale && record.Type != orderShared.TypePayout {
+		return nil, fmt.Errorf("unknown order type %q", record.Type)
+	}
+	if !orderShared.IsKnownStatus(record.Status) {
+		return nil, fmt.Errorf("unknown order status %q", record.Status)
+	}
+
+	order := &orderShared.Order{
+		MerchantOrderID:       record.MerchantOrderID,
+		PaymentGatewayOrderID: record.OrderID,
+		Type:                  record.Type,
+		Amount:                record.Amount.String(),
+		Currency:              record.Currency,
+		Status:                record.Status,
+		ErrorMessage:          record.ErrorMessage,
+		Environment:           s.config.Environment,
+		CreatedAt:             record.CreatedAt,
+	}
+
+	// payouts and orders created elsewhere carry no user, they are imported without one
+	var param customParam
+	if record.CustomParam != "" && json.Unmarshal([]byte(record.CustomParam), &param) == nil {
+		order.UserID = param.UserId
+	}
+	return order, nil
+}
+
+// parseQuery validates the request, the dates are whole days such as 2024-01-31
+func parseQuery(req *shared.ClientRequest) (shared.Query, error) {
+	var fields []apperror.FieldError
+	date := func(field, value string) time.Time {
+		if value == "" {
+			fields = append(fields, apperror.FieldError{Field: field, Rule: "required", Message: "is required"})
+			return time.Time{}
+		}
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: field, Rule: "date", Message: "must be a date such as 2024-01-31"})
+		}
+		return parsed
+	}
+
+	query := shared.Query{
+		FromDate:    date("fromDate", req.FromDate),
+		ToDate:      date("toDate", req.ToDate),
+		DateType:    req.DateType,
+		Types:       list(req.Types),
+		Statuses:    list(req.Statuses),
+		EndpointIDs: list(req.EndpointIds),
+	}
+	if query.DateType == "" {
+		query.DateType = shared.DateTypeCreated
+	}
+	if query.DateType != shared.DateTypeCreated && query.DateType != shared.DateTypeEnded {
+		fields = append(fields, apperror.FieldError{Field: "dateType", Rule: "oneof", Message: "must be one of: created ended"})
+	}
+	if !query.FromDate.IsZero() && !query.ToDate.IsZero() && query.ToDate.Before(query.FromDate) {
+		fields = append(fields, apperror.FieldError{Field: "toDate", Rule: "gtefield", Message: "must not be before fromDate"})
+	}
+
+	if len(fields) > 0 {
+		return shared.Query{}, apperror.Validation("request validation failed", fields...)
+	}
+	return query, nil
+}
+
+// list splits a comma separated list, nil when it is empty
+func list(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
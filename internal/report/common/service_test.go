@@ -0,0 +1,195 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/money"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/report/common/zota"
+	"zota-dev-challenge/internal/report/shared"
+)
+
+type reportTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockGateway *zota.MockOrdersReportPaymentGateway
+	orders      *order.MemoryRepository
+	service     *Service
+	request     shared.ClientRequest
+}
+
+func (s *reportTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockGateway = zota.NewMockOrdersReportPaymentGateway(s.mockCtrl)
+	logger, _ := zap.NewDevelopment()
+
+	s.orders = order.NewMemoryRepository()
+	s.service = NewService(logger, &config.Config{Environment: config.EnvironmentSandbox}, s.mockGateway, s.orders)
+
+	s.request = shared.ClientRequest{FromDate: "2024-01-30", ToDate: "2024-01-31"}
+}
+
+func (s *reportTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *reportTestSuite) expectReport(invalid []shared.InvalidRecord, records ...shared.Record) {
+	s.mockGateway.EXPECT().OrdersReport(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, query shared.Query, handle func(shared.Record) error) ([]shared.InvalidRecord, error) {
+			for _, record := range records {
+				if err := handle(record); err != nil {
+					return nil, err
+				}
+			}
+			return invalid, nil
+		})
+}
+
+func testRecord(merchantOrderID, orderID, status string) shared.Record {
+	amount, _ := money.Parse("100.00", "USD")
+	return shared.Record{OrderID: orderID, MerchantOrderID: merchantOrderID, Type: orderShared.TypeSale,
+		Status: status, Amount: amount, Currency: "USD", CustomParam: `{"UserId":"user123"}`,
+		CreatedAt: time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC)}
+}
+
+func TestOrdersReport_PassesTheQuery(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.DateType = shared.DateTypeEnded
+	s.request.Statuses = "APPROVED, DECLINED"
+	s.mockGateway.EXPECT().OrdersReport(gomock.Any(), shared.Query{
+		FromDate: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC),
+		ToDate:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		DateType: shared.DateTypeEnded,
+		Statuses: []string{orderShared.StatusApproved, orderShared.StatusDeclined},
+	}, gomock.Any()).Return(nil, nil)
+
+	require.NoError(t, s.service.OrdersReport(context.Background(), &s.request, func(shared.Record) error { return nil }))
+}
+
+func TestOrdersReport_ValidatesTheQuery(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	tests := map[string]struct {
+		request shared.ClientRequest
+		field   string
+	}{
+		"missing fromDate":  {shared.ClientRequest{ToDate: "2024-01-31"}, "fromDate"},
+		"invalid toDate":    {shared.ClientRequest{FromDate: "2024-01-31", ToDate: "31/01/2024"}, "toDate"},
+		"reversed range":    {shared.ClientRequest{FromDate: "2024-01-31", ToDate: "2024-01-30"}, "toDate"},
+		"unknown date type": {shared.ClientRequest{FromDate: "2024-01-31", ToDate: "2024-01-31", DateType: "updated"}, "dateType"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := s.service.OrdersReport(context.Background(), &test.request, func(shared.Record) error { return nil })
+
+			var appErr *apperror.Error
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, apperror.CodeValidation, appErr.Code)
+			require.Len(t, appErr.Fields, 1)
+			assert.Equal(t, test.field, appErr.Fields[0].Field)
+		})
+	}
+}
+
+func TestImport_StoresUnknownOrders(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{MerchantOrderID: "known", PaymentGatewayOrderID: "order1",
+		Type: orderShared.TypeSale, Amount: "100.00", Currency: "USD", Status: orderShared.StatusPending}))
+	declined := testRecord("declined", "order2", orderShared.StatusDeclined)
+	declined.ErrorMessage = "insufficient funds"
+	s.expectReport(nil,
+		testRecord("known", "order1", orderShared.StatusApproved),
+		testRecord("approved", "order3", orderShared.StatusApproved),
+		declined,
+	)
+
+	summary, err := s.service.Import(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, &shared.ImportSummary{Imported: 2, Existing: 1, Failures: []shared.ImportFailure{}}, summary)
+
+	known, err := s.orders.FindByMerchantOrderID("known")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusPending, known.Status, "a known order is left to reconciliation")
+
+	approved, err := s.orders.FindByMerchantOrderID("approved")
+	require.NoError(t, err)
+	assert.Equal(t, "order3", approved.PaymentGatewayOrderID)
+	assert.Equal(t, orderShared.StatusApproved, approved.Status)
+	assert.Equal(t, "user123", approved.UserID)
+	assert.Equal(t, "100.00", approved.Amount)
+	assert.Equal(t, config.EnvironmentSandbox, approved.Environment)
+	assert.True(t, approved.CreatedAt.Equal(time.Date(2024, 1, 30, 10, 0, 0, 0, time.UTC)), "the order keeps Zota's creation time")
+
+	imported, err := s.orders.FindByMerchantOrderID("declined")
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient funds", imported.ErrorMessage)
+
+	transitions, err := s.orders.ListTransitions("approved")
+	require.NoError(t, err)
+	assert.Empty(t, transitions, "imported orders do not go through the state machine")
+}
+
+func TestImport_RejectsUnknownStatusesAndTypes(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	unknownStatus := testRecord("unknownStatus", "order1", "REVERSED")
+	unknownType := testRecord("unknownType", "order2", orderShared.StatusApproved)
+	unknownType.Type = "REFUND"
+	s.expectReport(nil, unknownStatus, unknownType, testRecord("approved", "order3", orderShared.StatusApproved))
+
+	summary, err := s.service.Import(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, &shared.ImportSummary{Imported: 1, Failed: 2, Failures: []shared.ImportFailure{
+		{MerchantOrderID: "unknownStatus", OrderID: "order1", Error: `unknown order status "REVERSED"`},
+		{MerchantOrderID: "unknownType", OrderID: "order2", Error: `unknown order type "REFUND"`},
+	}}, summary)
+
+	_, err = s.orders.FindByMerchantOrderID("unknownStatus")
+	assert.ErrorIs(t, err, orderShared.ErrOrderNotFound)
+}
+
+func TestImport_ReportsInvalidRows(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.expectReport([]shared.InvalidRecord{{Line: 3, MerchantOrderID: "unreadable", OrderID: "order2", Error: `invalid amount "1O0"`}},
+		testRecord("approved", "order1", orderShared.StatusApproved))
+
+	summary, err := s.service.Import(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, &shared.ImportSummary{Imported: 1, Failed: 1, Failures: []shared.ImportFailure{
+		{MerchantOrderID: "unreadable", OrderID: "order2", Error: `orders report line 3: invalid amount "1O0"`},
+	}}, summary)
+}
+
+func TestImport_Error(t *testing.T) {
+	s := &reportTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	expectedError := apperror.GatewayUnavailable("payment gateway unavailable", errors.New("connection refused"))
+	s.mockGateway.EXPECT().OrdersReport(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, expectedError)
+
+	summary, err := s.service.Import(context.Background(), &s.request)
+	assert.ErrorIs(t, err, expectedError)
+	assert.Nil(t, summary)
+}
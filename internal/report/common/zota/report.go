@@ -0,0 +1,294 @@
+package zota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/schema"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	"zota-dev-challenge/internal/report/shared"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+const OrdersReportApiPath = "api/v1/query/orders-report/csv"
+
+// report dates are whole days, the report times are written in UTC
+const (
+	reportDateLayout = "2006-01-02"
+	reportTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Columns of the orders report that are read, they are looked up by name so Zota may reorder or add columns
+const (
+	columnID                     = "ID"
+	columnType                   = "Type"
+	columnStatus                 = "Status"
+	columnErrorMessage           = "Error Message"
+	columnEndpointID             = "Endpoint ID"
+	columnProcessorTransactionID = "Processor Transaction ID"
+	columnMerchantOrderID        = "Merchant Order ID"
+	columnAmount                 = "Amount"
+	columnCurrency               = "Currency"
+	columnCustomerEmail          = "Customer Email"
+	columnCustomParam            = "Custom Param"
+	columnCreatedAt              = "Created At"
+	columnEndedAt                = "Ended At"
+)
+
+// requiredColumns - a report without them cannot be matched with the local orders
+var requiredColumns = []string{columnID, columnType, columnStatus, columnMerchantOrderID, columnAmount, columnCurrency, columnCreatedAt}
+
+type ReportRequest struct {
+	MerchantId  string `json:"merchantID" schema:"merchantID"`
+	DateType    string `json:"dateType" schema:"dateType"`
+	EndpointIds string `json:"endpointIds" schema:"endpointIds"`
+	FromDate    string `json:"fromDate" schema:"fromDate"`
+	RequestId   string `json:"requestID" schema:"requestID"`
+	Statuses    string `json:"statuses" schema:"statuses"`
+	Timestamp   string `json:"timestamp" schema:"timestamp"`
+	ToDate      string `json:"toDate" schema:"toDate"`
+	Types       string `json:"types" schema:"types"`
+	Signature   string `json:"signature" schema:"signature" log:"secret"`
+}
+
+type ReportGateway struct {
+	logger  *zap.Logger
+	config  *config.Config
+	client  *zotaapi.Client
+	metrics *metrics.Metrics
+}
+
+func NewReportGateway(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) *ReportGateway {
+	return &ReportGateway{logger: logger, config: config, client: client, metrics: metrics}
+}
+
+func (s *ReportGateway) OrdersReport(ctx context.Context, query shared.Query, handle func(record shared.Record) error) ([]shared.InvalidRecord, error) {
+	ctx, span := tracing.Start(ctx, "zota.ReportGateway.OrdersReport")
+	invalid, err := s.ordersReport(ctx, query, handle)
+	tracing.End(span, err)
+	return invalid, err
+}
+
+func (s *ReportGateway) ordersReport(ctx context.Context, query shared.Query, handle func(record shared.Record) error) ([]shared.InvalidRecord, error) {
+	s.logger.Info("Requesting orders report from Zota",
+		zap.String("fromDate", query.FromDate.Format(reportDateLayout)),
+		zap.String("toDate", query.ToDate.Format(reportDateLayout)),
+		zap.String("dateType", query.DateType))
+
+	reportReq := s.buildReportReq(query)
+	reportApiUrl, err := s.buildReportApiUrl(reportReq)
+	if err != nil {
+		s.logger.Error("Failed to build orders report API URL", zap.Error(err))
+		return nil, err
+	}
+
+	ctx, cancel := zotaapi.WithTimeout(ctx, s.config.ZotaReportTimeout)
+	defer cancel()
+
+	started := time.Now()
+	resp, err := s.sendReportRequest(ctx, reportApiUrl)
+	if err == nil && resp.Stream == nil {
+		// failures come in Zota's JSON error envelope, only the report itself is CSV
+		err = zotaapi.DecodeResponse(resp.StatusCode, resp.Body, &zotaapi.Envelope{})
+		s.logger.Error("Zota did not accept the orders report request", zap.Int("statusCode", resp.StatusCode), zap.Error(err))
+	}
+	if err != nil {
+		s.metrics.ObserveZotaCall(metrics.OperationReport, started, err)
+		return nil, err
+	}
+	defer resp.Stream.Close()
+
+	// the report is parsed while it arrives, so its size does not matter
+	count, invalid, err := parseReport(resp.Stream, handle)
+	s.metrics.ObserveZotaCall(metrics.OperationReport, started, err)
+	for _, record := range invalid {
+		s.logger.Warn("Skipped invalid orders report row",
+			zap.Int("line", record.Line),
+			zap.String("merchantOrderID", record.MerchantOrderID),
+			zap.String("orderID", record.OrderID),
+			zap.String("error", record.Error))
+	}
+	if err != nil {
+		s.logger.Error("Failed to read orders report", zap.Int("records", count), zap.Int("invalid", len(invalid)), zap.Error(err))
+		return invalid, err
+	}
+
+	s.logger.Info("Read orders report", zap.Int("records", count), zap.Int("invalid", len(invalid)))
+	return invalid, nil
+}
+
+func (s *ReportGateway) buildReportReq(query shared.Query) ReportRequest {
+	dateType := query.DateType
+	if dateType == "" {
+		dateType = shared.DateTypeCreated
+	}
+
+	req := ReportRequest{
+		MerchantId:  s.config.ZotaMerchantId,
+		DateType:    dateType,
+		EndpointIds: strings.Join(query.EndpointIDs, ","),
+		FromDate:    query.FromDate.UTC().Format(reportDateLayout),
+		RequestId:   uuid.New().String(),
+		Statuses:    strings.Join(query.Statuses, ","),
+		Timestamp:   strconv.FormatInt(time.Now().Unix(), 10),
+		ToDate:      query.ToDate.UTC().Format(reportDateLayout),
+		Types:       strings.Join(query.Types, ","),
+	}
+	req.Signature = s.buildSignature(req)
+	return req
+}
+
+// buildSignature signs the query parameters in alphabetical order of their names, as Zota expects for reports
+func (s *ReportGateway) buildSignature(req ReportRequest) string {
+	signatureString := fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s", req.MerchantId, req.DateType, req.EndpointIds, req.FromDate,
+		req.RequestId, req.Statuses, req.Timestamp, req.ToDate, req.Types, s.config.ZotaAPISecretKey)
+	hash := sha256.New()
+	hash.Write([]byte(signatureString))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (s *ReportGateway) buildReportApiUrl(reportReq ReportRequest) (string, error) {
+	encoder := schema.NewEncoder()
+
+	values := url.Values{}
+	if err := encoder.Encode(reportReq, values); err != nil {
+		return "", fmt.Errorf("failed to marshal request to query parameters: %w", err)
+	}
+
+	return fmt.Sprintf("%s/%s/?%s", s.config.ZotaBaseUrl, OrdersReportApiPath, values.Encode()), nil
+}
+
+func (s *ReportGateway) sendReportRequest(ctx context.Context, reportApiUrl string) (*zotaapi.Response, error) {
+	s.logger.Info("Sending orders report request to Zota server", zap.String("url", reportApiUrl))
+
+	ctx, span := tracing.StartClient(ctx, "zota.ReportGateway.sendReportRequest", http.MethodGet, reportApiUrl)
+	defer span.End()
+
+	// a report changes nothing at Zota, so the client may retry it
+	resp, err := s.client.Do(ctx, zotaapi.Request{Method: http.MethodGet, URL: reportApiUrl, Idempotent: true, Stream: true})
+	if err != nil {
+		s.logger.Error("Failed to send orders report request", zap.Error(err))
+		tracing.Record(span, err)
+		return nil, err
+	}
+	tracing.RecordResponse(span, resp.StatusCode)
+	return resp, nil
+}
+
+// parseReport reads the CSV report row by row, the columns are found by the names in the header.
+// It returns how many records were handled and the rows that could not be read, those are skipped.
+func parseReport(body io.Reader, handle func(record shared.Record) error) (int, []shared.InvalidRecord, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, nil, apperror.GatewayError("malformed payment gateway response", errors.New("orders report has no header"))
+	}
+	if err != nil {
+		return 0, nil, apperror.GatewayError("malformed payment gateway response", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return 0, nil, apperror.GatewayError("malformed payment gateway response",
+				fmt.Errorf("orders report has no %q column", name))
+		}
+	}
+
+	count := 0
+	var invalid []shared.InvalidRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return count, invalid, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// a row with a stray quote or the wrong number of fields, the reader carries on with the next one
+			invalid = append(invalid, shared.InvalidRecord{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return count, invalid, apperror.GatewayError("malformed payment gateway response", err)
+		}
+
+		record, err := parseRecord(row, columns)
+		if err != nil {
+			// the identifiers are read before the failing column, so the row can still be traced
+			line, _ := reader.FieldPos(0)
+			invalid = append(invalid, shared.InvalidRecord{Line: line, MerchantOrderID: record.MerchantOrderID,
+				OrderID: record.OrderID, Error: err.Error()})
+			continue
+		}
+		if err := handle(record); err != nil {
+			return count, invalid, err
+		}
+		count++
+	}
+}
+
+func parseRecord(row []string, columns map[string]int) (shared.Record, error) {
+	column := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	record := shared.Record{
+		OrderID:                column(columnID),
+		Type:                   column(columnType),
+		Status:                 column(columnStatus),
+		ErrorMessage:           column(columnErrorMessage),
+		EndpointID:             column(columnEndpointID),
+		ProcessorTransactionID: column(columnProcessorTransactionID),
+		MerchantOrderID:        column(columnMerchantOrderID),
+		Currency:               column(columnCurrency),
+		CustomerEmail:          column(columnCustomerEmail),
+		CustomParam:            column(columnCustomParam),
+	}
+
+	var err error
+	if record.Amount, err = money.Parse(column(columnAmount), record.Currency); err != nil {
+		return record, fmt.Errorf("invalid amount %q: %w", column(columnAmount), err)
+	}
+	if record.CreatedAt, err = parseTime(column(columnCreatedAt)); err != nil {
+		return record, err
+	}
+	if record.EndedAt, err = parseTime(column(columnEndedAt)); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// parseTime reads a report time, an empty one is the zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	at, err := time.ParseInLocation(reportTimeLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return at, nil
+}
@@ -0,0 +1,196 @@
+package zota
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/report/shared"
+	"zota-dev-challenge/internal/zotaapi"
+	"zota-dev-challenge/internal/zotasim"
+)
+
+const testReport = `ID,Type,Status,Error Message,Endpoint ID,Processor Transaction ID,Merchant Order ID,Amount,Currency,Customer Email,Custom Param,Created At,Ended At
+order1,SALE,APPROVED,,1050,proc1,merchantOrder1,100.00,USD,test@example.com,,2024-01-31 10:00:00,2024-01-31 10:05:00
+order2,SALE,PENDING,,1050,,merchantOrder2,1500,JPY,test@example.com,,2024-01-31 11:00:00,
+`
+
+type reportGatewayTestSuite struct {
+	config        *config.Config
+	reportGateway *ReportGateway
+	query         shared.Query
+}
+
+func (s *reportGatewayTestSuite) setup(t *testing.T, handler http.HandlerFunc) {
+	logger, _ := zap.NewDevelopment()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	s.config = &config.Config{
+		ZotaBaseUrl:       server.URL,
+		ZotaMerchantId:    "merchant123",
+		ZotaAPISecretKey:  "secret123",
+		ZotaReportTimeout: time.Second,
+	}
+	s.reportGateway = NewReportGateway(logger, s.config, zotaapi.NewClient(logger, s.config), metrics.New())
+	s.query = shared.Query{
+		FromDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ToDate:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Types:    []string{"SALE", "PAYOUT"},
+	}
+}
+
+func (s *reportGatewayTestSuite) collect(t *testing.T) ([]shared.Record, []shared.InvalidRecord, error) {
+	var records []shared.Record
+	invalid, err := s.reportGateway.OrdersReport(context.Background(), s.query, func(record shared.Record) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, invalid, err
+}
+
+func TestOrdersReport_Success(t *testing.T) {
+	s := &reportGatewayTestSuite{}
+	s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query/orders-report/csv/", r.URL.Path)
+
+		query := r.URL.Query()
+		assert.Equal(t, "merchant123", query.Get("merchantID"))
+		assert.Equal(t, "created", query.Get("dateType"))
+		assert.Equal(t, "2024-01-01", query.Get("fromDate"))
+		assert.Equal(t, "2024-01-31", query.Get("toDate"))
+		assert.Equal(t, "SALE,PAYOUT", query.Get("types"))
+		assert.NotEmpty(t, query.Get("requestID"))
+		assert.Equal(t, zotasim.Sign("secret123", query.Get("merchantID"), query.Get("dateType"), query.Get("endpointIds"),
+			query.Get("fromDate"), query.Get("requestID"), query.Get("statuses"), query.Get("timestamp"),
+			query.Get("toDate"), query.Get("types")), query.Get("signature"))
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(testReport))
+	})
+
+	records, invalid, err := s.collect(t)
+	require.NoError(t, err)
+	assert.Empty(t, invalid)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "order1", records[0].OrderID)
+	assert.Equal(t, "merchantOrder1", records[0].MerchantOrderID)
+	assert.Equal(t, "APPROVED", records[0].Status)
+	assert.Equal(t, "100.00", records[0].Amount.String())
+	assert.Equal(t, "USD", records[0].Currency)
+	assert.Equal(t, time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), records[0].CreatedAt)
+	assert.Equal(t, time.Date(2024, 1, 31, 10, 5, 0, 0, time.UTC), records[0].EndedAt)
+
+	assert.Equal(t, "1500", records[1].Amount.String())
+	assert.True(t, records[1].EndedAt.IsZero(), "pending orders have not ended")
+}
+
+func TestOrdersReport_ColumnsAreFoundByName(t *testing.T) {
+	s := &reportGatewayTestSuite{}
+	s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Merchant Order ID,Currency,Amount,Status,Type,ID,Created At,Extra\n" +
+			"merchantOrder1,EUR,10.50,DECLINED,SALE,order1,2024-01-31 10:00:00,ignored\n"))
+	})
+
+	records, _, err := s.collect(t)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "merchantOrder1", records[0].MerchantOrderID)
+	assert.Equal(t, "10.50", records[0].Amount.String())
+	assert.Equal(t, "EUR", records[0].Currency)
+	assert.Equal(t, "DECLINED", records[0].Status)
+}
+
+func TestOrdersReport_MalformedReport(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty":          "",
+		"missing column": "ID,Type,Status\norder1,SALE,APPROVED\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &reportGatewayTestSuite{}
+			s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			})
+
+			_, _, err := s.collect(t)
+			assert.Equal(t, apperror.CodeGatewayError, apperror.From(err).Code)
+		})
+	}
+}
+
+func TestOrdersReport_InvalidRowsAreSkipped(t *testing.T) {
+	for name, test := range map[string]struct {
+		body    string
+		invalid shared.InvalidRecord
+	}{
+		"invalid amount": {
+			body:    strings.Replace(testReport, "100.00", "1O0", 1),
+			invalid: shared.InvalidRecord{Line: 2, MerchantOrderID: "merchantOrder1", OrderID: "order1"},
+		},
+		"invalid time": {
+			body:    strings.Replace(testReport, "2024-01-31 10:00:00", "yesterday", 1),
+			invalid: shared.InvalidRecord{Line: 2, MerchantOrderID: "merchantOrder1", OrderID: "order1"},
+		},
+		"missing field": {
+			body:    strings.Replace(testReport, ",proc1,", ",", 1),
+			invalid: shared.InvalidRecord{Line: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &reportGatewayTestSuite{}
+			s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(test.body))
+			})
+
+			records, invalid, err := s.collect(t)
+			require.NoError(t, err)
+			require.Len(t, records, 1, "the rows after the invalid one are still read")
+			assert.Equal(t, "merchantOrder2", records[0].MerchantOrderID)
+
+			require.Len(t, invalid, 1)
+			assert.NotEmpty(t, invalid[0].Error)
+			invalid[0].Error = ""
+			assert.Equal(t, test.invalid, invalid[0])
+		})
+	}
+}
+
+func TestOrdersReport_ErrorEnvelope(t *testing.T) {
+	s := &reportGatewayTestSuite{}
+	s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"401","message":"invalid signature"}`))
+	})
+
+	records, _, err := s.collect(t)
+	assert.Empty(t, records)
+	require.Error(t, err)
+	require.NotNil(t, apperror.From(err).Gateway)
+	assert.Equal(t, "invalid signature", apperror.From(err).Gateway.Message)
+}
+
+func TestOrdersReport_HandlerErrorStopsTheReport(t *testing.T) {
+	s := &reportGatewayTestSuite{}
+	s.setup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testReport))
+	})
+
+	stop := errors.New("stop")
+	calls := 0
+	_, err := s.reportGateway.OrdersReport(context.Background(), s.query, func(record shared.Record) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"context"
+	"time"
+	"zota-dev-challenge/internal/money"
+)
+
+// Date types of a report query, the date of the orders the range applies to
+const (
+	DateTypeCreated = "created"
+	DateTypeEnded   = "ended"
+)
+
+// Query - the orders a report covers, the dates are whole days in UTC and both are included
+type Query struct {
+	FromDate    time.Time
+	ToDate      time.Time
+	DateType    string
+	Types       []string
+	Statuses    []string
+	EndpointIDs []string
+}
+
+// ClientRequest - the report query as the API and the command line take it, the lists are comma separated
+type ClientRequest struct {
+	FromDate    string `json:"fromDate" schema:"fromDate"`
+	ToDate      string `json:"toDate" schema:"toDate"`
+	DateType    string `json:"dateType" schema:"dateType"`
+	Types       string `json:"types" schema:"types"`
+	Statuses    string `json:"statuses" schema:"statuses"`
+	EndpointIds string `json:"endpointIds" schema:"endpointIds"`
+}
+
+// Record - an order as Zota reports it
+type Record struct {
+	OrderID                string       `json:"orderId"`
+	Type                   string       `json:"type"`
+	Status                 string       `json:"status"`
+	ErrorMessage           string       `json:"errorMessage,omitempty"`
+	EndpointID             string       `json:"endpointId"`
+	ProcessorTransactionID string       `json:"processorTransactionId"`
+	MerchantOrderID        string       `json:"merchantOrderId"`
+	Amount                 money.Amount `json:"amount" swaggertype:"string"`
+	Currency               string       `json:"currency"`
+	CustomerEmail          string       `json:"-" log:"email"` // customer data stays out of the report answers
+	CustomParam            string       `json:"customParam"`
+	CreatedAt              time.Time    `json:"createdAt"`
+	EndedAt                time.Time    `json:"endedAt"` // zero while the order is not final
+} //@name ReportRecord
+
+// ImportSummary - the outcome of importing the orders of a report into the local store
+type ImportSummary struct {
+	Imported int `json:"imported"`
+	// Existing - orders the local store already knew, they are left unchanged
+	Existing int             `json:"existing"`
+	Failed   int             `json:"failed"`
+	Failures []ImportFailure `json:"failures"`
+} //@name ImportSummary
+
+type ImportFailure struct {
+	MerchantOrderID string `json:"merchantOrderId"`
+	OrderID         string `json:"orderId"`
+	Error           string `json:"error"`
+} //@name ImportFailure
+
+// InvalidRecord - a row of the report that could not be read, it is skipped instead of failing the report
+type InvalidRecord struct {
+	// Line - the line of the row in the CSV report, the header is line 1
+	Line            int
+	MerchantOrderID string
+	OrderID         string
+	Error           string
+}
+
+type OrdersReportPaymentGateway interface {
+	// OrdersReport calls handle with every order of the report in the order Zota lists them,
+	// an error returned by handle stops the report and is returned.
+	// Rows that cannot be read are skipped and returned, only a report without the expected header fails.
+	OrdersReport(ctx context.Context, query Query, handle func(record Record) error) ([]InvalidRecord, error)
+}
@@ -2,26 +2,83 @@ package internal
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 	"net/http"
+	"net/url"
+	"path"
+	"zota-dev-challenge/docs"
+	"zota-dev-challenge/internal/admin"
+	callback "zota-dev-challenge/internal/callback/common"
+	"zota-dev-challenge/internal/config"
 	deposit "zota-dev-challenge/internal/deposit/common"
+	"zota-dev-challenge/internal/health"
+	"zota-dev-challenge/internal/idempotency"
+	ledger "zota-dev-challenge/internal/ledger/common"
+	"zota-dev-challenge/internal/metrics"
+	payout "zota-dev-challenge/internal/payout/common"
+	report "zota-dev-challenge/internal/report/common"
 	status "zota-dev-challenge/internal/status/common"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/webhook"
 )
 
-func InitRouterV1(depositService *deposit.Service, statusService *status.Service, validator *validator.Validate, logger *zap.Logger) *chi.Mux {
+func InitRouterV1(depositService *deposit.Service, statusService *status.Service, payoutService *payout.Service,
+	callbackService *callback.Service, ledgerService *ledger.Service, reportService *report.Service, idempotencyStore idempotency.Store, webhookStore webhook.Store,
+	dispatcher *webhook.Dispatcher, checker *health.Checker, appMetrics *metrics.Metrics, config *config.Config, validator *validator.Validate, logger *zap.Logger) *chi.Mux {
 	r := chi.NewRouter()
+	// the request ID is echoed in error responses, so clients can refer to a failed call
+	r.Use(middleware.RequestID)
+	r.Use(metrics.Middleware(appMetrics))
+	r.Use(tracing.Middleware)
 
-	r.Post("/api/v1/deposit", deposit.Handler(depositService, logger, validator))
+	// probed by orchestrators and load balancers, outside of the versioned API
+	r.Get("/healthz", health.LivenessHandler(logger))
+	r.Get("/readyz", health.ReadinessHandler(checker, logger))
+	r.Method(http.MethodGet, "/metrics", metrics.Handler(appMetrics))
+
+	// a retried deposit must not open a second payment page, so repeats are answered with the first response
+	r.With(idempotency.Middleware(idempotencyStore, config.IdempotencyTTL, logger)).
+		Post("/api/v1/deposit", deposit.Handler(depositService, logger, validator))
 	r.Get("/api/v1/status", status.Handler(statusService, logger))
+	r.Post("/api/v1/payout", payout.Handler(payoutService, logger, validator))
+	r.Post("/api/v1/callback/deposit", callback.Handler(callbackService, logger))
+	// payout callbacks share the deposit callback payload and signature
+	r.Post("/api/v1/callback/payout", callback.Handler(callbackService, logger))
+	// the admin routes expose every user's money and orders and change the local store, only holders of the admin token reach them
+	r.Group(func(r chi.Router) {
+		r.Use(admin.Middleware(config.AdminAPIToken, logger))
+		r.Get("/api/v1/users/{userId}/balances", ledger.BalanceHandler(ledgerService, logger))
+		r.Get("/api/v1/users/{userId}/transactions", ledger.HistoryHandler(ledgerService, logger))
+		// reading Zota's report is bounded by its own timeout, the answer still gets the usual time to be written after it
+		reportWriteTimeout := config.ZotaReportTimeout + config.ServerWriteTimeout
+		r.Get("/api/v1/reports/orders", report.Handler(reportService, reportWriteTimeout, logger))
+		r.Post("/api/v1/reports/orders/import", report.ImportHandler(reportService, reportWriteTimeout, logger))
+		r.Get("/api/v1/webhooks/deliveries", webhook.ListHandler(webhookStore, logger))
+		r.Get("/api/v1/webhooks/deliveries/{id}", webhook.DeliveryHandler(webhookStore, logger))
+		r.Post("/api/v1/webhooks/deliveries/{id}/redeliver", webhook.RedeliverHandler(dispatcher, logger))
+	})
+
+	initSwagger(r, config)
+
+	return r
+}
+
+// initSwagger serves the API documentation with the public URL, so it also works behind a proxy or on another port
+func initSwagger(r chi.Router, config *config.Config) {
+	if publicURL, err := url.Parse(config.PublicBaseURL); err == nil {
+		docs.SwaggerInfo.Host = publicURL.Host
+		docs.SwaggerInfo.BasePath = path.Join("/", publicURL.Path, "api/v1")
+		docs.SwaggerInfo.Schemes = []string{publicURL.Scheme}
+	}
 
 	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
+		httpSwagger.URL(config.PublicBaseURL+"/swagger/doc.json"),
 	))
 	r.Get("/swagger/doc.json", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./docs/swagger.json")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(docs.SwaggerInfo.ReadDoc()))
 	})
-
-	return r
 }
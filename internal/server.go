@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"os"
+	"zota-dev-challenge/internal/config"
+)
+
+// InitServer builds the HTTP server from the configuration, TLS material is loaded here so a bad file fails the startup
+func InitServer(config *config.Config, router *chi.Mux) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              config.ListenAddr,
+		Handler:           router,
+		ReadTimeout:       config.ServerReadTimeout,
+		ReadHeaderTimeout: config.ServerHeaderTimeout,
+		WriteTimeout:      config.ServerWriteTimeout,
+		IdleTimeout:       config.ServerIdleTimeout,
+		MaxHeaderBytes:    config.ServerMaxHeaderBytes,
+	}
+
+	if !config.TLSEnabled() {
+		return server, nil
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = tlsConfig
+	return server, nil
+}
+
+func newTLSConfig(config *config.Config) (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	// with a client CA every caller has to present a certificate signed by it
+	if config.TLSClientCAFile != "" {
+		caPEM, err := os.ReadFile(config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS client CA: %w", err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in TLS client CA %s", config.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/config"
+)
+
+// writeCertificate creates a self-signed certificate and its key in dir
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestInitServer_Timeouts(t *testing.T) {
+	cfg := &config.Config{
+		ListenAddr:           "127.0.0.1:9090",
+		ServerReadTimeout:    time.Second,
+		ServerHeaderTimeout:  2 * time.Second,
+		ServerWriteTimeout:   3 * time.Second,
+		ServerIdleTimeout:    4 * time.Second,
+		ServerMaxHeaderBytes: 1024,
+	}
+
+	server, err := InitServer(cfg, chi.NewRouter())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9090", server.Addr)
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Equal(t, 2*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 3*time.Second, server.WriteTimeout)
+	assert.Equal(t, 4*time.Second, server.IdleTimeout)
+	assert.Equal(t, 1024, server.MaxHeaderBytes)
+	assert.Nil(t, server.TLSConfig)
+}
+
+func TestInitServer_MutualTLS(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+	cfg := &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: certFile}
+
+	server, err := InitServer(cfg, chi.NewRouter())
+	require.NoError(t, err)
+	require.NotNil(t, server.TLSConfig)
+	assert.Len(t, server.TLSConfig.Certificates, 1)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, server.TLSConfig.ClientAuth)
+	assert.NotNil(t, server.TLSConfig.ClientCAs)
+}
+
+func TestInitServer_BadCertificateFailsStartup(t *testing.T) {
+	cfg := &config.Config{TLSCertFile: "missing.pem", TLSKeyFile: "missing.key"}
+
+	_, err := InitServer(cfg, chi.NewRouter())
+	assert.ErrorContains(t, err, "load TLS certificate")
+}
+
+func TestInitSwagger_UsesPublicBaseURL(t *testing.T) {
+	r := chi.NewRouter()
+	initSwagger(r, &config.Config{PublicBaseURL: "https://pay.example.com/merchant"})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/swagger/doc.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var doc struct {
+		Host     string   `json:"host"`
+		BasePath string   `json:"basePath"`
+		Schemes  []string `json:"schemes"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&doc))
+	assert.Equal(t, "pay.example.com", doc.Host)
+	assert.Equal(t, "/merchant/api/v1", doc.BasePath)
+	assert.Equal(t, []string{"https"}, doc.Schemes)
+}
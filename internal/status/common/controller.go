@@ -5,9 +5,9 @@ import (
 	"github.com/gorilla/schema"
 	"go.uber.org/zap"
 	"net/http"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/status/shared"
-
-	_ "zota-dev-challenge/internal/status/common/zota"
+	"zota-dev-challenge/internal/tracing"
 )
 
 var decoder = schema.NewDecoder()
@@ -20,24 +20,33 @@ var decoder = schema.NewDecoder()
 // @Tags status check
 // @Accept json
 // @Produce json
-// @Param orderId query string true "Order ID"
+// @Param orderId query string false "Order ID, looked up in the order store when omitted"
 // @Param merchantOrderId query string true "Merchant Order ID"
-// @Success 200 {object} _.StatusResponse "Status Check successful"
+// @Success 200 {object} shared.Response "Status Check successful"
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 404 {object} apperror.Response "Order not found"
+// @Failure 409 {object} apperror.Response "Order belongs to the other Zota environment"
+// @Failure 502 {object} apperror.Response "Unexpected payment gateway response"
+// @Failure 503 {object} apperror.Response "Payment gateway unavailable"
 // @Router /status [get]
 func Handler(service ServiceInterface, logger *zap.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "status.Handler")
+		defer span.End()
+
 		var req shared.ClientRequest
 		// Decode request params - it's get request
 		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
 			logger.Error("Failed to decode request", zap.Error(err))
-			http.Error(w, "Failed to decode request", http.StatusBadRequest)
+			apperror.Write(w, r, logger, apperror.InvalidRequest("invalid query parameters", err))
 			return
 		}
 
-		res, err := service.CheckStatus(&req)
+		res, err := service.CheckStatus(ctx, &req)
+		tracing.Record(span, err)
 		if err != nil {
 			logger.Error("Failed to check status", zap.Error(err))
-			http.Error(w, "Failed to check status", http.StatusInternalServerError)
+			apperror.Write(w, r, logger, err)
 			return
 		}
 
@@ -45,7 +45,7 @@ func TestHandler_Success(t *testing.T) {
 		Status: "Success",
 	}
 
-	s.mockService.EXPECT().CheckStatus(&s.request).Return(expectedResponse, nil)
+	s.mockService.EXPECT().CheckStatus(gomock.Any(), &s.request).Return(expectedResponse, nil)
 
 	request, err := http.NewRequest("GET", "/status?orderId=order123&merchantOrderId=merchantOrder123", nil)
 	require.NoError(t, err)
@@ -84,7 +84,7 @@ func TestHandler_CheckStatusError(t *testing.T) {
 
 	expectedError := errors.New("status check error")
 
-	s.mockService.EXPECT().CheckStatus(&s.request).Return(nil, expectedError)
+	s.mockService.EXPECT().CheckStatus(gomock.Any(), &s.request).Return(nil, expectedError)
 
 	request, err := http.NewRequest("GET", "/status?orderId=order123&merchantOrderId=merchantOrder123", nil)
 	require.NoError(t, err)
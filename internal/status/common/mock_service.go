@@ -1,10 +1,11 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: internal/status/common/service.go
 
-// Package status is a generated GoMock package.
+// Package common is a generated GoMock package.
 package common
 
 import (
+	context "context"
 	reflect "reflect"
 	shared "zota-dev-challenge/internal/status/shared"
 
@@ -35,16 +36,16 @@ func (m *MockServiceInterface) EXPECT() *MockServiceInterfaceMockRecorder {
 }
 
 // CheckStatus mocks base method.
-func (m *MockServiceInterface) CheckStatus(req *shared.ClientRequest) (*shared.Response, error) {
+func (m *MockServiceInterface) CheckStatus(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckStatus", req)
+	ret := m.ctrl.Call(m, "CheckStatus", ctx, req)
 	ret0, _ := ret[0].(*shared.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckStatus indicates an expected call of CheckStatus.
-func (mr *MockServiceInterfaceMockRecorder) CheckStatus(req interface{}) *gomock.Call {
+func (mr *MockServiceInterfaceMockRecorder) CheckStatus(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckStatus", reflect.TypeOf((*MockServiceInterface)(nil).CheckStatus), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckStatus", reflect.TypeOf((*MockServiceInterface)(nil).CheckStatus), ctx, req)
 }
@@ -0,0 +1,234 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	orderCommon "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+// backoffDivisor - an order is checked again after a tenth of its age, bounded by the poll interval and the max back-off
+const backoffDivisor = 10
+
+// Poller - resolves orders whose final status never reached us by periodically checking them with the payment gateway
+type Poller struct {
+	logger       *zap.Logger
+	config       *config.Config
+	statusClient shared.StatusPaymentGateway
+	orders       orderShared.OrderRepository
+	stateMachine *orderCommon.StateMachine
+	metrics      *metrics.Metrics
+
+	mu          sync.Mutex
+	lastChecked map[string]time.Time
+
+	// a backlog larger than a batch is paged through oldest first, one batch per round
+	cursor orderShared.Cursor
+	passed map[string]struct{} // the orders listed since the pass through the backlog started
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	now    func() time.Time
+}
+
+func NewPoller(logger *zap.Logger, config *config.Config, statusClient shared.StatusPaymentGateway,
+	orders orderShared.OrderRepository, stateMachine *orderCommon.StateMachine, metrics *metrics.Metrics) *Poller {
+	return &Poller{
+		logger:       logger,
+		config:       config,
+		statusClient: statusClient,
+		orders:       orders,
+		stateMachine: stateMachine,
+		metrics:      metrics,
+		lastChecked:  make(map[string]time.Time),
+		passed:       make(map[string]struct{}),
+		now:          time.Now,
+	}
+}
+
+// Start runs the poller in the background until Stop is called
+func (p *Poller) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.config.PollerInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Poll(ctx)
+			}
+		}
+	}()
+}
+
+// Stop cancels the status checks in flight and waits for the round to wind down or for the context to expire
+func (p *Poller) Stop(ctx context.Context) error {
+	p.cancel()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Poll checks the non-final orders of the next batch that are due, with at most PollerConcurrency checks in flight
+func (p *Poller) Poll(ctx context.Context) {
+	orders, err := p.orders.ListNonFinal(p.cursor, p.config.PollerBatchSize)
+	if err != nil {
+		p.logger.Error("Failed to list pending orders", zap.Error(err))
+		return
+	}
+	p.advance(orders)
+
+	now := p.now()
+	sem := make(chan struct{}, p.config.PollerConcurrency)
+	var wg sync.WaitGroup
+
+	for _, order := range orders {
+		if !p.isDue(order, now) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			// shutting down, leave the rest for the next run
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(order orderShared.Order) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.check(ctx, order)
+		}(order)
+	}
+
+	wg.Wait()
+}
+
+// advance moves the cursor past the batch. A short batch ends the pass through the backlog,
+// the next round starts again with the oldest order.
+func (p *Poller) advance(orders []orderShared.Order) {
+	for _, order := range orders {
+		p.passed[order.MerchantOrderID] = struct{}{}
+	}
+	if len(orders) >= p.config.PollerBatchSize {
+		p.cursor = orders[len(orders)-1].Cursor()
+		return
+	}
+
+	p.metrics.SetPendingOrders(len(p.passed))
+	p.forgetResolved(p.passed)
+	p.cursor = orderShared.Cursor{}
+	p.passed = make(map[string]struct{})
+}
+
+// forgetResolved drops the check times of orders that were resolved elsewhere, e.g. by a callback
+func (p *Poller) forgetResolved(pending map[string]struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for merchantOrderID := range p.lastChecked {
+		if _, ok := pending[merchantOrderID]; !ok {
+			delete(p.lastChecked, merchantOrderID)
+		}
+	}
+}
+
+func (p *Poller) isDue(order orderShared.Order, now time.Time) bool {
+	// orders of the other environment are unknown to the Zota we talk to
+	if !order.InEnvironment(p.config.Environment) {
+		return false
+	}
+
+	p.mu.Lock()
+	lastChecked, ok := p.lastChecked[order.MerchantOrderID]
+	p.mu.Unlock()
+	if !ok {
+		return true
+	}
+
+	backoff := now.Sub(order.CreatedAt) / backoffDivisor
+	if backoff < p.config.PollerInterval {
+		backoff = p.config.PollerInterval
+	}
+	if backoff > p.config.PollerMaxBackoff {
+		backoff = p.config.PollerMaxBackoff
+	}
+	return now.Sub(lastChecked) >= backoff
+}
+
+func (p *Poller) check(ctx context.Context, order orderShared.Order) {
+	// every check is a trace of its own, the round has no caller to attach it to
+	ctx, span := tracing.Start(ctx, "status.Poller.check",
+		tracing.AttrMerchantOrderID.String(order.MerchantOrderID), tracing.AttrZotaOrderID.String(order.PaymentGatewayOrderID),
+		tracing.AttrCurrency.String(order.Currency))
+	defer span.End()
+
+	p.mu.Lock()
+	p.lastChecked[order.MerchantOrderID] = p.now()
+	p.mu.Unlock()
+
+	if order.PaymentGatewayOrderID == "" {
+		p.logger.Warn("Looking up order with a lost outcome by merchant order ID", zap.String("merchantOrderID", order.MerchantOrderID))
+	}
+
+	res, err := p.statusClient.CheckStatus(ctx, shared.Request{
+		ClientRequest: shared.ClientRequest{
+			OrderId:         order.PaymentGatewayOrderID,
+			MerchantOrderId: order.MerchantOrderID,
+		},
+	})
+	tracing.Record(span, err)
+	if err != nil {
+		p.logger.Error("Failed to poll order status", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+		return
+	}
+	span.SetAttributes(tracing.AttrStatus.String(res.Status))
+
+	if order.PaymentGatewayOrderID == "" && res.ClientRequest.OrderId != "" {
+		p.recordOrderID(order.MerchantOrderID, res.ClientRequest.OrderId)
+	}
+
+	updated, err := p.stateMachine.Settle(order.MerchantOrderID, res.Status, orderShared.SourceStatusPoll, "", res.Amount.String())
+	if err != nil && !errors.Is(err, orderShared.ErrIllegalTransition) {
+		p.logger.Error("Failed to update polled order", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+		return
+	}
+
+	if updated != nil && orderShared.IsFinal(updated.Status) {
+		p.mu.Lock()
+		delete(p.lastChecked, order.MerchantOrderID)
+		p.mu.Unlock()
+	}
+}
+
+// recordOrderID stores the Zota order ID of an order whose deposit or payout answer was lost
+func (p *Poller) recordOrderID(merchantOrderID, orderID string) {
+	order, err := p.orders.FindByMerchantOrderID(merchantOrderID)
+	if err == nil {
+		order.PaymentGatewayOrderID = orderID
+		err = p.orders.Update(order)
+	}
+	if err != nil {
+		p.logger.Error("Failed to record Zota order ID", zap.String("merchantOrderID", merchantOrderID), zap.Error(err))
+	}
+}
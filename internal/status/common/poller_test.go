@@ -0,0 +1,242 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/status/common/zota"
+	"zota-dev-challenge/internal/status/shared"
+)
+
+type pollerTestSuite struct {
+	mockCtrl    *gomock.Controller
+	mockGateway *zota.MockStatusPaymentGateway
+	orders      *order.MemoryRepository
+	poller      *Poller
+	now         time.Time
+}
+
+func (s *pollerTestSuite) setup(t *testing.T) {
+	s.mockCtrl = gomock.NewController(t)
+	s.mockGateway = zota.NewMockStatusPaymentGateway(s.mockCtrl)
+	logger, _ := zap.NewDevelopment()
+
+	cfg := &config.Config{
+		PollerInterval:    time.Minute,
+		PollerMaxBackoff:  time.Hour,
+		PollerConcurrency: 2,
+		PollerBatchSize:   100,
+	}
+
+	s.orders = order.NewMemoryRepository()
+	s.poller = NewPoller(logger, cfg, s.mockGateway, s.orders, order.NewStateMachine(logger, s.orders, metrics.New()),
+		metrics.New())
+	s.now = time.Now()
+	s.poller.now = func() time.Time { return s.now }
+}
+
+func (s *pollerTestSuite) teardown() {
+	s.mockCtrl.Finish()
+}
+
+func (s *pollerTestSuite) createOrder(t *testing.T, merchantOrderID, paymentGatewayOrderID, status string) {
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       merchantOrderID,
+		PaymentGatewayOrderID: paymentGatewayOrderID,
+		Status:                status,
+	}))
+}
+
+func TestPoll_ResolvesPendingOrders(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "pending", "gateway1", orderShared.StatusPending)
+	s.createOrder(t, "approved", "gateway2", orderShared.StatusApproved)
+	s.createOrder(t, "notSent", "", orderShared.StatusCreated)
+
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
+		ClientRequest: shared.ClientRequest{OrderId: "gateway1", MerchantOrderId: "pending"},
+	}).Return(&shared.Response{Status: orderShared.StatusDeclined}, nil)
+
+	s.poller.Poll(context.Background())
+
+	stored, err := s.orders.FindByMerchantOrderID("pending")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusDeclined, stored.Status)
+
+	transitions, err := s.orders.ListTransitions("pending")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, orderShared.SourceStatusPoll, transitions[0].Source)
+}
+
+func TestPoll_PagesThroughBacklogLargerThanBatch(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+	s.poller.config.PollerBatchSize = 2
+
+	// orders that never reached Zota cannot be checked, they must not take up the batches
+	for i := 1; i <= 3; i++ {
+		s.createOrder(t, fmt.Sprintf("notSent%d", i), "", orderShared.StatusCreated)
+	}
+	for i := 1; i <= 5; i++ {
+		s.createOrder(t, fmt.Sprintf("pending%d", i), fmt.Sprintf("gateway%d", i), orderShared.StatusPending)
+	}
+
+	var mu sync.Mutex
+	var checked []string
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req shared.Request) (*shared.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			checked = append(checked, req.MerchantOrderId)
+			return &shared.Response{Status: orderShared.StatusPending}, nil
+		}).Times(5)
+
+	// a batch per round, the last one is short and ends the pass
+	for round := 0; round < 3; round++ {
+		s.poller.Poll(context.Background())
+	}
+	assert.ElementsMatch(t, []string{"pending1", "pending2", "pending3", "pending4", "pending5"}, checked,
+		"every pending order is checked once per pass")
+
+	// the next pass starts with the oldest order again, the checked orders are not due yet
+	s.poller.Poll(context.Background())
+	assert.Len(t, checked, 5)
+}
+
+func TestPoll_LooksUpLostOrdersByMerchantOrderID(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	// the deposit call timed out, Zota may have accepted the order without us learning its order ID
+	s.createOrder(t, "lost", "", orderShared.StatusUnknown)
+
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
+		ClientRequest: shared.ClientRequest{MerchantOrderId: "lost"},
+	}).Return(&shared.Response{ClientRequest: shared.ClientRequest{OrderId: "gateway1", MerchantOrderId: "lost"},
+		Status: orderShared.StatusApproved}, nil)
+
+	s.poller.Poll(context.Background())
+
+	lost, err := s.orders.FindByMerchantOrderID("lost")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, lost.Status)
+	assert.Equal(t, "gateway1", lost.PaymentGatewayOrderID, "the Zota order ID is recorded")
+}
+
+func TestPoll_SkipsOrdersOfOtherEnvironment(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+	s.poller.config.Environment = config.EnvironmentLive
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       "sandboxOrder",
+		PaymentGatewayOrderID: "1",
+		Status:                orderShared.StatusPending,
+		Environment:           config.EnvironmentSandbox,
+	}))
+
+	// no status check is expected, the mock fails on any call
+	s.poller.Poll(context.Background())
+}
+
+func TestPoll_BacksOffByOrderAge(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "pending", "gateway1", orderShared.StatusPending)
+
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), gomock.Any()).
+		Return(&shared.Response{Status: orderShared.StatusPending}, nil).Times(2)
+
+	// first round checks, a round within the poll interval does not
+	s.poller.Poll(context.Background())
+	s.now = s.now.Add(30 * time.Second)
+	s.poller.Poll(context.Background())
+
+	// after the interval the young order is checked again
+	s.now = s.now.Add(time.Minute)
+	s.poller.Poll(context.Background())
+
+	// at 15 minutes of age the back-off is 1.5 minutes, one minute later is too early
+	s.now = s.now.Add(13*time.Minute + 30*time.Second)
+	s.poller.lastChecked["pending"] = s.now.Add(-time.Minute)
+	s.poller.Poll(context.Background())
+}
+
+func TestPoll_GatewayErrorKeepsOrderPending(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "pending", "gateway1", orderShared.StatusPending)
+
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), gomock.Any()).Return(nil, errors.New("gateway error"))
+
+	s.poller.Poll(context.Background())
+
+	stored, err := s.orders.FindByMerchantOrderID("pending")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusPending, stored.Status)
+}
+
+func TestPoller_StartStop(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.poller.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.poller.Stop(ctx))
+}
+
+func TestPoller_StopCancelsChecksInFlight(t *testing.T) {
+	s := &pollerTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.createOrder(t, "pending", "gateway1", orderShared.StatusPending)
+
+	started := make(chan struct{})
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ shared.Request) (*shared.Response, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.poller.Poll(ctx)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the poll round did not stop with its context")
+	}
+}
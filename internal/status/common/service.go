@@ -1,34 +1,86 @@
 package common
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
+	orderCommon "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
 	"zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/tracing"
 )
 
 type ServiceInterface interface {
-	CheckStatus(req *shared.ClientRequest) (*shared.Response, error)
+	CheckStatus(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error)
 }
 
 type Service struct {
 	logger       *zap.Logger
 	config       *config.Config
 	statusClient shared.StatusPaymentGateway
+	orders       orderShared.OrderRepository
+	stateMachine *orderCommon.StateMachine
 }
 
-func NewService(logger *zap.Logger, config *config.Config, statusClient shared.StatusPaymentGateway) *Service {
-	return &Service{logger: logger, config: config, statusClient: statusClient}
+func NewService(logger *zap.Logger, config *config.Config, statusClient shared.StatusPaymentGateway,
+	orders orderShared.OrderRepository, stateMachine *orderCommon.StateMachine) *Service {
+	return &Service{logger: logger, config: config, statusClient: statusClient, orders: orders, stateMachine: stateMachine}
 }
 
-func (s *Service) CheckStatus(req *shared.ClientRequest) (*shared.Response, error) {
+func (s *Service) CheckStatus(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "status.Service.CheckStatus",
+		tracing.AttrMerchantOrderID.String(req.MerchantOrderId), tracing.AttrZotaOrderID.String(req.OrderId))
+	res, err := s.checkStatus(ctx, req)
+	if res != nil {
+		span.SetAttributes(tracing.AttrStatus.String(res.Status), tracing.AttrCurrency.String(res.Currency))
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s *Service) checkStatus(ctx context.Context, req *shared.ClientRequest) (*shared.Response, error) {
+	if req.MerchantOrderId == "" {
+		return nil, apperror.Validation("request validation failed",
+			apperror.FieldError{Field: "merchantOrderId", Rule: "required", Message: "is required"})
+	}
+
+	order, err := s.orders.FindByMerchantOrderID(req.MerchantOrderId)
+	if err != nil && !errors.Is(err, orderShared.ErrOrderNotFound) {
+		s.logger.Error("Failed to read order", zap.Error(err))
+		return nil, err
+	}
+
+	// the order is only known to the Zota environment it was created in, querying the other one would mix them up
+	if order != nil && !order.InEnvironment(s.config.Environment) {
+		return nil, apperror.Conflict(fmt.Sprintf("order was created in the %s environment", order.Environment))
+	}
+
 	serviceModel := shared.Request{
 		ClientRequest: *req,
 	}
+	// the caller may only know our merchant order ID, the payment gateway one is in the store
+	if serviceModel.OrderId == "" && order != nil {
+		serviceModel.OrderId = order.PaymentGatewayOrderID
+	}
+	if serviceModel.OrderId == "" {
+		return nil, apperror.NotFound("order not found", orderShared.ErrOrderNotFound)
+	}
 
-	res, err := s.statusClient.CheckStatus(serviceModel)
+	res, err := s.statusClient.CheckStatus(ctx, serviceModel)
 	if err != nil {
 		s.logger.Error("Failed to check status", zap.Error(err))
 		return nil, err
 	}
+	res.Environment = s.config.Environment
+
+	if order != nil {
+		// the payment gateway answer is returned as is, an illegal transition only keeps the stored status unchanged
+		if _, err := s.stateMachine.Settle(order.MerchantOrderID, res.Status, orderShared.SourceStatusPoll, "", res.Amount.String()); err != nil {
+			s.logger.Error("Failed to update order status", zap.String("merchantOrderID", order.MerchantOrderID), zap.Error(err))
+		}
+	}
 	return res, nil
 }
@@ -1,13 +1,18 @@
 package common
 
 import (
+	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"testing"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
 	"zota-dev-challenge/internal/status/common/zota"
 	"zota-dev-challenge/internal/status/shared"
 )
@@ -16,6 +21,7 @@ type statusTestSuite struct {
 	mockCtrl    *gomock.Controller
 	mockGateway *zota.MockStatusPaymentGateway
 	logger      *zap.Logger
+	orders      *order.MemoryRepository
 	service     *Service
 	request     shared.ClientRequest
 }
@@ -27,7 +33,8 @@ func (s *statusTestSuite) setup(t *testing.T) {
 
 	cfg := &config.Config{}
 
-	s.service = NewService(s.logger, cfg, s.mockGateway)
+	s.orders = order.NewMemoryRepository()
+	s.service = NewService(s.logger, cfg, s.mockGateway, s.orders, order.NewStateMachine(s.logger, s.orders, metrics.New()))
 
 	s.request = shared.ClientRequest{
 		OrderId:         "1111",
@@ -48,11 +55,11 @@ func TestCheckStatus_Success(t *testing.T) {
 		Status: "Success",
 	}
 
-	s.mockGateway.EXPECT().CheckStatus(shared.Request{
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
 		ClientRequest: s.request,
 	}).Return(expectedResponse, nil)
 
-	response, err := s.service.CheckStatus(&s.request)
+	response, err := s.service.CheckStatus(context.Background(), &s.request)
 	require.NoError(t, err)
 	assert.Equal(t, expectedResponse, response)
 }
@@ -64,12 +71,111 @@ func TestCheckStatus_Error(t *testing.T) {
 
 	expectedError := errors.New("status check error")
 
-	s.mockGateway.EXPECT().CheckStatus(shared.Request{
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
 		ClientRequest: s.request,
 	}).Return(nil, expectedError)
 
-	response, err := s.service.CheckStatus(&s.request)
+	response, err := s.service.CheckStatus(context.Background(), &s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 	assert.Equal(t, expectedError, err)
 }
+
+func TestCheckStatus_UpdatesStoredOrder(t *testing.T) {
+	s := &statusTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       s.request.MerchantOrderId,
+		PaymentGatewayOrderID: s.request.OrderId,
+		Status:                orderShared.StatusCreated,
+	}))
+
+	// only the merchant order ID is known to the caller, the gateway order ID comes from the store
+	s.request.OrderId = ""
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
+		ClientRequest: shared.ClientRequest{OrderId: "1111", MerchantOrderId: "2222"},
+	}).Return(&shared.Response{Status: orderShared.StatusApproved}, nil)
+
+	_, err := s.service.CheckStatus(context.Background(), &s.request)
+	require.NoError(t, err)
+
+	stored, err := s.orders.FindByMerchantOrderID("2222")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, stored.Status)
+
+	transitions, err := s.orders.ListTransitions("2222")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, orderShared.SourceStatusPoll, transitions[0].Source)
+}
+
+func TestCheckStatus_IllegalTransitionKeepsStoredStatus(t *testing.T) {
+	s := &statusTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       s.request.MerchantOrderId,
+		PaymentGatewayOrderID: s.request.OrderId,
+		Status:                orderShared.StatusApproved,
+	}))
+
+	s.mockGateway.EXPECT().CheckStatus(gomock.Any(), shared.Request{
+		ClientRequest: s.request,
+	}).Return(&shared.Response{Status: orderShared.StatusPending}, nil)
+
+	response, err := s.service.CheckStatus(context.Background(), &s.request)
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusPending, response.Status)
+
+	stored, err := s.orders.FindByMerchantOrderID("2222")
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, stored.Status)
+}
+
+func TestCheckStatus_MissingMerchantOrderID(t *testing.T) {
+	s := &statusTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	s.request.MerchantOrderId = ""
+
+	res, err := s.service.CheckStatus(context.Background(), &s.request)
+	assert.Nil(t, res)
+	assert.Equal(t, apperror.CodeValidation, apperror.From(err).Code)
+}
+
+func TestCheckStatus_UnknownOrder(t *testing.T) {
+	s := &statusTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	// without a payment gateway order ID and a stored order there is nothing to ask the payment gateway about
+	s.request.OrderId = ""
+
+	res, err := s.service.CheckStatus(context.Background(), &s.request)
+	assert.Nil(t, res)
+	assert.Equal(t, apperror.CodeNotFound, apperror.From(err).Code)
+	assert.ErrorIs(t, err, orderShared.ErrOrderNotFound)
+}
+
+func TestCheckStatus_OrderOfOtherEnvironment(t *testing.T) {
+	s := &statusTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+	s.service.config.Environment = config.EnvironmentLive
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       s.request.MerchantOrderId,
+		PaymentGatewayOrderID: s.request.OrderId,
+		Status:                orderShared.StatusPending,
+		Environment:           config.EnvironmentSandbox,
+	}))
+
+	// a sandbox order must never be looked up at live Zota
+	res, err := s.service.CheckStatus(context.Background(), &s.request)
+	assert.Nil(t, res)
+	assert.Equal(t, apperror.CodeConflict, apperror.From(err).Code)
+}
@@ -5,6 +5,7 @@
 package zota
 
 import (
+	context "context"
 	reflect "reflect"
 	shared "zota-dev-challenge/internal/status/shared"
 
@@ -35,16 +36,16 @@ func (m *MockStatusPaymentGateway) EXPECT() *MockStatusPaymentGatewayMockRecorde
 }
 
 // CheckStatus mocks base method.
-func (m *MockStatusPaymentGateway) CheckStatus(req shared.Request) (*shared.Response, error) {
+func (m *MockStatusPaymentGateway) CheckStatus(ctx context.Context, req shared.Request) (*shared.Response, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckStatus", req)
+	ret := m.ctrl.Call(m, "CheckStatus", ctx, req)
 	ret0, _ := ret[0].(*shared.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckStatus indicates an expected call of CheckStatus.
-func (mr *MockStatusPaymentGatewayMockRecorder) CheckStatus(req interface{}) *gomock.Call {
+func (mr *MockStatusPaymentGatewayMockRecorder) CheckStatus(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckStatus", reflect.TypeOf((*MockStatusPaymentGateway)(nil).CheckStatus), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckStatus", reflect.TypeOf((*MockStatusPaymentGateway)(nil).CheckStatus), ctx, req)
 }
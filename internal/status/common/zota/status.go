@@ -1,19 +1,23 @@
 package zota
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"github.com/gorilla/schema"
 	"go.uber.org/zap"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
 	"zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/tracing"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 const StatusCheckApiPath = "api/v1/query/order-status"
@@ -23,7 +27,7 @@ type StatusRequest struct {
 	OrderId         string `json:"orderID" schema:"orderID"`
 	MerchantOrderId string `json:"merchantOrderID" schema:"merchantOrderID"`
 	Timestamp       string `json:"timestamp" schema:"timestamp"`
-	Signature       string `json:"signature" schema:"signature"`
+	Signature       string `json:"signature" schema:"signature" log:"secret"`
 }
 
 type StatusResponse struct {
@@ -60,15 +64,28 @@ type ExtraData struct {
 }
 
 type StatusGateway struct {
-	logger *zap.Logger
-	config *config.Config
+	logger  *zap.Logger
+	config  *config.Config
+	client  *zotaapi.Client
+	metrics *metrics.Metrics
 }
 
-func NewStatusGateway(logger *zap.Logger, config *config.Config) *StatusGateway {
-	return &StatusGateway{logger: logger, config: config}
+func NewStatusGateway(logger *zap.Logger, config *config.Config, client *zotaapi.Client, metrics *metrics.Metrics) *StatusGateway {
+	return &StatusGateway{logger: logger, config: config, client: client, metrics: metrics}
 }
 
-func (s *StatusGateway) CheckStatus(req shared.Request) (*shared.Response, error) {
+func (s *StatusGateway) CheckStatus(ctx context.Context, req shared.Request) (*shared.Response, error) {
+	ctx, span := tracing.Start(ctx, "zota.StatusGateway.CheckStatus",
+		tracing.AttrMerchantOrderID.String(req.MerchantOrderId), tracing.AttrZotaOrderID.String(req.OrderId))
+	res, err := s.checkStatus(ctx, req)
+	if res != nil {
+		span.SetAttributes(tracing.AttrStatus.String(res.Status), tracing.AttrCurrency.String(res.Currency))
+	}
+	tracing.End(span, err)
+	return res, err
+}
+
+func (s *StatusGateway) checkStatus(ctx context.Context, req shared.Request) (*shared.Response, error) {
 	s.logger.Info("Checking status with Zota", zap.Any("request", req))
 
 	statusReq, err := s.buildStatusReq(req)
@@ -83,16 +100,19 @@ func (s *StatusGateway) CheckStatus(req shared.Request) (*shared.Response, error
 		return nil, err
 	}
 
-	respBody, statusCode, err := s.sendStatusRequest(statusCheckApiUrl)
+	ctx, cancel := zotaapi.WithTimeout(ctx, s.config.ZotaStatusTimeout)
+	defer cancel()
+
+	// the latency includes the retries, it is what the caller waited for
+	started := time.Now()
+	respBody, statusCode, err := s.sendStatusRequest(ctx, statusCheckApiUrl)
 	if err != nil {
+		s.metrics.ObserveZotaCall(metrics.OperationStatus, started, err)
 		return nil, err
 	}
 
-	if statusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK statusResponse from Zota server: %s", http.StatusText(statusCode))
-	}
-
-	response, err := s.handleStatusResponse(respBody, req)
+	response, err := s.handleStatusResponse(respBody, statusCode, req)
+	s.metrics.ObserveZotaCall(metrics.OperationStatus, started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -131,52 +151,52 @@ func (s *StatusGateway) buildStatusCheckApiUrl(statusReq StatusRequest) (string,
 	return fmt.Sprintf("%s/%s/?%s", s.config.ZotaBaseUrl, StatusCheckApiPath, values.Encode()), nil
 }
 
-func (s *StatusGateway) sendStatusRequest(statusCheckApiUrl string) ([]byte, int, error) {
+func (s *StatusGateway) sendStatusRequest(ctx context.Context, statusCheckApiUrl string) ([]byte, int, error) {
 	s.logger.Info("Sending status request to Zota server", zap.String("url", statusCheckApiUrl))
 
-	httpReq, err := http.NewRequest("GET", statusCheckApiUrl, nil)
-	if err != nil {
-		s.logger.Error("Failed to create HTTP request", zap.Error(err))
-		return nil, 0, err
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		s.logger.Error("Failed to send HTTP request", zap.Error(err))
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
+	ctx, span := tracing.StartClient(ctx, "zota.StatusGateway.sendStatusRequest", http.MethodGet, statusCheckApiUrl)
+	defer span.End()
 
-	respBody, err := io.ReadAll(resp.Body)
+	// a status query changes nothing at Zota, so the client may retry it
+	resp, err := s.client.Do(ctx, zotaapi.Request{Method: http.MethodGet, URL: statusCheckApiUrl, Idempotent: true})
 	if err != nil {
-		s.logger.Error("Failed to read statusResponse body", zap.Error(err))
+		s.logger.Error("Failed to send status request", zap.Error(err))
+		tracing.Record(span, err)
 		return nil, 0, err
 	}
-
-	return respBody, resp.StatusCode, nil
+	tracing.RecordResponse(span, resp.StatusCode)
+	return resp.Body, resp.StatusCode, nil
 }
 
-func (s *StatusGateway) handleStatusResponse(respBody []byte, req shared.Request) (*shared.Response, error) {
+func (s *StatusGateway) handleStatusResponse(respBody []byte, statusCode int, req shared.Request) (*shared.Response, error) {
 	var statusResponse StatusResponse
-	if err := json.Unmarshal(respBody, &statusResponse); err != nil {
-		s.logger.Error("Failed to unmarshal statusResponse body", zap.Error(err))
+	if err := zotaapi.DecodeResponse(statusCode, respBody, &statusResponse); err != nil {
+		s.logger.Error("Zota did not accept the status request", zap.Int("statusCode", statusCode), zap.Error(err))
 		return nil, err
 	}
 
 	if statusResponse.Data == nil {
-		return nil, fmt.Errorf("no data in statusResponse")
+		return nil, apperror.GatewayError("malformed payment gateway response", fmt.Errorf("no data in statusResponse"))
+	}
+
+	amount, err := money.Parse(statusResponse.Data.Amount, statusResponse.Data.Currency)
+	if err != nil {
+		s.logger.Error("Invalid amount in statusResponse", zap.String("amount", statusResponse.Data.Amount), zap.Error(err))
+		return nil, apperror.GatewayError("malformed payment gateway response", err)
 	}
 
 	response := shared.Response{
 		ClientRequest: req.ClientRequest,
 		Type:          statusResponse.Data.Type,
 		Status:        statusResponse.Data.Status,
-		Amount:        statusResponse.Data.Amount,
+		Amount:        amount,
 		Currency:      statusResponse.Data.Currency,
 		CustomerEmail: statusResponse.Data.CustomerEmail,
 	}
+	// an order looked up by merchant order ID learns its Zota order ID from the answer
+	if response.ClientRequest.OrderId == "" {
+		response.ClientRequest.OrderId = statusResponse.Data.OrderID
+	}
 
 	s.logger.Info("Successfully checked status request",
 		zap.Any("request", req),
@@ -1,6 +1,7 @@
 package zota
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -9,13 +10,19 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strconv"
 	"testing"
 	"time"
+	"zota-dev-challenge/internal/apperror"
 	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/redact"
 	"zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/zotaapi"
 )
 
 type statusGatewayTestSuite struct {
@@ -39,7 +46,7 @@ func (s *statusGatewayTestSuite) setup(t *testing.T) {
 		ZotaDepositRedirectUrl: "https://redirect.example.com",
 	}
 
-	s.statusGateway = NewStatusGateway(s.logger, s.config)
+	s.statusGateway = NewStatusGateway(s.logger, s.config, zotaapi.NewClient(s.logger, s.config), metrics.New())
 
 	s.request = shared.Request{
 		ClientRequest: shared.ClientRequest{
@@ -79,15 +86,40 @@ func TestCheckStatus_Success(t *testing.T) {
 
 	s.statusGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := s.statusGateway.CheckStatus(s.request)
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
 	require.NoError(t, err)
 	assert.Equal(t, "type1", response.Type)
 	assert.Equal(t, "status1", response.Status)
-	assert.Equal(t, "100.00", response.Amount)
+	assert.Equal(t, "100.00", response.Amount.String())
 	assert.Equal(t, "USD", response.Currency)
 	assert.Equal(t, "test@example.com", response.CustomerEmail)
 }
 
+func TestCheckStatus_LogsAreRedacted(t *testing.T) {
+	s := &statusGatewayTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	core, logs := observer.New(zap.DebugLevel)
+	s.statusGateway.logger = zap.New(redact.New(s.config.ZotaAPISecretKey).Core(core))
+
+	// a refused connection fails with an error that quotes the signed URL
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	s.statusGateway.config.ZotaBaseUrl = server.URL
+
+	_, err := s.statusGateway.CheckStatus(context.Background(), s.request)
+	require.Error(t, err)
+
+	require.NotEmpty(t, logs.FilterMessage("Failed to send status request").All())
+	signature := regexp.MustCompile(`signature=[0-9a-f]{64}`)
+	for _, entry := range logs.All() {
+		logged := fmt.Sprintf("%s %v", entry.Message, entry.ContextMap())
+		assert.False(t, signature.MatchString(logged), logged)
+		assert.NotContains(t, logged, s.config.ZotaAPISecretKey)
+	}
+}
+
 func TestCheckStatus_BuildStatusReqError(t *testing.T) {
 	s := &statusGatewayTestSuite{}
 	s.setup(t)
@@ -96,7 +128,7 @@ func TestCheckStatus_BuildStatusReqError(t *testing.T) {
 	// Simulate a failure in building the status request by using invalid configuration
 	s.statusGateway.config.ZotaMerchantId = ""
 
-	response, err := s.statusGateway.CheckStatus(s.request)
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -114,11 +146,51 @@ func TestCheckStatus_HTTPRequestError(t *testing.T) {
 
 	s.statusGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := s.statusGateway.CheckStatus(s.request)
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
 
+func TestCheckStatus_Timeout(t *testing.T) {
+	s := &statusGatewayTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	s.config.ZotaBaseUrl = server.URL
+	s.config.ZotaStatusTimeout = 20 * time.Millisecond
+
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, apperror.CodeGatewayUnavailable, apperror.From(err).Code)
+}
+
+func TestCheckStatus_CancelledByCaller(t *testing.T) {
+	s := &statusGatewayTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a cancelled call must not reach Zota")
+	}))
+	defer server.Close()
+
+	s.config.ZotaBaseUrl = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.statusGateway.CheckStatus(ctx, s.request)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestCheckStatus_NonOKHTTPResponse(t *testing.T) {
 	s := &statusGatewayTestSuite{}
 	s.setup(t)
@@ -132,9 +204,30 @@ func TestCheckStatus_NonOKHTTPResponse(t *testing.T) {
 
 	s.statusGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := s.statusGateway.CheckStatus(s.request)
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeGatewayRejected, apperror.From(err).Code)
+}
+
+func TestCheckStatus_ErrorEnvelope(t *testing.T) {
+	s := &statusGatewayTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code":"500","message":"temporary failure"}`))
+	}))
+	defer server.Close()
+
+	s.statusGateway.config.ZotaBaseUrl = server.URL
+
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
+	assert.Nil(t, response)
+	appErr := apperror.From(err)
+	assert.Equal(t, apperror.CodeGatewayUnavailable, appErr.Code)
+	assert.Equal(t, &apperror.GatewayFailure{Code: "500", Message: "temporary failure"}, appErr.Gateway)
 }
 
 func TestCheckStatus_UnmarshalResponseError(t *testing.T) {
@@ -151,7 +244,7 @@ func TestCheckStatus_UnmarshalResponseError(t *testing.T) {
 
 	s.statusGateway.config.ZotaBaseUrl = server.URL
 
-	response, err := s.statusGateway.CheckStatus(s.request)
+	response, err := s.statusGateway.CheckStatus(context.Background(), s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
 }
@@ -203,11 +296,11 @@ func TestHandleStatusResponse(t *testing.T) {
 	}
 	statusResponseJSON, _ := json.Marshal(statusResponse)
 
-	response, err := s.statusGateway.handleStatusResponse(statusResponseJSON, s.request)
+	response, err := s.statusGateway.handleStatusResponse(statusResponseJSON, http.StatusOK, s.request)
 	require.NoError(t, err)
 	assert.Equal(t, "type1", response.Type)
 	assert.Equal(t, "status1", response.Status)
-	assert.Equal(t, "100.00", response.Amount)
+	assert.Equal(t, "100.00", response.Amount.String())
 	assert.Equal(t, "USD", response.Currency)
 	assert.Equal(t, "test@example.com", response.CustomerEmail)
 }
@@ -223,8 +316,24 @@ func TestHandleStatusResponse_NoDataError(t *testing.T) {
 	}
 	statusResponseJSON, _ := json.Marshal(statusResponse)
 
-	response, err := s.statusGateway.handleStatusResponse(statusResponseJSON, s.request)
+	response, err := s.statusGateway.handleStatusResponse(statusResponseJSON, http.StatusOK, s.request)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	assert.Equal(t, apperror.CodeGatewayError, apperror.From(err).Code)
+	assert.ErrorContains(t, err, "no data in statusResponse")
+}
+
+func TestHandleStatusResponse_InvalidAmount(t *testing.T) {
+	s := &statusGatewayTestSuite{}
+	s.setup(t)
+	defer s.teardown()
+
+	statusResponseJSON, _ := json.Marshal(StatusResponse{
+		Code: "200",
+		Data: &Data{Status: "APPROVED", Amount: "abc", Currency: "USD"},
+	})
+
+	response, err := s.statusGateway.handleStatusResponse(statusResponseJSON, http.StatusOK, s.request)
 	assert.Error(t, err)
 	assert.Nil(t, response)
-	assert.Equal(t, "no data in statusResponse", err.Error())
 }
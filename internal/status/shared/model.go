@@ -1,9 +1,14 @@
 package shared
 
+import (
+	"context"
+	"zota-dev-challenge/internal/money"
+)
+
 type ClientRequest struct {
 	OrderId         string `json:"orderId"`
 	MerchantOrderId string `json:"merchantOrderId"`
-}
+} //@name StatusRequest
 
 type Request struct {
 	ClientRequest
@@ -13,11 +18,12 @@ type Response struct {
 	ClientRequest ClientRequest `json:"request"`
 	Type          string        `json:"type"`
 	Status        string        `json:"status"`
-	Amount        string        `json:"amount"`
+	Amount        money.Amount  `json:"amount" swaggertype:"string"`
 	Currency      string        `json:"currency"`
-	CustomerEmail string        `json:"customerEmail"`
-}
+	CustomerEmail string        `json:"customerEmail" log:"email"`
+	Environment   string        `json:"environment"` // sandbox or live
+} //@name StatusResponse
 
 type StatusPaymentGateway interface {
-	CheckStatus(req Request) (*Response, error)
+	CheckStatus(ctx context.Context, req Request) (*Response, error)
 }
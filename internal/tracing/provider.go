@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"os"
+	"zota-dev-challenge/internal/config"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Setup installs the configured tracer provider and the W3C trace-context propagator globally,
+// the spans still buffered are flushed when the application stops
+func Setup(lc fx.Lifecycle, logger *zap.Logger, config *config.Config) error {
+	// the trace context is taken over from incoming requests whether or not we export spans ourselves
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(config)
+	if err != nil {
+		return err
+	}
+	if exporter == nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return nil
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(config.TracingServiceName),
+			semconv.DeploymentEnvironment(config.Environment),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return provider.Shutdown(ctx)
+		},
+	})
+
+	logger.Info("Exporting traces", zap.String("exporter", config.TracingExporter))
+	return nil
+}
+
+// newExporter returns nil when the spans are not exported at all
+func newExporter(config *config.Config) (sdktrace.SpanExporter, error) {
+	switch config.TracingExporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		// without an endpoint the exporter follows the standard OTEL_EXPORTER_OTLP_* variables
+		var options []otlptracehttp.Option
+		if config.TracingOTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(config.TracingOTLPEndpoint))
+		}
+		return otlptracehttp.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", config.TracingExporter)
+	}
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/url"
+	"zota-dev-challenge/internal/apperror"
+)
+
+// instrumentationName - the name every span of the service is created under
+const instrumentationName = "zota-dev-challenge"
+
+// Span attributes shared by the deposit, status and payout flows
+const (
+	AttrMerchantOrderID = attribute.Key("merchant.order_id")
+	AttrZotaOrderID     = attribute.Key("zota.order_id")
+	AttrCurrency        = attribute.Key("order.currency")
+	AttrStatus          = attribute.Key("order.status")
+	AttrOutcome         = attribute.Key("outcome")
+	AttrErrorCode       = attribute.Key("error.code")
+	AttrZotaErrorCode   = attribute.Key("zota.error_code")
+)
+
+// OutcomeSuccess - the outcome of a span that ended without an error, failed ones carry the error code
+const OutcomeSuccess = "success"
+
+// Start opens a span of the service, the tracer is looked up on every call so the provider installed by Setup is used
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Annotate adds attributes to the span of the context, e.g. IDs only known halfway through
+func Annotate(ctx context.Context, attributes ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attributes...)
+}
+
+// End records the outcome of the span and ends it
+func End(span trace.Span, err error) {
+	Record(span, err)
+	span.End()
+}
+
+// Record sets the outcome of the span, typed errors contribute their code and Zota's own code
+func Record(span trace.Span, err error) {
+	if err == nil {
+		span.SetAttributes(AttrOutcome.String(OutcomeSuccess))
+		return
+	}
+
+	appErr := apperror.From(err)
+	span.SetAttributes(AttrOutcome.String(appErr.Code), AttrErrorCode.String(appErr.Code))
+	if appErr.Gateway != nil {
+		span.SetAttributes(AttrZotaErrorCode.String(appErr.Gateway.Code))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, appErr.Message)
+}
+
+// StartClient opens the span of a call to Zota, the URL is logged without its query since it may carry a signature
+func StartClient(ctx context.Context, name, method, rawURL string) (context.Context, trace.Span) {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		rawURL = parsed.Scheme + "://" + parsed.Host + parsed.Path
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(method), semconv.URLFull(rawURL)))
+}
+
+// RecordResponse records the HTTP status Zota answered with, the envelope is judged by the caller
+func RecordResponse(span trace.Span, statusCode int) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+}
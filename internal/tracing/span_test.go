@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"zota-dev-challenge/internal/apperror"
+)
+
+// record installs a provider that keeps the ended spans in memory for the test
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestEnd_Success(t *testing.T) {
+	recorder := record(t)
+
+	ctx, span := Start(context.Background(), "operation", AttrCurrency.String("USD"))
+	Annotate(ctx, AttrMerchantOrderID.String("merchantOrder123"))
+	End(span, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "operation", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	attrs := attributes(spans[0])
+	assert.Equal(t, OutcomeSuccess, attrs[AttrOutcome].AsString())
+	assert.Equal(t, "USD", attrs[AttrCurrency].AsString())
+	assert.Equal(t, "merchantOrder123", attrs[AttrMerchantOrderID].AsString())
+}
+
+func TestEnd_RecordsErrorCodes(t *testing.T) {
+	recorder := record(t)
+
+	_, span := Start(context.Background(), "operation")
+	err := apperror.GatewayRejected("payment gateway rejected the request", nil)
+	err.Gateway = &apperror.GatewayFailure{Code: "400", Message: "invalid signature"}
+	End(span, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Len(t, spans[0].Events(), 1, "the error is recorded as an event")
+
+	attrs := attributes(spans[0])
+	assert.Equal(t, apperror.CodeGatewayRejected, attrs[AttrOutcome].AsString())
+	assert.Equal(t, apperror.CodeGatewayRejected, attrs[AttrErrorCode].AsString())
+	assert.Equal(t, "400", attrs[AttrZotaErrorCode].AsString())
+}
+
+func TestEnd_UntypedError(t *testing.T) {
+	recorder := record(t)
+
+	_, span := Start(context.Background(), "operation")
+	End(span, errors.New("boom"))
+
+	attrs := attributes(recorder.Ended()[0])
+	assert.Equal(t, apperror.CodeInternal, attrs[AttrOutcome].AsString())
+	_, hasZotaCode := attrs[AttrZotaErrorCode]
+	assert.False(t, hasZotaCode)
+}
+
+func TestStartClient_StripsQuery(t *testing.T) {
+	recorder := record(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, span := StartClient(ctx, "call", "GET", "https://api.zotapay-sandbox.com/api/v1/query/order-status/?signature=secret")
+	RecordResponse(span, 200)
+	span.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	attrs := attributes(spans[0])
+	assert.Equal(t, "https://api.zotapay-sandbox.com/api/v1/query/order-status/", attrs["url.full"].AsString())
+	assert.Equal(t, int64(200), attrs["http.response.status_code"].AsInt64())
+}
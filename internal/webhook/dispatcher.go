@@ -0,0 +1,332 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	orderCommon "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	"zota-dev-challenge/internal/tracing"
+)
+
+const (
+	// dispatchInterval - how often due retries are looked for, new events are sent right away
+	dispatchInterval = time.Second
+	// dispatchBatchSize - the most deliveries sent in one round
+	dispatchBatchSize = 100
+	// maxErrorBody - how much of a failed answer is kept in the delivery log
+	maxErrorBody = 256
+)
+
+// Outcomes of a delivery attempt, the outcome label of the webhook metrics
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeFailed    = "failed"
+	OutcomeDead      = "dead"
+)
+
+// Dispatcher - turns order status changes into deliveries and sends them until they succeed or die
+type Dispatcher struct {
+	logger        *zap.Logger
+	config        *config.Config
+	store         Store
+	metrics       *metrics.Metrics
+	client        *http.Client
+	subscriptions map[string]config.WebhookSubscription
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	now    func() time.Time
+	jitter func(max time.Duration) time.Duration
+}
+
+// NewDispatcher subscribes to the state machine, so every status change from any source is published
+func NewDispatcher(logger *zap.Logger, config *config.Config, store Store, stateMachine *orderCommon.StateMachine,
+	metrics *metrics.Metrics) *Dispatcher {
+	d := &Dispatcher{
+		logger:        logger,
+		config:        config,
+		store:         store,
+		metrics:       metrics,
+		client:        &http.Client{Timeout: config.WebhookTimeout},
+		subscriptions: byName(config.Webhooks),
+		wake:          make(chan struct{}, 1),
+		now:           time.Now,
+		jitter: func(max time.Duration) time.Duration {
+			// at least half the back-off, so retries of a struggling subscriber stay spread out
+			return max/2 + time.Duration(rand.Int63n(int64(max/2)+1))
+		},
+	}
+	stateMachine.OnTransition(d.Publish)
+	return d
+}
+
+func byName(subscriptions []config.WebhookSubscription) map[string]config.WebhookSubscription {
+	named := make(map[string]config.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		named[subscription.Name] = subscription
+	}
+	return named
+}
+
+// Publish queues the event of a status change for every subscription that wants it
+func (d *Dispatcher) Publish(order orderShared.Order, transition orderShared.Transition) {
+	event := NewEvent(uuid.New().String(), order, transition)
+
+	var subscriptions []config.WebhookSubscription
+	for _, subscription := range d.config.Webhooks {
+		if Subscribes(subscription, event.Type) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("Failed to marshal webhook event", zap.String("eventType", event.Type), zap.Error(err))
+		return
+	}
+
+	now := d.now()
+	deliveries := make([]Delivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, Delivery{
+			ID:            uuid.New().String(),
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Subscription:  subscription.Name,
+			URL:           subscription.URL,
+			Payload:       payload,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	if err := d.store.Add(deliveries...); err != nil {
+		d.logger.Error("Failed to queue webhook deliveries", zap.String("eventType", event.Type), zap.Error(err))
+		return
+	}
+	d.signal()
+}
+
+// signal starts a round right away, unless one is already due
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start sends the deliveries in the background until Stop is called
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(dispatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+			d.Dispatch(ctx)
+		}
+	}()
+}
+
+// Stop cancels the deliveries in flight and waits for the round to wind down or for the context to expire,
+// cancelled attempts are not counted
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.cancel()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Dispatch sends every due delivery, with at most WebhookConcurrency in flight
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	deliveries, err := d.store.Due(d.now(), dispatchBatchSize)
+	if err != nil {
+		d.logger.Error("Failed to list due webhook deliveries", zap.Error(err))
+		return
+	}
+
+	sem := make(chan struct{}, d.config.WebhookConcurrency)
+	var wg sync.WaitGroup
+
+	for _, delivery := range deliveries {
+		select {
+		case <-ctx.Done():
+			// shutting down, leave the rest for the next run
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(delivery Delivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+
+	wg.Wait()
+}
+
+// Redeliver sends a delivery again with a fresh set of attempts, e.g. a dead letter once the subscriber is fixed
+func (d *Dispatcher) Redeliver(id string) (*Delivery, error) {
+	delivery, err := d.store.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == StatusPending {
+		return delivery, nil
+	}
+
+	now := d.now()
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+	if err := d.store.Update(*delivery, nil); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("Redelivering webhook", zap.String("deliveryID", id), zap.String("subscription", delivery.Subscription))
+	d.signal()
+	return delivery, nil
+}
+
+// attempt sends the delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery Delivery) {
+	started := d.now()
+	statusCode, err := d.send(ctx, delivery, started)
+	if ctx.Err() != nil {
+		// shutting down, the attempt is not the subscriber's fault
+		return
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.UpdatedAt = now
+	attempt := &Attempt{
+		DeliveryID: delivery.ID,
+		Number:     delivery.Attempts,
+		StatusCode: statusCode,
+		DurationMs: now.Sub(started).Milliseconds(),
+		At:         started,
+	}
+
+	outcome := OutcomeDelivered
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.NextAttemptAt = time.Time{}
+		delivery.LastError = ""
+	case delivery.Attempts >= d.config.WebhookMaxAttempts:
+		outcome = OutcomeDead
+		delivery.Status = StatusDead
+		delivery.NextAttemptAt = time.Time{}
+		delivery.LastError = err.Error()
+		attempt.Error = err.Error()
+	default:
+		outcome = OutcomeFailed
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		attempt.Error = err.Error()
+	}
+	d.metrics.ObserveWebhookAttempt(delivery.Subscription, outcome)
+
+	fields := []zap.Field{
+		zap.String("deliveryID", delivery.ID),
+		zap.String("eventType", delivery.EventType),
+		zap.String("subscription", delivery.Subscription),
+		zap.Int("attempt", delivery.Attempts),
+	}
+	switch outcome {
+	case OutcomeDelivered:
+		d.logger.Info("Delivered webhook", fields...)
+	case OutcomeDead:
+		d.logger.Error("Webhook delivery is dead after its last attempt", append(fields, zap.Error(err))...)
+	default:
+		d.logger.Warn("Webhook delivery failed, retrying",
+			append(fields, zap.Time("nextAttemptAt", delivery.NextAttemptAt), zap.Error(err))...)
+	}
+
+	if err := d.store.Update(delivery, attempt); err != nil {
+		d.logger.Error("Failed to record webhook attempt", zap.String("deliveryID", delivery.ID), zap.Error(err))
+	}
+}
+
+var errUnknownSubscription = errors.New("subscription is no longer configured")
+
+// send posts the signed payload, anything but a 2xx answer is an error
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery, at time.Time) (statusCode int, err error) {
+	subscription, ok := d.subscriptions[delivery.Subscription]
+	if !ok {
+		return 0, errUnknownSubscription
+	}
+
+	ctx, span := tracing.StartClient(ctx, "webhook.Dispatcher.send", http.MethodPost, delivery.URL)
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, at, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	tracing.RecordResponse(span, resp.StatusCode)
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("subscriber answered %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	// drained so the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	return resp.StatusCode, nil
+}
+
+// backoff - exponential back-off with jitter, attempt is the number of failed attempts so far
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.WebhookRetryBaseDelay << (attempt - 1)
+	if delay > d.config.WebhookRetryMaxDelay || delay <= 0 {
+		delay = d.config.WebhookRetryMaxDelay
+	}
+	return d.jitter(delay)
+}
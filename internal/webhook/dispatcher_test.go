@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/config"
+	"zota-dev-challenge/internal/metrics"
+	order "zota-dev-challenge/internal/order/common"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+// received - a request the subscriber got
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+type dispatcherTestSuite struct {
+	orders       *order.MemoryRepository
+	stateMachine *order.StateMachine
+	store        *MemoryStore
+	metrics      *metrics.Metrics
+	dispatcher   *Dispatcher
+	now          time.Time
+
+	mu       sync.Mutex
+	status   int
+	received []received
+}
+
+func (s *dispatcherTestSuite) setup(t *testing.T, events ...string) {
+	s.status = http.StatusOK
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.received = append(s.received, received{header: r.Header.Clone(), body: body})
+		w.WriteHeader(s.status)
+		io.WriteString(w, "subscriber says no")
+	}))
+	t.Cleanup(subscriber.Close)
+
+	if len(events) == 0 {
+		events = []string{"*"}
+	}
+	cfg := &config.Config{
+		Webhooks: []config.WebhookSubscription{
+			{Name: "ledger", URL: subscriber.URL, Events: events, Secret: "ledger-secret"},
+		},
+		WebhookMaxAttempts:    3,
+		WebhookRetryBaseDelay: time.Minute,
+		WebhookRetryMaxDelay:  time.Hour,
+		WebhookTimeout:        time.Second,
+		WebhookConcurrency:    2,
+	}
+
+	logger := zap.NewNop()
+	s.orders = order.NewMemoryRepository()
+	s.metrics = metrics.New()
+	s.stateMachine = order.NewStateMachine(logger, s.orders, s.metrics)
+	s.store = NewMemoryStore(time.Hour)
+	s.dispatcher = NewDispatcher(logger, cfg, s.store, s.stateMachine, s.metrics)
+	s.now = time.Now()
+	s.dispatcher.now = func() time.Time { return s.now }
+	s.dispatcher.jitter = func(max time.Duration) time.Duration { return max }
+
+	require.NoError(t, s.orders.Create(&orderShared.Order{
+		MerchantOrderID:       "merchantOrder123",
+		PaymentGatewayOrderID: "zotaOrder123",
+		Type:                  orderShared.TypeSale,
+		UserID:                "user-1",
+		Amount:                "100.00",
+		Currency:              "USD",
+		Status:                orderShared.StatusCreated,
+	}))
+}
+
+func (s *dispatcherTestSuite) requests() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.received...)
+}
+
+func (s *dispatcherTestSuite) answer(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *dispatcherTestSuite) onlyDelivery(t *testing.T) Delivery {
+	deliveries, err := s.store.List("", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	return deliveries[0]
+}
+
+func TestDispatch_DeliversSignedEvent(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+	s.dispatcher.Dispatch(context.Background())
+
+	requests := s.requests()
+	require.Len(t, requests, 1)
+	request := requests[0]
+	assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+	assert.Equal(t, "deposit.approved", request.header.Get(HeaderEventType))
+	assert.NoError(t, Verify("ledger-secret", request.header.Get(HeaderSignature), request.body, s.now, time.Minute))
+
+	var event Event
+	require.NoError(t, json.Unmarshal(request.body, &event))
+	assert.Equal(t, request.header.Get(HeaderEventID), event.ID)
+	assert.Equal(t, "deposit.approved", event.Type)
+	assert.Equal(t, orderShared.StatusApproved, event.Data.Order.Status)
+	assert.Equal(t, "100.00", event.Data.Order.Amount)
+	assert.Equal(t, orderShared.StatusCreated, event.Data.PreviousStatus)
+	assert.Equal(t, orderShared.SourceCallback, event.Data.Source)
+
+	delivery := s.onlyDelivery(t)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	attempts, err := s.store.Attempts(delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, http.StatusOK, attempts[0].StatusCode)
+
+	s.dispatcher.Dispatch(context.Background())
+	assert.Len(t, s.requests(), 1, "a delivered event is not sent again")
+}
+
+func TestDispatch_FiltersEventTypes(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t, "deposit.approved", "deposit.declined")
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusPending, orderShared.SourceStatusPoll, "")
+	require.NoError(t, err)
+	_, err = s.stateMachine.Transition("merchantOrder123", orderShared.StatusDeclined, orderShared.SourceCallback, "card declined")
+	require.NoError(t, err)
+	s.dispatcher.Dispatch(context.Background())
+
+	requests := s.requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "deposit.declined", requests[0].header.Get(HeaderEventType))
+
+	var event Event
+	require.NoError(t, json.Unmarshal(requests[0].body, &event))
+	assert.Equal(t, orderShared.StatusPending, event.Data.PreviousStatus)
+	assert.Equal(t, "card declined", event.Data.Reason)
+}
+
+func TestDispatch_RetriesWithBackoffUntilDead(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	s.answer(http.StatusServiceUnavailable)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+
+	s.dispatcher.Dispatch(context.Background())
+	delivery := s.onlyDelivery(t)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Equal(t, s.now.Add(time.Minute), delivery.NextAttemptAt)
+	assert.Equal(t, "subscriber answered 503: subscriber says no", delivery.LastError)
+
+	s.dispatcher.Dispatch(context.Background())
+	assert.Len(t, s.requests(), 1, "the retry is not due yet")
+
+	s.now = s.now.Add(time.Minute)
+	s.dispatcher.Dispatch(context.Background())
+	delivery = s.onlyDelivery(t)
+	assert.Equal(t, s.now.Add(2*time.Minute), delivery.NextAttemptAt, "the back-off doubles")
+
+	s.now = s.now.Add(2 * time.Minute)
+	s.dispatcher.Dispatch(context.Background())
+	delivery = s.onlyDelivery(t)
+	assert.Equal(t, StatusDead, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+
+	s.now = s.now.Add(24 * time.Hour)
+	s.dispatcher.Dispatch(context.Background())
+	requests := s.requests()
+	require.Len(t, requests, 3, "a dead letter is not retried")
+	assert.Equal(t, requests[0].header.Get(HeaderEventID), requests[2].header.Get(HeaderEventID),
+		"every attempt carries the same event ID")
+
+	attempts, err := s.store.Attempts(delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 3)
+	for i, attempt := range attempts {
+		assert.Equal(t, i+1, attempt.Number)
+		assert.Equal(t, http.StatusServiceUnavailable, attempt.StatusCode)
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(s.metrics.Registry, strings.NewReader(`
+# HELP merchant_webhook_attempts_total Webhook delivery attempts by subscription and outcome: delivered, failed or dead.
+# TYPE merchant_webhook_attempts_total counter
+merchant_webhook_attempts_total{outcome="dead",subscription="ledger"} 1
+merchant_webhook_attempts_total{outcome="failed",subscription="ledger"} 2
+`), "merchant_webhook_attempts_total"))
+}
+
+func TestRedeliver_SendsDeadLetterAgain(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	s.answer(http.StatusGone)
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		s.dispatcher.Dispatch(context.Background())
+		s.now = s.now.Add(time.Hour)
+	}
+	require.Equal(t, StatusDead, s.onlyDelivery(t).Status)
+
+	s.answer(http.StatusNoContent)
+	redelivered, err := s.dispatcher.Redeliver(s.onlyDelivery(t).ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+
+	s.dispatcher.Dispatch(context.Background())
+	delivery := s.onlyDelivery(t)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	assert.Empty(t, delivery.LastError)
+
+	attempts, err := s.store.Attempts(delivery.ID)
+	require.NoError(t, err)
+	assert.Len(t, attempts, 4, "the delivery log keeps the attempts made before the redelivery")
+
+	_, err = s.dispatcher.Redeliver("missing")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestDispatch_UnreachableSubscriber(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	s.dispatcher.config.Webhooks[0].URL = "http://127.0.0.1:1/hooks"
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+	s.dispatcher.Dispatch(context.Background())
+
+	delivery := s.onlyDelivery(t)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.NotEmpty(t, delivery.LastError)
+	attempts, err := s.store.Attempts(delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Zero(t, attempts[0].StatusCode)
+}
+
+func TestStartStop_DeliversInBackground(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	s.dispatcher.now = time.Now
+
+	s.dispatcher.Start()
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return len(s.requests()) == 1 }, 2*time.Second, 10*time.Millisecond,
+		"new events are sent without waiting for the next tick")
+	require.NoError(t, s.dispatcher.Stop(context.Background()))
+}
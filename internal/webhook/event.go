@@ -0,0 +1,72 @@
+// Package webhook tells downstream services about order status changes.
+//
+// Every change is turned into an event and delivered to each subscription whose event types match, as a signed
+// JSON POST. Failed deliveries are retried with exponential back-off and become dead letters once they run out of
+// attempts, every attempt is kept in the delivery log.
+package webhook
+
+import (
+	"strings"
+	"time"
+	"zota-dev-challenge/internal/config"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+// Event - the body of a delivery
+type Event struct {
+	// ID - the same for every subscriber and every attempt, so subscribers can drop duplicates
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      EventData `json:"data"`
+} //@name WebhookEvent
+
+// EventData - the order after the change and what it changed from
+type EventData struct {
+	Order          orderShared.Order `json:"order"`
+	PreviousStatus string            `json:"previousStatus"`
+	Source         string            `json:"source"`
+	Reason         string            `json:"reason,omitempty"`
+} //@name WebhookEventData
+
+// eventOrderTypes - the event type prefix of an order type
+var eventOrderTypes = map[string]string{
+	orderShared.TypeSale:   "deposit",
+	orderShared.TypePayout: "payout",
+}
+
+// EventType names the change, e.g. deposit.approved or payout.declined
+func EventType(order orderShared.Order) string {
+	orderType, ok := eventOrderTypes[order.Type]
+	if !ok {
+		// orders stored before types were recorded are deposits
+		orderType = eventOrderTypes[orderShared.TypeSale]
+	}
+	return orderType + "." + strings.ToLower(order.Status)
+}
+
+// NewEvent describes the transition of the order
+func NewEvent(id string, order orderShared.Order, transition orderShared.Transition) Event {
+	return Event{
+		ID:        id,
+		Type:      EventType(order),
+		CreatedAt: transition.CreatedAt,
+		Data: EventData{
+			Order:          order,
+			PreviousStatus: transition.From,
+			Source:         transition.Source,
+			Reason:         transition.Reason,
+		},
+	}
+}
+
+// Subscribes reports whether the subscription wants the event type, filters are *, <type>.* or exact types
+func Subscribes(subscription config.WebhookSubscription, eventType string) bool {
+	orderType, _, _ := strings.Cut(eventType, ".")
+	for _, filter := range subscription.Events {
+		if filter == "*" || filter == eventType || filter == orderType+".*" {
+			return true
+		}
+	}
+	return false
+}
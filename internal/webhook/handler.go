@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"zota-dev-challenge/internal/apperror"
+)
+
+// defaultListLimit - how many deliveries are listed unless the request asks for another number
+const defaultListLimit = 100
+
+var deliveryStatuses = map[string]bool{"": true, StatusPending: true, StatusDelivered: true, StatusDead: true}
+
+// DeliveryLog - a delivery and every attempt made to send it
+type DeliveryLog struct {
+	Delivery Delivery  `json:"delivery"`
+	Attempts []Attempt `json:"attempts"`
+} //@name WebhookDeliveryLog
+
+// ListHandler
+// @Summary List webhook deliveries
+// @Description lists the webhook deliveries newest first, status=dead lists the dead letters
+// @Tags webhooks
+// @Produce json
+// @Param status query string false "pending, delivered or dead"
+// @Param limit query int false "Most deliveries listed, defaults to 100"
+// @Success 200 {array} Delivery
+// @Security AdminToken
+// @Failure 400 {object} apperror.Response "Invalid request"
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Router /webhooks/deliveries [get]
+func ListHandler(store Store, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if !deliveryStatuses[status] {
+			apperror.Write(w, r, logger, apperror.InvalidRequest("status must be pending, delivered or dead", nil))
+			return
+		}
+
+		limit := defaultListLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				apperror.Write(w, r, logger, apperror.InvalidRequest("limit must be a positive number", err))
+				return
+			}
+			limit = parsed
+		}
+
+		deliveries, err := store.List(status, limit)
+		if err != nil {
+			logger.Error("Failed to list webhook deliveries", zap.Error(err))
+			apperror.Write(w, r, logger, apperror.Internal(err))
+			return
+		}
+		if deliveries == nil {
+			deliveries = []Delivery{}
+		}
+		writeJSON(w, logger, http.StatusOK, deliveries)
+	}
+}
+
+// DeliveryHandler
+// @Summary Show a webhook delivery
+// @Description shows a webhook delivery with its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} DeliveryLog
+// @Security AdminToken
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Failure 404 {object} apperror.Response "Delivery not found"
+// @Router /webhooks/deliveries/{id} [get]
+func DeliveryHandler(store Store, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		delivery, err := store.Find(id)
+		if err != nil {
+			writeStoreError(w, r, logger, err)
+			return
+		}
+		attempts, err := store.Attempts(id)
+		if err != nil {
+			writeStoreError(w, r, logger, err)
+			return
+		}
+		if attempts == nil {
+			attempts = []Attempt{}
+		}
+		writeJSON(w, logger, http.StatusOK, DeliveryLog{Delivery: *delivery, Attempts: attempts})
+	}
+}
+
+// RedeliverHandler
+// @Summary Redeliver a webhook
+// @Description sends a delivery again with a fresh set of attempts, e.g. a dead letter once the subscriber is fixed
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Success 202 {object} Delivery "Delivery queued"
+// @Security AdminToken
+// @Failure 401 {object} apperror.Response "Missing or invalid admin token"
+// @Failure 404 {object} apperror.Response "Delivery not found"
+// @Router /webhooks/deliveries/{id}/redeliver [post]
+func RedeliverHandler(dispatcher *Dispatcher, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		delivery, err := dispatcher.Redeliver(chi.URLParam(r, "id"))
+		if err != nil {
+			writeStoreError(w, r, logger, err)
+			return
+		}
+		writeJSON(w, logger, http.StatusAccepted, delivery)
+	}
+}
+
+func writeStoreError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error) {
+	if errors.Is(err, ErrDeliveryNotFound) {
+		apperror.Write(w, r, logger, apperror.NotFound("webhook delivery not found", err))
+		return
+	}
+	logger.Error("Failed to read webhook delivery", zap.Error(err))
+	apperror.Write(w, r, logger, apperror.Internal(err))
+}
+
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+func (s *dispatcherTestSuite) router() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/webhooks/deliveries", ListHandler(s.store, zap.NewNop()))
+	r.Get("/webhooks/deliveries/{id}", DeliveryHandler(s.store, zap.NewNop()))
+	r.Post("/webhooks/deliveries/{id}/redeliver", RedeliverHandler(s.dispatcher, zap.NewNop()))
+	return r
+}
+
+func serve(t *testing.T, handler http.Handler, method, target string, body any) int {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	if body != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), body), rec.Body.String())
+	}
+	return rec.Code
+}
+
+func TestHandlers_ListShowAndRedeliverDeadLetters(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	s.answer(http.StatusInternalServerError)
+	router := s.router()
+
+	_, err := s.stateMachine.Transition("merchantOrder123", orderShared.StatusApproved, orderShared.SourceCallback, "")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		s.dispatcher.Dispatch(context.Background())
+		s.now = s.now.Add(time.Hour)
+	}
+
+	var dead []Delivery
+	assert.Equal(t, http.StatusOK, serve(t, router, http.MethodGet, "/webhooks/deliveries?status=dead", &dead))
+	require.Len(t, dead, 1)
+	assert.Equal(t, "deposit.approved", dead[0].EventType)
+
+	var log DeliveryLog
+	assert.Equal(t, http.StatusOK, serve(t, router, http.MethodGet, "/webhooks/deliveries/"+dead[0].ID, &log))
+	assert.Equal(t, StatusDead, log.Delivery.Status)
+	assert.Len(t, log.Attempts, 3)
+
+	var redelivered Delivery
+	assert.Equal(t, http.StatusAccepted,
+		serve(t, router, http.MethodPost, "/webhooks/deliveries/"+dead[0].ID+"/redeliver", &redelivered))
+	assert.Equal(t, StatusPending, redelivered.Status)
+
+	var none []Delivery
+	assert.Equal(t, http.StatusOK, serve(t, router, http.MethodGet, "/webhooks/deliveries?status=dead", &none))
+	assert.Empty(t, none)
+}
+
+func TestHandlers_RejectBadRequests(t *testing.T) {
+	s := &dispatcherTestSuite{}
+	s.setup(t)
+	router := s.router()
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, router, http.MethodGet, "/webhooks/deliveries?status=lost", nil))
+	assert.Equal(t, http.StatusBadRequest, serve(t, router, http.MethodGet, "/webhooks/deliveries?limit=-1", nil))
+	assert.Equal(t, http.StatusNotFound, serve(t, router, http.MethodGet, "/webhooks/deliveries/missing", nil))
+	assert.Equal(t, http.StatusNotFound, serve(t, router, http.MethodPost, "/webhooks/deliveries/missing/redeliver", nil))
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/config"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+func TestSign_VerifiesWithTheSameSecret(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"event-1"}`)
+
+	header := Sign("secret", at, body)
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify("secret", header, body, at.Add(time.Minute), 5*time.Minute))
+	assert.ErrorIs(t, Verify("other", header, body, at, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, []byte(`{"id":"event-2"}`), at, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", "v1=abc", body, at, 5*time.Minute), ErrInvalidSignature)
+}
+
+func TestVerify_RefusesOldSignatures(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	header := Sign("secret", at, body)
+
+	assert.ErrorIs(t, Verify("secret", header, body, at.Add(10*time.Minute), 5*time.Minute), ErrExpiredSignature)
+	assert.ErrorIs(t, Verify("secret", header, body, at.Add(-10*time.Minute), 5*time.Minute), ErrExpiredSignature)
+	assert.NoError(t, Verify("secret", header, body, at.Add(10*time.Minute), 0), "no tolerance, no timestamp check")
+}
+
+func TestEventType(t *testing.T) {
+	assert.Equal(t, "deposit.approved", EventType(orderShared.Order{Type: orderShared.TypeSale, Status: orderShared.StatusApproved}))
+	assert.Equal(t, "payout.declined", EventType(orderShared.Order{Type: orderShared.TypePayout, Status: orderShared.StatusDeclined}))
+	assert.Equal(t, "deposit.pending", EventType(orderShared.Order{Status: orderShared.StatusPending}))
+}
+
+func TestSubscribes(t *testing.T) {
+	subscription := config.WebhookSubscription{Events: []string{"deposit.approved", "payout.*"}}
+
+	assert.True(t, Subscribes(subscription, "deposit.approved"))
+	assert.True(t, Subscribes(subscription, "payout.pending"))
+	assert.False(t, Subscribes(subscription, "deposit.declined"))
+	assert.True(t, Subscribes(config.WebhookSubscription{Events: []string{"*"}}, "deposit.declined"))
+}
@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+const deliveryColumns = `id, event_id, event_type, subscription, url, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+// addedColumns - columns introduced after the first schema, added to existing databases on start
+var addedColumns []struct{ table, column, definition string }
+
+// SQLiteStore keeps the deliveries in an embedded SQLite database, pending deliveries survive a restart
+type SQLiteStore struct {
+	db        *sql.DB
+	retention time.Duration
+
+	mu          sync.Mutex
+	lastEvicted time.Time
+	now         func() time.Time
+}
+
+// NewSQLiteStore keeps delivered deliveries for retention, pending deliveries and dead letters stay until handled
+func NewSQLiteStore(dsn string, retention time.Duration) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhook store: %w", err)
+	}
+	// SQLite allows a single writer, serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate webhook store: %w", err)
+	}
+	return &SQLiteStore{db: db, retention: retention, now: time.Now}, nil
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+
+	for _, added := range addedColumns {
+		exists, err := hasColumn(db, added.table, added.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, added.table, added.column, added.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	return count > 0, err
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Add(deliveries ...Delivery) error {
+	if err := s.evictDelivered(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delivery := range deliveries {
+		_, err := tx.Exec(`INSERT INTO webhook_deliveries (`+deliveryColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			delivery.ID, delivery.EventID, delivery.EventType, delivery.Subscription, delivery.URL, []byte(delivery.Payload),
+			delivery.Status, delivery.Attempts, nullableTime(delivery.NextAttemptAt), delivery.LastError,
+			delivery.CreatedAt.UnixNano(), delivery.UpdatedAt.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to insert webhook delivery %s: %w", delivery.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Update(delivery Delivery, attempt *Attempt) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE webhook_deliveries SET event_id = ?, event_type = ?, subscription = ?, url = ?, payload = ?,
+		status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, created_at = ?, updated_at = ? WHERE id = ?`,
+		delivery.EventID, delivery.EventType, delivery.Subscription, delivery.URL, []byte(delivery.Payload),
+		delivery.Status, delivery.Attempts, nullableTime(delivery.NextAttemptAt), delivery.LastError,
+		delivery.CreatedAt.UnixNano(), delivery.UpdatedAt.UnixNano(), delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %s: %w", delivery.ID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	if attempt != nil {
+		_, err = tx.Exec(`INSERT INTO webhook_attempts (delivery_id, number, status_code, error, duration_ms, at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			delivery.ID, attempt.Number, attempt.StatusCode, attempt.Error, attempt.DurationMs, attempt.At.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to record webhook delivery %s attempt: %w", delivery.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Find(id string) (*Delivery, error) {
+	row := s.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+
+	delivery, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook delivery %s: %w", id, err)
+	}
+	return delivery, nil
+}
+
+func (s *SQLiteStore) Due(now time.Time, limit int) ([]Delivery, error) {
+	// a delivery without a next attempt is due right away, NULLs sort first
+	return s.query(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?) ORDER BY next_attempt_at LIMIT ?`,
+		StatusPending, now.UnixNano(), sqlLimit(limit))
+}
+
+func (s *SQLiteStore) List(status string, limit int) ([]Delivery, error) {
+	if status == "" {
+		return s.query(`SELECT `+deliveryColumns+` FROM webhook_deliveries ORDER BY created_at DESC LIMIT ?`, sqlLimit(limit))
+	}
+	return s.query(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE status = ? ORDER BY created_at DESC LIMIT ?`,
+		status, sqlLimit(limit))
+}
+
+func (s *SQLiteStore) Attempts(id string) ([]Attempt, error) {
+	if _, err := s.Find(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT delivery_id, number, status_code, error, duration_ms, at
+		FROM webhook_attempts WHERE delivery_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook delivery %s attempts: %w", id, err)
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+	for rows.Next() {
+		var attempt Attempt
+		var at int64
+		if err := rows.Scan(&attempt.DeliveryID, &attempt.Number, &attempt.StatusCode, &attempt.Error,
+			&attempt.DurationMs, &at); err != nil {
+			return nil, err
+		}
+		attempt.At = time.Unix(0, at).UTC()
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func (s *SQLiteStore) query(query string, args ...any) ([]Delivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// evictDelivered drops the deliveries that succeeded longer than the retention ago with their log, at most every evictionInterval
+func (s *SQLiteStore) evictDelivered() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastEvicted) < evictionInterval {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before := now.Add(-s.retention).UnixNano()
+	if _, err := tx.Exec(`DELETE FROM webhook_attempts WHERE delivery_id IN
+		(SELECT id FROM webhook_deliveries WHERE status = ? AND updated_at < ?)`, StatusDelivered, before); err != nil {
+		return fmt.Errorf("failed to evict webhook attempts: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE status = ? AND updated_at < ?`, StatusDelivered, before); err != nil {
+		return fmt.Errorf("failed to evict webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.lastEvicted = now
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(row scanner) (*Delivery, error) {
+	var delivery Delivery
+	var payload []byte
+	var nextAttemptAt sql.NullInt64
+	var createdAt, updatedAt int64
+	err := row.Scan(&delivery.ID, &delivery.EventID, &delivery.EventType, &delivery.Subscription, &delivery.URL, &payload,
+		&delivery.Status, &delivery.Attempts, &nextAttemptAt, &delivery.LastError, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = time.Unix(0, nextAttemptAt.Int64).UTC()
+	}
+	delivery.CreatedAt = time.Unix(0, createdAt).UTC()
+	delivery.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return &delivery, nil
+}
+
+// nullableTime stores the zero time, which has no Unix time, as NULL
+func nullableTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+// sqlLimit - SQLite reads a negative limit as no limit
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+	"zota-dev-challenge/internal/config"
+	orderCommon "zota-dev-challenge/internal/order/common"
+)
+
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// Delivery statuses
+const (
+	// StatusPending - waiting for its next attempt
+	StatusPending = "pending"
+	// StatusDelivered - the subscriber answered 2xx
+	StatusDelivered = "delivered"
+	// StatusDead - every attempt failed, only a redelivery sends it again
+	StatusDead = "dead"
+)
+
+// Delivery - an event on its way to one subscription
+type Delivery struct {
+	ID           string          `json:"id"`
+	EventID      string          `json:"eventId"`
+	EventType    string          `json:"eventType"`
+	Subscription string          `json:"subscription"`
+	URL          string          `json:"url"`
+	Payload      json.RawMessage `json:"payload" swaggertype:"object"`
+	Status       string          `json:"status"`
+	// Attempts - the attempts since the delivery was created or last redelivered
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+} //@name WebhookDelivery
+
+// Attempt - an entry of the delivery log
+type Attempt struct {
+	DeliveryID string `json:"deliveryId"`
+	Number     int    `json:"number"`
+	// StatusCode - what the subscriber answered, 0 when it could not be reached
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	At         time.Time `json:"at"`
+} //@name WebhookAttempt
+
+type Store interface {
+	Add(deliveries ...Delivery) error
+	// Update stores the delivery, the attempt is appended to its log unless nil
+	Update(delivery Delivery, attempt *Attempt) error
+	Find(id string) (*Delivery, error)
+	// Due returns up to limit pending deliveries whose next attempt is not after now, the oldest first
+	Due(now time.Time, limit int) ([]Delivery, error)
+	// List returns up to limit deliveries with the status, any status when empty, the newest first
+	List(status string, limit int) ([]Delivery, error)
+	// Attempts returns the delivery log of the delivery, the oldest attempt first
+	Attempts(id string) ([]Attempt, error)
+}
+
+const defaultSQLiteDSN = "webhooks.db"
+
+// NewStore keeps the deliveries in the kind of store the orders are kept in, in-memory by default
+func NewStore(lc fx.Lifecycle, logger *zap.Logger, config *config.Config) (Store, error) {
+	switch config.OrderStore {
+	case "", orderCommon.StoreMemory:
+		logger.Info("Using in-memory webhook store")
+		return NewMemoryStore(config.WebhookRetention), nil
+	case orderCommon.StoreSQLite:
+		dsn := config.WebhookStoreDSN
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+
+		store, err := NewSQLiteStore(dsn, config.WebhookRetention)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return store.Close()
+			},
+		})
+
+		logger.Info("Using SQLite webhook store", zap.String("dsn", dsn))
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown webhook store %q", config.OrderStore)
+	}
+}
+
+// evictionInterval - how often the delivered deliveries past their retention are swept
+const evictionInterval = time.Minute
+
+// MemoryStore keeps the deliveries in process, pending deliveries are lost on restart
+type MemoryStore struct {
+	retention time.Duration
+
+	mu          sync.Mutex
+	deliveries  map[string]*Delivery
+	attempts    map[string][]Attempt
+	lastEvicted time.Time
+	now         func() time.Time
+}
+
+// NewMemoryStore keeps delivered deliveries for retention, pending deliveries and dead letters stay until handled
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention:  retention,
+		deliveries: make(map[string]*Delivery),
+		attempts:   make(map[string][]Attempt),
+		now:        time.Now,
+	}
+}
+
+func (s *MemoryStore) Add(deliveries ...Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastEvicted) >= evictionInterval {
+		s.evictDelivered(now)
+		s.lastEvicted = now
+	}
+
+	for _, delivery := range deliveries {
+		delivery := delivery
+		s.deliveries[delivery.ID] = &delivery
+	}
+	return nil
+}
+
+func (s *MemoryStore) Update(delivery Delivery, attempt *Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	s.deliveries[delivery.ID] = &delivery
+	if attempt != nil {
+		s.attempts[delivery.ID] = append(s.attempts[delivery.ID], *attempt)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Find(id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	found := *delivery
+	return &found, nil
+}
+
+func (s *MemoryStore) Due(now time.Time, limit int) ([]Delivery, error) {
+	deliveries := s.collect(func(delivery *Delivery) bool {
+		return delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now)
+	})
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	return truncate(deliveries, limit), nil
+}
+
+func (s *MemoryStore) List(status string, limit int) ([]Delivery, error) {
+	deliveries := s.collect(func(delivery *Delivery) bool {
+		return status == "" || delivery.Status == status
+	})
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return truncate(deliveries, limit), nil
+}
+
+func (s *MemoryStore) Attempts(id string) ([]Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[id]; !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	return append([]Attempt(nil), s.attempts[id]...), nil
+}
+
+func (s *MemoryStore) collect(match func(*Delivery) bool) []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []Delivery
+	for _, delivery := range s.deliveries {
+		if match(delivery) {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries
+}
+
+// evictDelivered drops the deliveries that succeeded longer than the retention ago, with their log
+func (s *MemoryStore) evictDelivered(now time.Time) {
+	for id, delivery := range s.deliveries {
+		if delivery.Status == StatusDelivered && now.Sub(delivery.UpdatedAt) > s.retention {
+			delete(s.deliveries, id)
+			delete(s.attempts, id)
+		}
+	}
+}
+
+func truncate(deliveries []Delivery, limit int) []Delivery {
+	if limit > 0 && len(deliveries) > limit {
+		return deliveries[:limit]
+	}
+	return deliveries
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDelivery(id string, createdAt time.Time) Delivery {
+	return Delivery{ID: id, EventID: "event-" + id, EventType: "deposit.approved", Subscription: "ledger",
+		URL: "https://ledger.example.com/webhooks", Payload: json.RawMessage(`{"id":"event-` + id + `"}`),
+		Status: StatusPending, NextAttemptAt: createdAt, CreatedAt: createdAt, UpdatedAt: createdAt}
+}
+
+func testStore(t *testing.T, store Store, now *time.Time) {
+	start := *now
+	first := newTestDelivery("first", start)
+	second := newTestDelivery("second", start.Add(time.Second))
+	require.NoError(t, store.Add(first, second))
+
+	found, err := store.Find("first")
+	require.NoError(t, err)
+	assert.Equal(t, first.EventID, found.EventID)
+	assert.JSONEq(t, string(first.Payload), string(found.Payload))
+	assert.True(t, found.NextAttemptAt.Equal(start))
+
+	_, err = store.Find("unknown")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	due, err := store.Due(start, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "the second delivery is not due yet")
+	assert.Equal(t, "first", due[0].ID)
+
+	// the first delivery fails and is retried later, the second one is now the oldest due
+	first.Attempts = 1
+	first.LastError = "subscriber answered 500"
+	first.NextAttemptAt = start.Add(time.Minute)
+	require.NoError(t, store.Update(first, &Attempt{DeliveryID: "first", Number: 1, StatusCode: 500, DurationMs: 12, At: start}))
+	due, err = store.Due(start.Add(time.Minute), 1)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "second", due[0].ID)
+
+	assert.ErrorIs(t, store.Update(newTestDelivery("unknown", start), nil), ErrDeliveryNotFound)
+
+	// a delivered delivery no longer has a next attempt
+	first.Status = StatusDelivered
+	first.Attempts = 2
+	first.LastError = ""
+	first.NextAttemptAt = time.Time{}
+	first.UpdatedAt = start.Add(time.Minute)
+	require.NoError(t, store.Update(first, &Attempt{DeliveryID: "first", Number: 2, StatusCode: 200, DurationMs: 8, At: start.Add(time.Minute)}))
+	found, err = store.Find("first")
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, found.Status)
+	assert.True(t, found.NextAttemptAt.IsZero())
+
+	attempts, err := store.Attempts("first")
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, 500, attempts[0].StatusCode)
+	assert.Equal(t, 200, attempts[1].StatusCode)
+
+	_, err = store.Attempts("unknown")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	all, err := store.List("", 0)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "second", all[0].ID, "the newest delivery comes first")
+
+	delivered, err := store.List(StatusDelivered, 10)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, "first", delivered[0].ID)
+
+	// a delivered delivery is dropped with its log once past the retention, pending ones stay
+	*now = start.Add(time.Minute + time.Hour + evictionInterval)
+	require.NoError(t, store.Add(newTestDelivery("third", *now)))
+	_, err = store.Find("first")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	_, err = store.Find("second")
+	assert.NoError(t, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(time.Hour)
+	store.now = func() time.Time { return now }
+
+	testStore(t, store, &now)
+}
+
+func TestSQLiteStore(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "webhooks.db"), time.Hour)
+	require.NoError(t, err)
+	defer store.Close()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	testStore(t, store, &now)
+}
+
+func TestSQLiteStore_KeepsPendingDeliveriesAcrossRestarts(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "webhooks.db")
+	store, err := NewSQLiteStore(dsn, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(newTestDelivery("pending", time.Now())))
+	require.NoError(t, store.Close())
+
+	store, err = NewSQLiteStore(dsn, time.Hour)
+	require.NoError(t, err)
+	defer store.Close()
+
+	due, err := store.Due(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "pending", due[0].ID)
+}
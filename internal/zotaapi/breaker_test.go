@@ -0,0 +1,51 @@
+package zotaapi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	breaker.Success()
+	breaker.Failure()
+	assert.True(t, breaker.Allow(), "a success resets the failure count")
+
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+	assert.True(t, breaker.IsOpen())
+}
+
+func TestBreaker_HalfOpenTrial(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	now = now.Add(time.Minute)
+
+	assert.True(t, breaker.Allow(), "the cool-down is over, a trial call goes through")
+	assert.False(t, breaker.Allow(), "only a single trial call at a time")
+
+	breaker.Failure()
+	assert.False(t, breaker.Allow(), "a failed trial opens the breaker again")
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	breaker.Success()
+	assert.True(t, breaker.Allow())
+	assert.True(t, breaker.Allow())
+}
+
+func TestBreaker_ZeroThresholdNeverOpens(t *testing.T) {
+	breaker := NewBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	assert.True(t, breaker.Allow())
+}
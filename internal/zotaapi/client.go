@@ -0,0 +1,192 @@
+package zotaapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+)
+
+// Request - a single call to the Zota API
+type Request struct {
+	Method string
+	URL    string
+	Body   []byte
+	// Idempotent requests, e.g. status queries, are retried on every transient failure.
+	// The others only when they could not have reached Zota, so a deposit is never sent twice.
+	Idempotent bool
+	// Stream leaves the body of an OK answer unread, for answers too large to hold in memory such as reports
+	Stream bool
+}
+
+// Response - the raw answer of Zota, decoded by the gateways
+type Response struct {
+	StatusCode int
+	Body       []byte
+	// Stream - the unread body of an OK answer to a streamed request, instead of Body. The caller must close it.
+	Stream io.ReadCloser
+}
+
+// Client - the HTTP client shared by every Zota gateway, it pools connections,
+// retries transient failures and stops calling Zota while it is degraded
+type Client struct {
+	logger     *zap.Logger
+	httpClient *http.Client
+	breaker    *Breaker
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	jitter         func(max time.Duration) time.Duration
+}
+
+func NewClient(logger *zap.Logger, config *config.Config) *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   config.ZotaConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   config.ZotaConnectTimeout,
+		ResponseHeaderTimeout: config.ZotaReadTimeout,
+		MaxIdleConns:          config.ZotaMaxIdleConns,
+		MaxIdleConnsPerHost:   config.ZotaMaxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	return &Client{
+		logger:         logger,
+		httpClient:     &http.Client{Transport: transport},
+		breaker:        NewBreaker(config.ZotaBreakerThreshold, config.ZotaBreakerCooldown),
+		maxAttempts:    config.ZotaMaxAttempts,
+		retryBaseDelay: config.ZotaRetryBaseDelay,
+		retryMaxDelay:  config.ZotaRetryMaxDelay,
+		jitter: func(max time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(max) + 1))
+		},
+	}
+}
+
+// Do sends the request, transport failures come back as typed gateway errors.
+// Any HTTP answer is returned as is, non-OK ones included.
+func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.Allow() {
+			c.logger.Warn("Zota circuit breaker is open, failing fast", zap.String("url", req.URL))
+			return nil, apperror.GatewayUnavailable("payment gateway is unavailable", ErrCircuitOpen)
+		}
+
+		resp, err := c.send(ctx, req)
+		c.record(ctx, resp, err)
+
+		if !c.shouldRetry(req, resp, err) || attempt >= c.maxAttempts {
+			if err != nil {
+				return nil, apperror.GatewayUnavailable("payment gateway is unreachable", err)
+			}
+			return resp, nil
+		}
+
+		delay := c.backoff(attempt)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt), attribute.String("delay", delay.String())))
+		c.logger.Warn("Retrying Zota request",
+			zap.String("url", req.URL), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil, apperror.GatewayUnavailable("payment gateway is unreachable", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Probe reports whether Zota can be reached at all, any HTTP answer counts.
+// It bypasses the retries and the breaker, so health checks neither wait for nor trip them.
+func (c *Client) Probe(ctx context.Context, url string) error {
+	_, err := c.send(ctx, Request{Method: http.MethodGet, URL: url})
+	return err
+}
+
+func (c *Client) send(ctx context.Context, req Request) (*Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stream && resp.StatusCode == http.StatusOK {
+		return &Response{StatusCode: resp.StatusCode, Stream: resp.Body}, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: body}, nil
+}
+
+// record feeds the outcome to the breaker, a call the caller gave up on says nothing about Zota
+func (c *Client) record(ctx context.Context, resp *Response, err error) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		c.breaker.Release()
+	case err != nil || isTransientStatus(resp.StatusCode):
+		c.breaker.Failure()
+	default:
+		c.breaker.Success()
+	}
+}
+
+func (c *Client) shouldRetry(req Request, resp *Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		isNetwork := errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !isNetwork {
+			return false
+		}
+		return req.Idempotent || isDialError(err)
+	}
+	return req.Idempotent && isTransientStatus(resp.StatusCode)
+}
+
+// backoff - full jitter exponential back-off
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryBaseDelay << (attempt - 1)
+	if delay > c.retryMaxDelay || delay <= 0 {
+		delay = c.retryMaxDelay
+	}
+	return c.jitter(delay)
+}
+
+// NotSent reports whether a failed call certainly never reached Zota: the breaker failed it fast or the connection
+// was never established. Any other transport failure may have happened after Zota received the request.
+func NotSent(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || isDialError(err)
+}
+
+// isDialError - the connection was never established, so the request cannot have reached Zota
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isTransientStatus(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
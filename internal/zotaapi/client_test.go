@@ -0,0 +1,204 @@
+package zotaapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	"zota-dev-challenge/internal/config"
+)
+
+type clientTestSuite struct {
+	config *config.Config
+	client *Client
+	calls  atomic.Int32
+	server *httptest.Server
+}
+
+func (s *clientTestSuite) setup(t *testing.T, handler func(call int32, w http.ResponseWriter)) {
+	s.config = &config.Config{
+		ZotaConnectTimeout:   time.Second,
+		ZotaReadTimeout:      time.Second,
+		ZotaMaxIdleConns:     2,
+		ZotaMaxAttempts:      3,
+		ZotaRetryBaseDelay:   time.Millisecond,
+		ZotaRetryMaxDelay:    time.Millisecond,
+		ZotaBreakerThreshold: 3,
+		ZotaBreakerCooldown:  time.Minute,
+	}
+	s.client = NewClient(zap.NewNop(), s.config)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(s.calls.Add(1), w)
+	}))
+	t.Cleanup(s.server.Close)
+}
+
+func TestClient_ReturnsNonOKAnswers(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"400"}`))
+	})
+
+	resp, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, `{"code":"400"}`, string(resp.Body))
+	assert.Equal(t, int32(1), s.calls.Load(), "a rejected request is not retried")
+}
+
+func TestClient_StreamsOKAnswers(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		if call == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ID,Status\n"))
+	})
+
+	resp, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true, Stream: true})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Stream)
+	defer resp.Stream.Close()
+	assert.Empty(t, resp.Body)
+
+	body, err := io.ReadAll(resp.Stream)
+	require.NoError(t, err)
+	assert.Equal(t, "ID,Status\n", string(body))
+	assert.Equal(t, int32(2), s.calls.Load(), "failed answers are read and retried as usual")
+}
+
+func TestClient_RetriesIdempotentRequestsOnServerErrors(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		if call < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"code":"200"}`))
+	})
+
+	resp, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), s.calls.Load())
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	resp, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(3), s.calls.Load())
+}
+
+func TestClient_DoesNotRetryNonIdempotentRequestsThatReachedZota(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	resp, err := s.client.Do(context.Background(), Request{Method: http.MethodPost, URL: s.server.URL, Body: []byte(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(1), s.calls.Load())
+}
+
+func TestClient_RetriesNonIdempotentRequestsThatNeverConnected(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {})
+
+	// a closed port refuses the connection before anything is sent
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "http://" + listener.Addr().String()
+	listener.Close()
+
+	attempts := 0
+	s.client.jitter = func(max time.Duration) time.Duration {
+		attempts++
+		return 0
+	}
+
+	_, err = s.client.Do(context.Background(), Request{Method: http.MethodPost, URL: url, Body: []byte(`{}`)})
+	assert.Equal(t, apperror.CodeGatewayUnavailable, apperror.From(err).Code)
+	assert.Equal(t, 2, attempts, "every attempt but the last waits before retrying")
+}
+
+func TestClient_CircuitBreakerFailsFast(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	s.config.ZotaMaxAttempts = 1
+	s.client = NewClient(zap.NewNop(), s.config)
+
+	for i := 0; i < 3; i++ {
+		_, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+		require.NoError(t, err)
+	}
+
+	_, err := s.client.Do(context.Background(), Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, apperror.CodeGatewayUnavailable, apperror.From(err).Code)
+	assert.Equal(t, int32(3), s.calls.Load(), "an open breaker does not call Zota")
+}
+
+func TestClient_CancelledCallsDoNotTripTheBreaker(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {})
+	s.config.ZotaBreakerThreshold = 1
+	s.client = NewClient(zap.NewNop(), s.config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.client.Do(ctx, Request{Method: http.MethodGet, URL: s.server.URL, Idempotent: true})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.False(t, s.client.breaker.IsOpen())
+}
+
+func TestClient_Backoff(t *testing.T) {
+	client := &Client{
+		retryBaseDelay: 100 * time.Millisecond,
+		retryMaxDelay:  time.Second,
+		jitter:         func(max time.Duration) time.Duration { return max },
+	}
+
+	assert.Equal(t, 100*time.Millisecond, client.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, client.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, client.backoff(3))
+	assert.Equal(t, time.Second, client.backoff(5))
+	assert.Equal(t, time.Second, client.backoff(80))
+}
+
+func TestClient_ProbeIgnoresStatusAndBreaker(t *testing.T) {
+	s := &clientTestSuite{}
+	s.setup(t, func(call int32, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	s.config.ZotaBreakerThreshold = 1
+	s.client = NewClient(zap.NewNop(), s.config)
+
+	// any HTTP answer means Zota can be reached
+	assert.NoError(t, s.client.Probe(context.Background(), s.server.URL))
+	assert.Equal(t, int32(1), s.calls.Load(), "a probe must not be retried")
+	assert.False(t, s.client.breaker.IsOpen())
+
+	s.server.Close()
+	assert.Error(t, s.client.Probe(context.Background(), s.server.URL))
+	assert.False(t, s.client.breaker.IsOpen())
+}
@@ -0,0 +1,14 @@
+package zotaapi
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout bounds a single Zota call, a zero timeout leaves the deadline to the caller's context
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,72 @@
+package zotaapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"zota-dev-challenge/internal/apperror"
+)
+
+// CodeOK - the code field of every successful Zota answer
+const CodeOK = "200"
+
+// Envelope - Zota wraps every answer, successful or not, in a code and a message
+type Envelope struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error - a failure reported by Zota in its error envelope
+type Error struct {
+	HTTPStatus int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("zota answered with code %s (HTTP %d): %s", e.Code, e.HTTPStatus, e.Message)
+}
+
+// DecodeResponse unmarshals a Zota answer into response.
+// Error envelopes become typed gateway errors that carry Zota's code and message.
+func DecodeResponse(statusCode int, body []byte, response any) error {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		if statusCode != http.StatusOK {
+			return apperror.FromHTTPStatus(statusCode,
+				fmt.Errorf("received non-OK response from Zota server: %s", http.StatusText(statusCode)))
+		}
+		return apperror.GatewayError("malformed payment gateway response", err)
+	}
+
+	if statusCode != http.StatusOK || envelope.Code != CodeOK {
+		return newGatewayError(statusCode, envelope)
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return apperror.GatewayError("malformed payment gateway response", err)
+	}
+	return nil
+}
+
+func newGatewayError(statusCode int, envelope Envelope) *apperror.Error {
+	zotaErr := &Error{HTTPStatus: statusCode, Code: envelope.Code, Message: envelope.Message}
+
+	// Zota may answer HTTP 200 with an error code in the body, the code then decides
+	status := statusCode
+	if status == http.StatusOK {
+		if code, err := strconv.Atoi(envelope.Code); err == nil {
+			status = code
+		}
+	}
+
+	appErr := apperror.FromHTTPStatus(status, zotaErr)
+	if envelope.Message != "" {
+		appErr.Message = appErr.Message + ": " + envelope.Message
+	}
+	if envelope != (Envelope{}) {
+		appErr.Gateway = &apperror.GatewayFailure{Code: envelope.Code, Message: envelope.Message}
+	}
+	return appErr
+}
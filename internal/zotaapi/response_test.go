@@ -0,0 +1,56 @@
+package zotaapi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"zota-dev-challenge/internal/apperror"
+)
+
+type testResponse struct {
+	Envelope
+	Data *struct {
+		OrderID string `json:"orderID"`
+	} `json:"data,omitempty"`
+}
+
+func TestDecodeResponse_Success(t *testing.T) {
+	var response testResponse
+	err := DecodeResponse(http.StatusOK, []byte(`{"code":"200","data":{"orderID":"1"}}`), &response)
+	require.NoError(t, err)
+	assert.Equal(t, "1", response.Data.OrderID)
+}
+
+func TestDecodeResponse_ErrorEnvelopes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		statusCode   int
+		body         string
+		expectedCode string
+		gateway      *apperror.GatewayFailure
+	}{
+		"rejected":               {http.StatusBadRequest, `{"code":"400","message":"bad amount"}`, apperror.CodeGatewayRejected, &apperror.GatewayFailure{Code: "400", Message: "bad amount"}},
+		"error code in HTTP 200": {http.StatusOK, `{"code":"401","message":"bad signature"}`, apperror.CodeGatewayRejected, &apperror.GatewayFailure{Code: "401", Message: "bad signature"}},
+		"server error":           {http.StatusBadGateway, `{"code":"502"}`, apperror.CodeGatewayUnavailable, &apperror.GatewayFailure{Code: "502"}},
+		"no envelope":            {http.StatusServiceUnavailable, `<html>down</html>`, apperror.CodeGatewayUnavailable, nil},
+		"malformed success":      {http.StatusOK, `not json`, apperror.CodeGatewayError, nil},
+		"missing code":           {http.StatusOK, `{}`, apperror.CodeGatewayError, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var response testResponse
+			appErr := apperror.From(DecodeResponse(tc.statusCode, []byte(tc.body), &response))
+			assert.Equal(t, tc.expectedCode, appErr.Code)
+			assert.Equal(t, tc.gateway, appErr.Gateway)
+			assert.Nil(t, response.Data)
+		})
+	}
+}
+
+func TestDecodeResponse_MessageReachesClients(t *testing.T) {
+	err := DecodeResponse(http.StatusBadRequest, []byte(`{"code":"400","message":"bad amount"}`), &testResponse{})
+	assert.Equal(t, "payment gateway rejected the request: bad amount", apperror.From(err).Message)
+
+	var zotaErr *Error
+	require.ErrorAs(t, err, &zotaErr)
+	assert.Equal(t, &Error{HTTPStatus: http.StatusBadRequest, Code: "400", Message: "bad amount"}, zotaErr)
+}
@@ -0,0 +1,321 @@
+package zotasim
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+// DepositRequest - the body of a deposit request, as Zota expects it
+type DepositRequest struct {
+	MerchantOrderID     string `json:"merchantOrderID"`
+	MerchantOrderDesc   string `json:"merchantOrderDesc"`
+	OrderAmount         string `json:"orderAmount"`
+	OrderCurrency       string `json:"orderCurrency"`
+	CustomerEmail       string `json:"customerEmail"`
+	CustomerFirstName   string `json:"customerFirstName"`
+	CustomerLastName    string `json:"customerLastName"`
+	CustomerAddress     string `json:"customerAddress"`
+	CustomerCountryCode string `json:"customerCountryCode"`
+	CustomerCity        string `json:"customerCity"`
+	CustomerZipCode     string `json:"customerZipCode"`
+	CustomerPhone       string `json:"customerPhone"`
+	CustomerIP          string `json:"customerIP"`
+	RedirectUrl         string `json:"redirectUrl"`
+	CallbackUrl         string `json:"callbackUrl"`
+	CustomParam         string `json:"customParam"`
+	CheckoutUrl         string `json:"checkoutUrl"`
+	Signature           string `json:"signature"`
+}
+
+// PayoutRequest - the body of a payout request, as Zota expects it
+type PayoutRequest struct {
+	MerchantOrderID           string `json:"merchantOrderID"`
+	MerchantOrderDesc         string `json:"merchantOrderDesc"`
+	OrderAmount               string `json:"orderAmount"`
+	OrderCurrency             string `json:"orderCurrency"`
+	CustomerEmail             string `json:"customerEmail"`
+	CustomerFirstName         string `json:"customerFirstName"`
+	CustomerLastName          string `json:"customerLastName"`
+	CustomerBankAccountNumber string `json:"customerBankAccountNumber"`
+	CustomerBankAccountName   string `json:"customerBankAccountName"`
+	CallbackUrl               string `json:"callbackUrl"`
+	CustomParam               string `json:"customParam"`
+	Signature                 string `json:"signature"`
+}
+
+// OrderData - an order in the data of a status answer and a callback
+type OrderData struct {
+	Type                   string `json:"type"`
+	Status                 string `json:"status"`
+	ErrorMessage           string `json:"errorMessage,omitempty"`
+	EndpointID             string `json:"endpointID"`
+	ProcessorTransactionID string `json:"processorTransactionID"`
+	OrderID                string `json:"orderID"`
+	MerchantOrderID        string `json:"merchantOrderID"`
+	Amount                 string `json:"amount"`
+	Currency               string `json:"currency"`
+	CustomerEmail          string `json:"customerEmail"`
+	CustomParam            string `json:"customParam"`
+	ExtraData              any    `json:"extraData"`
+}
+
+var errInvalidSignature = errors.New("invalid signature")
+
+func (s *Simulator) handleDeposit(w http.ResponseWriter, r *http.Request) {
+	endpointID := chi.URLParam(r, "endpointID")
+
+	var req DepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if missing := missingField(map[string]string{
+		"merchantOrderID": req.MerchantOrderID, "merchantOrderDesc": req.MerchantOrderDesc, "orderAmount": req.OrderAmount,
+		"orderCurrency": req.OrderCurrency, "customerEmail": req.CustomerEmail, "customerFirstName": req.CustomerFirstName,
+		"customerLastName": req.CustomerLastName, "customerAddress": req.CustomerAddress,
+		"customerCountryCode": req.CustomerCountryCode, "customerCity": req.CustomerCity,
+		"customerZipCode": req.CustomerZipCode, "customerPhone": req.CustomerPhone, "customerIP": req.CustomerIP,
+		"redirectUrl": req.RedirectUrl, "checkoutUrl": req.CheckoutUrl, "signature": req.Signature,
+	}); missing != "" {
+		s.writeError(w, http.StatusBadRequest, "missing "+missing)
+		return
+	}
+	if err := s.verify(req.Signature, endpointID, req.MerchantOrderID, req.OrderAmount, req.CustomerEmail); err != nil {
+		s.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	order := &Order{
+		Type:            orderShared.TypeSale,
+		EndpointID:      endpointID,
+		MerchantOrderID: req.MerchantOrderID,
+		Amount:          req.OrderAmount,
+		Currency:        req.OrderCurrency,
+		CustomerEmail:   req.CustomerEmail,
+		CustomParam:     req.CustomParam,
+		CallbackURL:     req.CallbackUrl,
+		RedirectURL:     req.RedirectUrl,
+		Scenario:        s.scenarioFor(req.MerchantOrderID, req.CustomerEmail),
+	}
+	if !s.accept(w, r, order) {
+		return
+	}
+
+	s.writeData(w, order.Scenario, map[string]string{
+		"depositUrl":      s.paymentURL(order.OrderID),
+		"merchantOrderID": order.MerchantOrderID,
+		"orderID":         order.OrderID,
+	})
+}
+
+func (s *Simulator) handlePayout(w http.ResponseWriter, r *http.Request) {
+	endpointID := chi.URLParam(r, "endpointID")
+
+	var req PayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if missing := missingField(map[string]string{
+		"merchantOrderID": req.MerchantOrderID, "merchantOrderDesc": req.MerchantOrderDesc, "orderAmount": req.OrderAmount,
+		"orderCurrency": req.OrderCurrency, "customerEmail": req.CustomerEmail,
+		"customerBankAccountNumber": req.CustomerBankAccountNumber, "signature": req.Signature,
+	}); missing != "" {
+		s.writeError(w, http.StatusBadRequest, "missing "+missing)
+		return
+	}
+	if err := s.verify(req.Signature, endpointID, req.MerchantOrderID, req.OrderAmount, req.CustomerEmail,
+		req.CustomerBankAccountNumber); err != nil {
+		s.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	order := &Order{
+		Type:            orderShared.TypePayout,
+		EndpointID:      endpointID,
+		MerchantOrderID: req.MerchantOrderID,
+		Amount:          req.OrderAmount,
+		Currency:        req.OrderCurrency,
+		CustomerEmail:   req.CustomerEmail,
+		CustomParam:     req.CustomParam,
+		CallbackURL:     req.CallbackUrl,
+		Scenario:        s.scenarioFor(req.MerchantOrderID, req.CustomerEmail),
+	}
+	if order.Scenario == ScenarioManual {
+		// a payout has no payment page that could decide it
+		order.Scenario = ScenarioApprove
+	}
+	if !s.accept(w, r, order) {
+		return
+	}
+
+	s.writeData(w, order.Scenario, map[string]string{
+		"merchantOrderID": order.MerchantOrderID,
+		"orderID":         order.OrderID,
+	})
+}
+
+// accept plays the scenario of a new order and stores it, it answers the request itself when the order is refused
+func (s *Simulator) accept(w http.ResponseWriter, r *http.Request, order *Order) bool {
+	if order.Scenario == ScenarioServerError {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+	if !s.wait(r, order.Scenario) {
+		return false
+	}
+
+	if err := s.create(order); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Simulator) handleStatus(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	merchantID, orderID, merchantOrderID := query.Get("merchantID"), query.Get("orderID"), query.Get("merchantOrderID")
+	timestamp := query.Get("timestamp")
+
+	if merchantID != s.config.MerchantID {
+		s.writeError(w, http.StatusUnauthorized, "unknown merchantID")
+		return
+	}
+	if err := s.verify(query.Get("signature"), merchantID, merchantOrderID, orderID, timestamp); err != nil {
+		s.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid timestamp")
+		return
+	}
+
+	s.mu.Lock()
+	// an order is looked up by merchant order ID when the merchant never learnt its order ID
+	if orderID == "" {
+		orderID = s.merchantOrders[merchantOrderID]
+	}
+	stored, ok := s.orders[orderID]
+	var order Order
+	if ok {
+		order = *stored
+	}
+	s.mu.Unlock()
+
+	if !ok || order.MerchantOrderID != merchantOrderID {
+		s.writeError(w, http.StatusNotFound, "order not found")
+		return
+	}
+	if order.Scenario == ScenarioServerError {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !s.wait(r, order.Scenario) {
+		return
+	}
+
+	s.writeData(w, order.Scenario, orderData(order))
+}
+
+func (s *Simulator) handleScript(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Scenario string `json:"scenario"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	scenario, err := ParseScenario(body.Scenario)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.Script(chi.URLParam(r, "merchantOrderID"), scenario)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func orderData(order Order) OrderData {
+	return OrderData{
+		Type:                   order.Type,
+		Status:                 order.Status,
+		ErrorMessage:           order.ErrorMessage,
+		EndpointID:             order.EndpointID,
+		ProcessorTransactionID: order.ProcessorTransactionID,
+		OrderID:                order.OrderID,
+		MerchantOrderID:        order.MerchantOrderID,
+		Amount:                 order.Amount,
+		Currency:               order.Currency,
+		CustomerEmail:          order.CustomerEmail,
+		CustomParam:            order.CustomParam,
+		ExtraData:              map[string]any{"amountChanged": false, "originalAmount": order.Amount},
+	}
+}
+
+// verify checks a signature: the SHA-256 of the fields in order, followed by the secret key
+func (s *Simulator) verify(signature string, fields ...string) error {
+	expected := Sign(s.config.SecretKey, fields...)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// Sign returns the signature of the fields the way Zota computes it, hex encoded
+func Sign(secretKey string, fields ...string) string {
+	hash := sha256.New()
+	for _, field := range fields {
+		hash.Write([]byte(field))
+	}
+	hash.Write([]byte(secretKey))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// missingField returns the name of the first empty field, in a stable order so answers are reproducible
+func missingField(fields map[string]string) string {
+	missing := ""
+	for name, value := range fields {
+		if value == "" && (missing == "" || name < missing) {
+			missing = name
+		}
+	}
+	return missing
+}
+
+func (s *Simulator) paymentURL(orderID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("%s/payment/%s", s.config.PublicURL, orderID)
+}
+
+// writeData answers with Zota's success envelope, cut short for ScenarioMalformed orders
+func (s *Simulator) writeData(w http.ResponseWriter, scenario Scenario, data any) {
+	body, err := json.Marshal(map[string]any{"code": "200", "data": data})
+	if err != nil {
+		s.logger.Error("Failed to marshal answer", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if scenario == ScenarioMalformed {
+		body = body[:len(body)/2]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// writeError answers with Zota's error envelope, the code repeats the HTTP status
+func (s *Simulator) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": strconv.Itoa(status), "message": message})
+}
@@ -0,0 +1,171 @@
+package zotasim
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+var paymentPage = template.Must(template.New("payment").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Zota simulator - payment</title></head>
+<body style="font-family: sans-serif; max-width: 32em; margin: 4em auto">
+<h1>Simulated payment</h1>
+<p>Order <code>{{.MerchantOrderID}}</code>: <strong>{{.Amount}} {{.Currency}}</strong></p>
+<p>Status: <strong>{{.Status}}</strong>{{if .ErrorMessage}} ({{.ErrorMessage}}){{end}}</p>
+{{if .Final}}<p>The order is final, nothing left to pay.</p>{{else}}
+<form method="post">
+<button name="outcome" value="approve">Approve</button>
+<button name="outcome" value="decline">Decline</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// handlePaymentPage stands in for the hosted payment page the customer is sent to
+func (s *Simulator) handlePaymentPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	stored, ok := s.orders[chi.URLParam(r, "orderID")]
+	var order Order
+	if ok {
+		order = *stored
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := paymentPage.Execute(w, struct {
+		Order
+		Final bool
+	}{Order: order, Final: orderShared.IsFinal(order.Status)}); err != nil {
+		s.logger.Error("Failed to render payment page", zap.Error(err))
+	}
+}
+
+// handlePaymentSubmit decides the order the way the customer chose and redirects back to the merchant
+func (s *Simulator) handlePaymentSubmit(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderID")
+
+	status := orderShared.StatusApproved
+	if r.FormValue("outcome") == "decline" {
+		status = orderShared.StatusDeclined
+	}
+
+	s.mu.Lock()
+	_, ok := s.orders[orderID]
+	if timer, scheduled := s.timers[orderID]; scheduled && timer.Stop() {
+		s.wg.Done()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.finish(orderID, status)
+
+	order, _ := s.orderByID(orderID)
+	if order.RedirectURL == "" {
+		http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, s.redirectURL(order), http.StatusSeeOther)
+}
+
+func (s *Simulator) orderByID(orderID string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[orderID]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+// redirectURL sends the customer back to the merchant with the signed outcome of the order
+func (s *Simulator) redirectURL(order Order) string {
+	redirect, err := url.Parse(order.RedirectURL)
+	if err != nil {
+		return order.RedirectURL
+	}
+
+	query := redirect.Query()
+	query.Set("status", order.Status)
+	query.Set("errorMessage", order.ErrorMessage)
+	query.Set("orderID", order.OrderID)
+	query.Set("merchantOrderID", order.MerchantOrderID)
+	query.Set("signature", Sign(s.config.SecretKey, order.Status, order.OrderID, order.MerchantOrderID))
+	redirect.RawQuery = query.Encode()
+	return redirect.String()
+}
+
+// CallbackRequest - the notification posted to the callback URL once an order is final
+type CallbackRequest struct {
+	OrderData
+	Signature string `json:"signature"`
+}
+
+// sendCallback posts the signed final status, retrying with back-off until the merchant answers 2xx
+func (s *Simulator) sendCallback(order Order) {
+	callback := CallbackRequest{
+		OrderData: orderData(order),
+		Signature: Sign(s.config.SecretKey, order.EndpointID, order.OrderID, order.MerchantOrderID, order.Status,
+			order.Amount, order.CustomerEmail),
+	}
+	body, err := json.Marshal(callback)
+	if err != nil {
+		s.logger.Error("Failed to marshal callback", zap.Error(err))
+		return
+	}
+	if order.Scenario == ScenarioMalformed {
+		body = body[:len(body)/2]
+	}
+
+	backoff := s.config.CallbackBackoff
+	for attempt := 1; attempt <= s.config.CallbackAttempts; attempt++ {
+		statusCode, err := s.postCallback(order.CallbackURL, body)
+		if err == nil && statusCode/100 == 2 {
+			s.logger.Info("Delivered callback", zap.String("merchantOrderID", order.MerchantOrderID), zap.Int("attempt", attempt))
+			return
+		}
+		s.logger.Warn("Callback was not accepted", zap.String("merchantOrderID", order.MerchantOrderID),
+			zap.Int("attempt", attempt), zap.Int("statusCode", statusCode), zap.Error(err))
+
+		if attempt == s.config.CallbackAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.ctx.Done():
+			return
+		}
+	}
+	s.logger.Error("Gave up on callback", zap.String("merchantOrderID", order.MerchantOrderID))
+}
+
+func (s *Simulator) postCallback(callbackURL string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,124 @@
+package zotasim
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportColumns - the header of the orders report, in the order Zota writes the columns
+var ReportColumns = []string{
+	"ID", "Type", "Status", "Error Message", "Endpoint ID", "Processor Transaction ID", "Merchant Order ID",
+	"Amount", "Currency", "Customer Email", "Custom Param", "Created At", "Ended At",
+}
+
+// report dates are whole days, the report times are written in UTC
+const (
+	reportDateLayout = "2006-01-02"
+	reportTimeLayout = "2006-01-02 15:04:05"
+)
+
+// handleOrdersReport answers the orders of a date range as CSV, filtered by type, status and endpoint
+func (s *Simulator) handleOrdersReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	merchantID := query.Get("merchantID")
+	dateType, fromDate, toDate := query.Get("dateType"), query.Get("fromDate"), query.Get("toDate")
+	endpointIDs, requestID, statuses := query.Get("endpointIds"), query.Get("requestID"), query.Get("statuses")
+	timestamp, types := query.Get("timestamp"), query.Get("types")
+
+	if merchantID != s.config.MerchantID {
+		s.writeError(w, http.StatusUnauthorized, "unknown merchantID")
+		return
+	}
+	if err := s.verify(query.Get("signature"), merchantID, dateType, endpointIDs, fromDate, requestID, statuses,
+		timestamp, toDate, types); err != nil {
+		s.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if requestID == "" {
+		s.writeError(w, http.StatusBadRequest, "missing requestID")
+		return
+	}
+	if dateType == "" {
+		dateType = "created"
+	}
+	if dateType != "created" && dateType != "ended" {
+		s.writeError(w, http.StatusBadRequest, "dateType must be created or ended")
+		return
+	}
+
+	from, errFrom := time.Parse(reportDateLayout, fromDate)
+	to, errTo := time.Parse(reportDateLayout, toDate)
+	if errFrom != nil || errTo != nil || to.Before(from) {
+		s.writeError(w, http.StatusBadRequest, "fromDate and toDate must be dates such as 2024-01-31, in order")
+		return
+	}
+	// toDate is inclusive
+	to = to.AddDate(0, 0, 1)
+
+	filter := reportFilter{types: set(types), statuses: set(statuses), endpointIDs: set(endpointIDs)}
+	orders := s.reportOrders(func(order Order) bool {
+		at := order.CreatedAt
+		if dateType == "ended" {
+			at = order.EndedAt
+		}
+		return !at.IsZero() && !at.Before(from) && at.Before(to) && filter.matches(order)
+	})
+
+	w.Header().Set("Content-Type", "text/csv")
+	writer := csv.NewWriter(w)
+	writer.Write(ReportColumns)
+	for _, order := range orders {
+		writer.Write([]string{
+			order.OrderID, order.Type, order.Status, order.ErrorMessage, order.EndpointID, order.ProcessorTransactionID,
+			order.MerchantOrderID, order.Amount, order.Currency, order.CustomerEmail, order.CustomParam,
+			reportTime(order.CreatedAt), reportTime(order.EndedAt),
+		})
+	}
+	writer.Flush()
+}
+
+// reportOrders returns copies of the matching orders, oldest first
+func (s *Simulator) reportOrders(match func(Order) bool) []Order {
+	s.mu.Lock()
+	var orders []Order
+	for _, order := range s.orders {
+		if match(*order) {
+			orders = append(orders, *order)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders
+}
+
+type reportFilter struct {
+	types, statuses, endpointIDs map[string]bool
+}
+
+// matches - an empty filter matches everything
+func (f reportFilter) matches(order Order) bool {
+	return (len(f.types) == 0 || f.types[order.Type]) &&
+		(len(f.statuses) == 0 || f.statuses[order.Status]) &&
+		(len(f.endpointIDs) == 0 || f.endpointIDs[order.EndpointID])
+}
+
+func set(list string) map[string]bool {
+	values := make(map[string]bool)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values[value] = true
+		}
+	}
+	return values
+}
+
+func reportTime(at time.Time) string {
+	if at.IsZero() {
+		return ""
+	}
+	return at.UTC().Format(reportTimeLayout)
+}
@@ -0,0 +1,307 @@
+// Package zotasim simulates the Zota API for local development and integration tests.
+//
+// It implements the deposit request, order status, payout and orders report endpoints, checks signatures the way
+// Zota does, keeps the orders in memory, serves a fake hosted payment page and posts signed callbacks.
+// Every order follows a scenario, scripted per merchant order ID, tagged on the customer email
+// (e.g. john+decline@example.com) or the simulator's default.
+package zotasim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	orderShared "zota-dev-challenge/internal/order/shared"
+)
+
+// Scenario - how the simulator treats an order
+type Scenario string
+
+const (
+	// ScenarioApprove - the order is approved once processed
+	ScenarioApprove Scenario = "approve"
+	// ScenarioDecline - the order is declined once processed
+	ScenarioDecline Scenario = "decline"
+	// ScenarioDelay - every answer about the order is late by Config.Delay and the order is approved after it
+	ScenarioDelay Scenario = "delay"
+	// ScenarioServerError - every request about the order fails with HTTP 500, nothing is stored
+	ScenarioServerError Scenario = "5xx"
+	// ScenarioMalformed - the order is accepted and approved, but every answer about it is truncated JSON
+	ScenarioMalformed Scenario = "malformed"
+	// ScenarioManual - the order waits until it is approved or declined on the payment page
+	ScenarioManual Scenario = "manual"
+)
+
+var scenarios = map[Scenario]bool{
+	ScenarioApprove: true, ScenarioDecline: true, ScenarioDelay: true,
+	ScenarioServerError: true, ScenarioMalformed: true, ScenarioManual: true,
+}
+
+// ParseScenario returns the scenario of the given name
+func ParseScenario(name string) (Scenario, error) {
+	scenario := Scenario(strings.ToLower(strings.TrimSpace(name)))
+	if !scenarios[scenario] {
+		return "", fmt.Errorf("unknown scenario %q, expected approve, decline, delay, 5xx, malformed or manual", name)
+	}
+	return scenario, nil
+}
+
+// Config - the merchant account the simulator accepts and how fast it works
+type Config struct {
+	MerchantID string
+	SecretKey  string
+	// PublicURL - where clients reach the simulator, the payment page URLs point there
+	PublicURL string
+	// DefaultScenario - for orders that are neither scripted nor tagged, defaults to approve
+	DefaultScenario Scenario
+	// ProcessingTime - how long an order stays pending before its final status, defaults to 1s
+	ProcessingTime time.Duration
+	// Delay - how late the answers and the final status of ScenarioDelay orders are, defaults to 30s
+	Delay time.Duration
+	// CallbackAttempts - how often a callback is posted until the merchant answers 2xx, defaults to 3
+	CallbackAttempts int
+	// CallbackBackoff - the wait before the next callback attempt, doubled every attempt, defaults to 1s
+	CallbackBackoff time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.DefaultScenario == "" {
+		c.DefaultScenario = ScenarioApprove
+	}
+	if c.ProcessingTime <= 0 {
+		c.ProcessingTime = time.Second
+	}
+	if c.Delay <= 0 {
+		c.Delay = 30 * time.Second
+	}
+	if c.CallbackAttempts <= 0 {
+		c.CallbackAttempts = 3
+	}
+	if c.CallbackBackoff <= 0 {
+		c.CallbackBackoff = time.Second
+	}
+	c.PublicURL = strings.TrimSuffix(c.PublicURL, "/")
+	return c
+}
+
+// Order - an order as the simulator keeps it
+type Order struct {
+	Type                   string
+	Status                 string
+	ErrorMessage           string
+	EndpointID             string
+	OrderID                string
+	MerchantOrderID        string
+	ProcessorTransactionID string
+	Amount                 string
+	Currency               string
+	CustomerEmail          string
+	CustomParam            string
+	CallbackURL            string
+	RedirectURL            string
+	Scenario               Scenario
+	CreatedAt              time.Time
+	EndedAt                time.Time
+}
+
+// Simulator - an in-memory Zota
+type Simulator struct {
+	logger *zap.Logger
+	config Config
+	client *http.Client
+
+	mu             sync.Mutex
+	orders         map[string]*Order // by Zota order ID
+	merchantOrders map[string]string // merchant order ID to Zota order ID
+	scripted       map[string]Scenario
+	timers         map[string]*time.Timer
+
+	// ctx is cancelled on Close, it stops the callbacks in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	now    func() time.Time
+}
+
+func New(logger *zap.Logger, config Config) *Simulator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Simulator{
+		logger:         logger,
+		config:         config.withDefaults(),
+		client:         &http.Client{Timeout: 10 * time.Second},
+		orders:         make(map[string]*Order),
+		merchantOrders: make(map[string]string),
+		scripted:       make(map[string]Scenario),
+		timers:         make(map[string]*time.Timer),
+		ctx:            ctx,
+		cancel:         cancel,
+		now:            time.Now,
+	}
+}
+
+// SetPublicURL points the payment page URLs to where the simulator was started, e.g. an httptest server
+func (s *Simulator) SetPublicURL(publicURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.PublicURL = strings.TrimSuffix(publicURL, "/")
+}
+
+// Handler serves the Zota API and the payment page
+func (s *Simulator) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/api/v1/deposit/request/{endpointID}/", s.handleDeposit)
+	r.Post("/api/v1/payout/request/{endpointID}/", s.handlePayout)
+	r.Get("/api/v1/query/order-status/", s.handleStatus)
+	r.Get("/api/v1/query/orders-report/csv/", s.handleOrdersReport)
+	r.Get("/payment/{orderID}", s.handlePaymentPage)
+	r.Post("/payment/{orderID}", s.handlePaymentSubmit)
+	r.Put("/simulator/scenarios/{merchantOrderID}", s.handleScript)
+	return r
+}
+
+// Script sets the scenario of an order that is yet to be requested
+func (s *Simulator) Script(merchantOrderID string, scenario Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted[merchantOrderID] = scenario
+}
+
+// Order returns a copy of the order with the merchant order ID
+func (s *Simulator) Order(merchantOrderID string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[s.merchantOrders[merchantOrderID]]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+// Close stops the pending status changes and waits for the callbacks in flight
+func (s *Simulator) Close() {
+	s.mu.Lock()
+	for orderID, timer := range s.timers {
+		if timer.Stop() {
+			s.wg.Done()
+		}
+		delete(s.timers, orderID)
+	}
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+// scenarioFor picks the scenario of a new order: scripted, tagged on the email or the default
+func (s *Simulator) scenarioFor(merchantOrderID, customerEmail string) Scenario {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if scenario, ok := s.scripted[merchantOrderID]; ok {
+		return scenario
+	}
+
+	local, _, _ := strings.Cut(customerEmail, "@")
+	if _, tag, ok := strings.Cut(local, "+"); ok {
+		if scenario, err := ParseScenario(tag); err == nil {
+			return scenario
+		}
+	}
+	return s.config.DefaultScenario
+}
+
+var errDuplicateOrder = errors.New("merchantOrderID already exists")
+
+// create stores a new order and starts processing it, unless it waits for the payment page
+func (s *Simulator) create(order *Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.merchantOrders[order.MerchantOrderID]; exists {
+		return errDuplicateOrder
+	}
+
+	order.OrderID = uuid.New().String()
+	order.Status = orderShared.StatusCreated
+	order.CreatedAt = s.now()
+	s.orders[order.OrderID] = order
+	s.merchantOrders[order.MerchantOrderID] = order.OrderID
+
+	switch order.Scenario {
+	case ScenarioManual:
+	case ScenarioDelay:
+		s.scheduleLocked(order, orderShared.StatusApproved, s.config.Delay)
+	case ScenarioDecline:
+		s.scheduleLocked(order, orderShared.StatusDeclined, s.config.ProcessingTime)
+	default:
+		s.scheduleLocked(order, orderShared.StatusApproved, s.config.ProcessingTime)
+	}
+
+	s.logger.Info("Created order", zap.String("type", order.Type), zap.String("orderID", order.OrderID),
+		zap.String("merchantOrderID", order.MerchantOrderID), zap.String("scenario", string(order.Scenario)))
+	return nil
+}
+
+// scheduleLocked moves the order to pending now and to its final status after the wait
+func (s *Simulator) scheduleLocked(order *Order, status string, wait time.Duration) {
+	order.Status = orderShared.StatusPending
+	orderID := order.OrderID
+
+	s.wg.Add(1)
+	s.timers[orderID] = time.AfterFunc(wait, func() {
+		defer s.wg.Done()
+		s.finish(orderID, status)
+	})
+}
+
+// finish gives a pending order its final status and posts the callback, final orders are left alone
+func (s *Simulator) finish(orderID, status string) bool {
+	s.mu.Lock()
+	order, ok := s.orders[orderID]
+	if !ok || orderShared.IsFinal(order.Status) {
+		s.mu.Unlock()
+		return false
+	}
+
+	delete(s.timers, orderID)
+	order.Status = status
+	order.EndedAt = s.now()
+	order.ProcessorTransactionID = uuid.New().String()
+	if status == orderShared.StatusDeclined {
+		order.ErrorMessage = "Transaction declined by the simulator"
+	}
+	finished := *order
+	s.mu.Unlock()
+
+	s.logger.Info("Order reached its final status", zap.String("orderID", orderID),
+		zap.String("merchantOrderID", finished.MerchantOrderID), zap.String("status", status))
+	if finished.CallbackURL != "" {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.sendCallback(finished)
+		}()
+	}
+	return true
+}
+
+// wait holds the answer of a ScenarioDelay order back, the caller may give up first
+func (s *Simulator) wait(r *http.Request, scenario Scenario) bool {
+	if scenario != ScenarioDelay {
+		return true
+	}
+
+	select {
+	case <-time.After(s.config.Delay):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,316 @@
+package zotasim
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"zota-dev-challenge/internal/apperror"
+	callbackZota "zota-dev-challenge/internal/callback/common/zota"
+	callbackShared "zota-dev-challenge/internal/callback/shared"
+	"zota-dev-challenge/internal/config"
+	depositZota "zota-dev-challenge/internal/deposit/common/zota"
+	depositShared "zota-dev-challenge/internal/deposit/shared"
+	"zota-dev-challenge/internal/metrics"
+	"zota-dev-challenge/internal/money"
+	orderShared "zota-dev-challenge/internal/order/shared"
+	payoutZota "zota-dev-challenge/internal/payout/common/zota"
+	payoutShared "zota-dev-challenge/internal/payout/shared"
+	statusZota "zota-dev-challenge/internal/status/common/zota"
+	statusShared "zota-dev-challenge/internal/status/shared"
+	"zota-dev-challenge/internal/zotaapi"
+)
+
+// simulatorTestSuite runs the merchant's own Zota gateways against the simulator
+type simulatorTestSuite struct {
+	simulator *Simulator
+	server    *httptest.Server
+	config    *config.Config
+	callbacks chan *callbackShared.Event
+	deposits  *depositZota.DepositGateway
+	statuses  *statusZota.StatusGateway
+	payouts   *payoutZota.PayoutGateway
+}
+
+func (s *simulatorTestSuite) setup(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	s.simulator = New(logger, Config{
+		MerchantID:       "merchant123",
+		SecretKey:        "secret123",
+		ProcessingTime:   10 * time.Millisecond,
+		Delay:            200 * time.Millisecond,
+		CallbackBackoff:  10 * time.Millisecond,
+		CallbackAttempts: 2,
+	})
+	s.server = httptest.NewServer(s.simulator.Handler())
+	s.simulator.SetPublicURL(s.server.URL)
+
+	s.config = &config.Config{
+		ZotaMerchantId:         "merchant123",
+		ZotaAPISecretKey:       "secret123",
+		ZotaBaseUrl:            s.server.URL,
+		ZotaEndpointId:         "1050",
+		ZotaEndpointIds:        map[string]string{"USD": "1050"},
+		ZotaDepositRedirectUrl: "https://merchant.example.com/return",
+	}
+
+	// the callbacks are verified by the merchant's callback gateway
+	s.callbacks = make(chan *callbackShared.Event, 10)
+	callbackGateway := callbackZota.NewCallbackGateway(logger, s.config)
+	merchant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		event, err := callbackGateway.ParseCallback(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.callbacks <- event
+	}))
+	t.Cleanup(merchant.Close)
+	s.config.ZotaDepositCallBackUrl = merchant.URL + "/api/v1/callback/deposit"
+	s.config.ZotaPayoutCallBackUrl = merchant.URL + "/api/v1/callback/payout"
+
+	client := zotaapi.NewClient(logger, s.config)
+	s.deposits = depositZota.NewDepositGateway(logger, s.config, client, metrics.New())
+	s.statuses = statusZota.NewStatusGateway(logger, s.config, client, metrics.New())
+	s.payouts = payoutZota.NewPayoutGateway(logger, s.config, client, metrics.New())
+
+	t.Cleanup(func() {
+		s.server.Close()
+		s.simulator.Close()
+	})
+}
+
+func (s *simulatorTestSuite) deposit(merchantOrderID, email string) (*depositShared.Response, error) {
+	return s.deposits.Deposit(context.Background(), depositShared.Request{
+		ClientRequest: depositShared.ClientRequest{
+			UserId:              "user123",
+			OrderAmount:         "10.50",
+			OrderCurrency:       "USD",
+			CustomerEmail:       email,
+			CustomerFirstName:   "John",
+			CustomerLastName:    "Doe",
+			CustomerAddress:     "123 Main St",
+			CustomerCountryCode: "US",
+			CustomerCity:        "New York",
+			CustomerZipCode:     "10001",
+			CustomerPhone:       "1234567890",
+			CustomerIp:          "127.0.0.1",
+			CheckoutUrl:         "https://merchant.example.com/checkout",
+		},
+		MerchantOrderID: merchantOrderID,
+		Amount:          money.FromMinor(1050, "USD"),
+	})
+}
+
+func (s *simulatorTestSuite) callback(t *testing.T) *callbackShared.Event {
+	select {
+	case event := <-s.callbacks:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no callback arrived")
+		return nil
+	}
+}
+
+func TestDeposit_ApprovedWithCallbackAndStatus(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	res, err := s.deposit("order1", "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "order1", res.OrderID)
+	assert.Equal(t, s.server.URL+"/payment/"+res.PaymentGatewayOrderID, res.DepositUrl)
+
+	event := s.callback(t)
+	assert.Equal(t, orderShared.StatusApproved, event.Status)
+	assert.Equal(t, "order1", event.MerchantOrderID)
+	assert.Equal(t, "10.50", event.Amount)
+
+	status, err := s.statuses.CheckStatus(context.Background(), statusShared.Request{
+		ClientRequest: statusShared.ClientRequest{OrderId: res.PaymentGatewayOrderID, MerchantOrderId: "order1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, orderShared.StatusApproved, status.Status)
+	assert.Equal(t, "10.50", status.Amount.String())
+
+	// a merchant that lost the deposit answer looks the order up by its own ID
+	status, err = s.statuses.CheckStatus(context.Background(), statusShared.Request{
+		ClientRequest: statusShared.ClientRequest{MerchantOrderId: "order1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, res.PaymentGatewayOrderID, status.ClientRequest.OrderId)
+	assert.Equal(t, orderShared.StatusApproved, status.Status)
+}
+
+func TestDeposit_DeclineTaggedOnEmail(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	_, err := s.deposit("order1", "john+decline@example.com")
+	require.NoError(t, err)
+
+	event := s.callback(t)
+	assert.Equal(t, orderShared.StatusDeclined, event.Status)
+	assert.NotEmpty(t, event.ErrorMessage)
+}
+
+func TestDeposit_ScriptedScenarios(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	s.simulator.Script("unavailable", ScenarioServerError)
+	_, err := s.deposit("unavailable", "john@example.com")
+	assert.Equal(t, apperror.CodeGatewayUnavailable, apperror.From(err).Code)
+	_, stored := s.simulator.Order("unavailable")
+	assert.False(t, stored)
+
+	s.simulator.Script("garbled", ScenarioMalformed)
+	_, err = s.deposit("garbled", "john@example.com")
+	assert.Equal(t, apperror.CodeGatewayError, apperror.From(err).Code)
+	_, stored = s.simulator.Order("garbled")
+	assert.True(t, stored, "Zota accepted the order even though its answer was garbled")
+}
+
+func TestDeposit_DelayOutlastsTimeout(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+	s.config.ZotaDepositTimeout = 50 * time.Millisecond
+
+	_, err := s.deposit("slow", "john+delay@example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDeposit_InvalidSignature(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+	s.config.ZotaAPISecretKey = "wrong"
+
+	_, err := s.deposit("order1", "john@example.com")
+	appErr := apperror.From(err)
+	assert.Equal(t, apperror.CodeGatewayRejected, appErr.Code)
+	require.NotNil(t, appErr.Gateway)
+	assert.Equal(t, "401", appErr.Gateway.Code)
+}
+
+func TestDeposit_DuplicateMerchantOrderID(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	_, err := s.deposit("order1", "john+manual@example.com")
+	require.NoError(t, err)
+	_, err = s.deposit("order1", "john+manual@example.com")
+	assert.Equal(t, apperror.CodeGatewayRejected, apperror.From(err).Code)
+}
+
+func TestPaymentPage_ManualDecision(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	res, err := s.deposit("order1", "john+manual@example.com")
+	require.NoError(t, err)
+
+	page, err := http.Get(res.DepositUrl)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	assert.Contains(t, string(body), "10.50 USD")
+	assert.Contains(t, string(body), `value="decline"`)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	submitted, err := client.PostForm(res.DepositUrl, url.Values{"outcome": {"decline"}})
+	require.NoError(t, err)
+	submitted.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, submitted.StatusCode)
+
+	redirect, err := url.Parse(submitted.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "merchant.example.com", redirect.Host)
+	assert.Equal(t, orderShared.StatusDeclined, redirect.Query().Get("status"))
+	assert.Equal(t, Sign("secret123", orderShared.StatusDeclined, res.PaymentGatewayOrderID, "order1"),
+		redirect.Query().Get("signature"))
+
+	assert.Equal(t, orderShared.StatusDeclined, s.callback(t).Status)
+}
+
+func TestPayout_Approved(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	res, err := s.payouts.Payout(context.Background(), payoutShared.Request{
+		ClientRequest: payoutShared.ClientRequest{
+			UserId:                    "user123",
+			OrderAmount:               "25.00",
+			OrderCurrency:             "USD",
+			CustomerEmail:             "john@example.com",
+			CustomerFirstName:         "John",
+			CustomerLastName:          "Doe",
+			CustomerBankAccountNumber: "12345678",
+			CustomerBankAccountName:   "John Doe",
+		},
+		MerchantOrderID: "payout1",
+		Amount:          money.FromMinor(2500, "USD"),
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, res.PaymentGatewayOrderID)
+
+	event := s.callback(t)
+	assert.Equal(t, orderShared.TypePayout, event.Type)
+	assert.Equal(t, orderShared.StatusApproved, event.Status)
+}
+
+func TestOrdersReport(t *testing.T) {
+	s := &simulatorTestSuite{}
+	s.setup(t)
+
+	_, err := s.deposit("order1", "john+manual@example.com")
+	require.NoError(t, err)
+	_, err = s.deposit("order2", "jane@example.com")
+	require.NoError(t, err)
+	s.callback(t)
+
+	today := time.Now().UTC().Format(reportDateLayout)
+	query := url.Values{
+		"merchantID": {"merchant123"}, "dateType": {"created"}, "endpointIds": {"1050"}, "fromDate": {today},
+		"requestID": {"report1"}, "statuses": {orderShared.StatusApproved}, "timestamp": {"1700000000"},
+		"toDate": {today}, "types": {orderShared.TypeSale},
+	}
+	query.Set("signature", Sign("secret123", "merchant123", "created", "1050", today, "report1",
+		orderShared.StatusApproved, "1700000000", today, orderShared.TypeSale))
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/query/orders-report/csv/?%s", s.server.URL, query.Encode()))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2, "the header and the approved order, the pending one is filtered out")
+	assert.Equal(t, ReportColumns, rows[0])
+	assert.Equal(t, "order2", rows[1][6])
+	assert.Equal(t, orderShared.StatusApproved, rows[1][2])
+
+	query.Set("signature", "wrong")
+	resp, err = http.Get(fmt.Sprintf("%s/api/v1/query/orders-report/csv/?%s", s.server.URL, query.Encode()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestParseScenario(t *testing.T) {
+	scenario, err := ParseScenario(" 5XX ")
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioServerError, scenario)
+
+	_, err = ParseScenario("explode")
+	assert.Error(t, err)
+}